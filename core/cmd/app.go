@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"regexp"
+	"time"
 
 	"github.com/sebastianmontero/vrf-oracle/core/static"
 	"github.com/urfave/cli"
@@ -541,6 +542,194 @@ func NewApp(client *Client) *cli.App {
 						},
 					},
 				},
+				{
+					Name:  "vrf",
+					Usage: "Commands for administering the EOS VRF oracle",
+					Subcommands: []cli.Command{
+						{
+							Name:        "verify",
+							Usage:       "Verify the proofs of a setrand action",
+							Description: "Takes an EOSProofResponse json, an array of them or the setrand action data, either as the first argument, in a file or from a transaction",
+							Action:      client.VerifyVRFProofs,
+							Flags: []cli.Flag{
+								cli.StringFlag{
+									Name:  "file, f",
+									Usage: "json file holding the proofs",
+								},
+								cli.StringFlag{
+									Name:  "tx, t",
+									Usage: "id of a transaction with setrand actions, fetched from the EOS node",
+								},
+								cli.StringFlag{
+									Name:  "url, u",
+									Usage: "url of the EOS node with the history api, defaults to EOS_URL",
+								},
+								cli.StringFlag{
+									Name:  "publicKey, pk",
+									Usage: "compressed public key the proofs must have been generated with, defaults to the keys in the key store",
+								},
+								cli.UintFlag{
+									Name:  "count",
+									Usage: "number of values to derive from the output of each proof",
+								},
+								cli.Uint64Flag{
+									Name:  "min",
+									Usage: "minimum of the derived values",
+								},
+								cli.Uint64Flag{
+									Name:  "max",
+									Usage: "maximum of the derived values",
+								},
+								cli.BoolFlag{
+									Name:  "unique",
+									Usage: "derive unique values",
+								},
+							},
+						},
+						{
+							Name:   "pubkey",
+							Usage:  "Show the vrf public keys encoded the way the EOS contract expects them",
+							Action: client.ShowVRFPublicKeys,
+							Flags: []cli.Flag{
+								cli.StringFlag{
+									Name:  "publicKey, pk",
+									Usage: "compressed public key of the key to show, defaults to all the keys",
+								},
+								cli.BoolFlag{
+									Name:  "register",
+									Usage: "register the key in the contract by pushing setpubkey with the contract key",
+								},
+							},
+						},
+						{
+							Name:  "rotate",
+							Usage: "Commands for rotating the vrf key without downtime",
+							Subcommands: []cli.Command{
+								{
+									Name:   "start",
+									Usage:  "Create a new key, register it in the contract and use it alongside the old key during the grace period",
+									Action: client.StartVRFKeyRotation,
+									Flags: []cli.Flag{
+										cli.StringFlag{
+											Name:  "publicKey, pk",
+											Usage: "compressed public key of the key to rotate, defaults to the new key of the last rotation",
+										},
+										cli.DurationFlag{
+											Name:  "grace, g",
+											Usage: "period during which the old key is still used for the requests that selected it",
+											Value: 24 * time.Hour,
+										},
+									},
+								},
+								{
+									Name:   "complete",
+									Usage:  "Archive the old key of the rotation in progress",
+									Action: client.CompleteVRFKeyRotation,
+									Flags: []cli.Flag{
+										cli.BoolFlag{
+											Name:  "force",
+											Usage: "complete the rotation before the grace period ends",
+										},
+									},
+								},
+								{
+									Name:   "list",
+									Usage:  "List the key rotations",
+									Action: client.ListVRFKeyRotations,
+								},
+							},
+						},
+						{
+							Name:  "threshold",
+							Usage: "Commands for the shares of threshold vrf group keys",
+							Subcommands: []cli.Command{
+								{
+									Name:   "keygen",
+									Usage:  "Create a group key and write its shares to encrypted files, one for each oracle",
+									Action: client.CreateVRFKeyShares,
+									Flags: []cli.Flag{
+										cli.IntFlag{
+											Name:  "threshold, t",
+											Usage: "number of shares needed to produce the vrf output",
+										},
+										cli.IntFlag{
+											Name:  "nodes, n",
+											Usage: "number of shares, one for each oracle",
+										},
+										cli.StringFlag{
+											Name:  "dir, d",
+											Usage: "directory to write the share files to",
+										},
+										cli.StringFlag{
+											Name:  "password, p",
+											Usage: "file containing the password to encrypt the share files with",
+										},
+									},
+								},
+								{
+									Name:   "import",
+									Usage:  "Import a share file into the db, protected by VRF_KEY_STORE_PASSWORD",
+									Action: client.ImportVRFKeyShare,
+									Flags: []cli.Flag{
+										cli.StringFlag{
+											Name:  "file, f",
+											Usage: "share file to import",
+										},
+										cli.StringFlag{
+											Name:  "password, p",
+											Usage: "file containing the password the share file was encrypted with",
+										},
+									},
+								},
+								{
+									Name:   "list",
+									Usage:  "List the key shares in the db",
+									Action: client.ListVRFKeyShares,
+								},
+								{
+									Name:   "register",
+									Usage:  "Register the group key of a share in the db in the contract",
+									Action: client.RegisterVRFGroupKey,
+									Flags: []cli.Flag{
+										cli.StringFlag{
+											Name:  "groupKey, gk",
+											Usage: "compressed public key of the group key to register",
+										},
+									},
+								},
+							},
+						},
+						{
+							Name:  "deadletters",
+							Usage: "Commands for the jobs table deltas the oracle could not process",
+							Subcommands: []cli.Command{
+								{
+									Name:   "list",
+									Usage:  "List the dead letters",
+									Action: client.ListVRFDeadLetters,
+									Flags: []cli.Flag{
+										cli.StringFlag{
+											Name:  "status, s",
+											Usage: "only list dead letters with this status: Failed, Replay or Replayed",
+										},
+									},
+								},
+								{
+									Name:        "replay",
+									Usage:       "Mark failed dead letters to be replayed by the running oracle",
+									Description: "Takes the ids of the dead letters to replay",
+									Action:      client.ReplayVRFDeadLetters,
+									Flags: []cli.Flag{
+										cli.BoolFlag{
+											Name:  "all",
+											Usage: "replay all failed dead letters",
+										},
+									},
+								},
+							},
+						},
+					},
+				},
 				{
 					Name:   "hard-reset",
 					Usage:  "Removes unstarted transactions, cancels pending transactions as well as deletes job runs. Use with caution, this command cannot be reverted! Only execute when the node is not started!",
@@ -657,6 +846,36 @@ func NewApp(client *Client) *cli.App {
 				},
 			},
 		},
+
+		{
+			Name:  "vrf",
+			Usage: "Commands for handling the requests of the EOS VRF oracle",
+			Subcommands: []cli.Command{
+				{
+					Name:  "requests",
+					Usage: "Commands for acting on VRF requests",
+					Subcommands: []cli.Command{
+						{
+							Name:    "retry",
+							Aliases: []string{"reprove"},
+							Usage:   "Process the last job of the VRF request with the specified ID again, generating its proofs again at the same block, the job must have finished",
+							Action:  client.RetryVRFRequest,
+						},
+						{
+							Name:   "cancel",
+							Usage:  "Cancel the VRF request with the specified ID, its pending jobs are failed",
+							Action: client.CancelVRFRequest,
+							Flags: []cli.Flag{
+								cli.StringFlag{
+									Name:  "reason, r",
+									Usage: "reason for cancelling the request, recorded in the runs of its pending jobs",
+								},
+							},
+						},
+					},
+				},
+			},
+		},
 	}...)
 	return app
 }
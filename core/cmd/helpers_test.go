@@ -0,0 +1,15 @@
+package cmd
+
+import (
+	"encoding/json"
+
+	"github.com/sebastianmontero/vrf-oracle/core/services/vrf"
+)
+
+func ExportedDecodeVRFProofs(data []byte, seedType string) ([]*vrf.EOSProofResponse, error) {
+	return decodeVRFProofs(data, seedType)
+}
+
+func ExportedSetRandProofsFromTransaction(raw json.RawMessage, seedType string) ([]*vrf.EOSProofResponse, error) {
+	return setRandProofsFromTransaction(raw, seedType)
+}
@@ -10,6 +10,7 @@ import (
 	clipkg "github.com/urfave/cli"
 
 	"github.com/sebastianmontero/vrf-oracle/core/logger"
+	"github.com/sebastianmontero/vrf-oracle/core/services/svrf"
 	"github.com/sebastianmontero/vrf-oracle/core/store"
 	"github.com/sebastianmontero/vrf-oracle/core/store/models/vrfkey"
 	"github.com/sebastianmontero/vrf-oracle/core/store/orm"
@@ -32,28 +33,27 @@ func (cli *Client) CreateVRFKey(c *clipkg.Context) error {
 	if err != nil {
 		return errors.Wrapf(err, "while creating new account")
 	}
-	uncompressedKey, err := key.StringUncompressed()
+	encoded, keyHash, err := svrf.EOSPublicKey(key)
 	if err != nil {
-		return errors.Wrapf(err, "while creating new account")
-	}
-	hash, err := key.Hash()
-	hashStr := hash.Hex()
-	if err != nil {
-		hashStr = "error while computing hash of public key: " + err.Error()
+		return errors.Wrapf(err, "while encoding new key")
 	}
 	fmt.Printf(`Created keypair.
 
 Compressed public key (use this for interactions with the chainlink node):
   %s
-Uncompressed public key (use this to register key with the VRFCoordinator):
+EOS public key (use this to register the key in the vrf contract):
   %s
-Hash of public key (use this to request randomness from your consuming contract):
+Key hash (use this to select the key in requests):
   %s
 
+The following command will register the key in the vrf contract:
+
+chainlink local vrf pubkey -pk %s --register
+
 The following command will export the encrypted secret key from the db to <save_path>:
 
-chainlink local vrf export -f <save_path> -pk %s
-`, key, uncompressedKey, hashStr, key)
+chainlink keys vrf export -f <save_path> -pk %s
+`, key, encoded, keyHash.Hex()[2:], key, key)
 	return nil
 }
 
@@ -0,0 +1,453 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"time"
+
+	eosc "github.com/eoscanada/eos-go"
+	"github.com/pkg/errors"
+	clipkg "github.com/urfave/cli"
+	"go.dedis.ch/kyber/v3"
+
+	"github.com/sebastianmontero/vrf-oracle/core/services/eos"
+	"github.com/sebastianmontero/vrf-oracle/core/services/eos/contracts"
+	"github.com/sebastianmontero/vrf-oracle/core/services/svrf"
+	"github.com/sebastianmontero/vrf-oracle/core/services/vrf"
+	"github.com/sebastianmontero/vrf-oracle/core/store"
+	"github.com/sebastianmontero/vrf-oracle/core/store/models"
+	"github.com/sebastianmontero/vrf-oracle/core/store/models/vrfkey"
+	"github.com/sebastianmontero/vrf-oracle/core/store/orm"
+)
+
+// vrfOracleStore returns a store that does not take the advisory lock, so that
+// it can be used while the vrf oracle is running
+func vrfOracleStore(cli *Client) *store.Store {
+	cli.Config.Dialect = orm.DialectPostgresWithoutLock
+	return cli.AppFactory.NewApplication(cli.Config).GetStore()
+}
+
+// ListVRFDeadLetters lists the jobs table deltas that the vrf oracle could not process
+func (cli *Client) ListVRFDeadLetters(c *clipkg.Context) error {
+	deadLetters, err := vrfOracleStore(cli).FindVRFDeadLetters(models.VRFDeadLetterStatus(c.String("status")))
+	if err != nil {
+		return cli.errorOut(errors.Wrap(err, "while listing dead letters"))
+	}
+	var rows [][]string
+	for _, deadLetter := range deadLetters {
+		rows = append(rows, []string{
+			strconv.FormatUint(deadLetter.ID, 10),
+			deadLetter.Scope,
+			deadLetter.Operation,
+			deadLetter.ForkStep,
+			strconv.FormatUint(deadLetter.BlockNum, 10),
+			string(deadLetter.Status),
+			strconv.Itoa(deadLetter.Replays),
+			deadLetter.Error,
+			deadLetter.NewData,
+			deadLetter.OldData,
+		})
+	}
+	fmt.Println("\n💀 VRF Dead Letters")
+	renderList([]string{"ID", "Scope", "Operation", "Fork Step", "Block Num", "Status", "Replays", "Error", "New Data", "Old Data"}, rows)
+	return nil
+}
+
+// ReplayVRFDeadLetters marks failed dead letters to be replayed by the running vrf oracle
+func (cli *Client) ReplayVRFDeadLetters(c *clipkg.Context) error {
+	ids := make([]uint64, 0, len(c.Args()))
+	for _, arg := range c.Args() {
+		id, err := strconv.ParseUint(arg, 10, 64)
+		if err != nil {
+			return cli.errorOut(errors.Wrapf(err, "invalid dead letter id: %v", arg))
+		}
+		ids = append(ids, id)
+	}
+	if len(ids) == 0 && !c.Bool("all") {
+		return cli.errorOut(errors.New("must specify the ids of the dead letters to replay or --all"))
+	}
+	marked, err := vrfOracleStore(cli).MarkVRFDeadLettersForReplay(ids...)
+	if err != nil {
+		return cli.errorOut(errors.Wrap(err, "while marking dead letters for replay"))
+	}
+	fmt.Printf("%v dead letters marked for replay, the vrf oracle will replay them shortly\n", marked)
+	return nil
+}
+
+// vrfContract returns the vrf contract, with the contract key to push the actions that require its permission
+func vrfContract(cli *Client) (*contracts.VRF, error) {
+	eosAPI, err := eos.New(cli.Config.EOSURL(), []string{cli.Config.VRFContractKey()})
+	if err != nil {
+		return nil, errors.Wrap(err, "while creating eos instance")
+	}
+	return contracts.NewVRF(cli.Config.VRFContract(), eosAPI), nil
+}
+
+// StartVRFKeyRotation creates a new vrf key, registers it in the contract and records the rotation. The running
+// oracle picks up the new key and uses it as default, keeping the old key unlocked until the rotation is completed.
+// The new key is protected by VRF_KEY_STORE_PASSWORD, the password the oracle unlocks its keys with
+func (cli *Client) StartVRFKeyRotation(c *clipkg.Context) error {
+	vrfStore := vrfOracleStore(cli)
+	password := cli.Config.VRFKeyStorePassword()
+	if password == "" {
+		return cli.errorOut(errors.New("must set VRF_KEY_STORE_PASSWORD"))
+	}
+	oldKey, err := vrfKeyToRotate(c, vrfStore)
+	if err != nil {
+		return cli.errorOut(err)
+	}
+	contract, err := vrfContract(cli)
+	if err != nil {
+		return cli.errorOut(err)
+	}
+	rotation, err := svrf.NewVRFKeyRotator(vrfStore, contract).Start(oldKey, password, c.Duration("grace"))
+	if err != nil {
+		return cli.errorOut(errors.Wrap(err, "while starting key rotation"))
+	}
+	fmt.Printf(`Started key rotation %v.
+
+New key %v registered in transaction %v.
+Both keys will be used by the oracle until the grace period ends at %v, then run:
+
+chainlink local vrf rotate complete
+`, rotation.ID, rotation.NewKey, rotation.RegisterTxID, rotation.GraceEndsAt)
+	return nil
+}
+
+// vrfKeyToRotate the key specified, the new key of the last rotation or the only key in the db
+func vrfKeyToRotate(c *clipkg.Context, vrfStore *store.Store) (vrfkey.PublicKey, error) {
+	if c.IsSet("publicKey") {
+		return getPublicKey(c)
+	}
+	last, err := vrfStore.FindLastVRFKeyRotation()
+	if err != nil {
+		return vrfkey.PublicKey{}, errors.Wrap(err, "while finding last key rotation")
+	}
+	if last != nil {
+		return vrfkey.NewPublicKeyFromHex(last.NewKey)
+	}
+	keys, err := vrfStore.VRFKeyStore.ListKeys()
+	if err != nil {
+		return vrfkey.PublicKey{}, err
+	}
+	if len(keys) != 1 {
+		return vrfkey.PublicKey{}, fmt.Errorf("there are %v keys, must specify the public key of the key to rotate", len(keys))
+	}
+	return *keys[0], nil
+}
+
+// CompleteVRFKeyRotation archives the old key of the rotation in progress once its grace period has ended
+func (cli *Client) CompleteVRFKeyRotation(c *clipkg.Context) error {
+	rotation, err := svrf.NewVRFKeyRotator(vrfOracleStore(cli), nil).Complete(c.Bool("force"))
+	if err != nil {
+		return cli.errorOut(errors.Wrap(err, "while completing key rotation"))
+	}
+	fmt.Printf("Completed key rotation %v, key %v archived\n", rotation.ID, rotation.OldKey)
+	return nil
+}
+
+// ListVRFKeyRotations lists the key rotations, which record the period during which each key was authoritative
+func (cli *Client) ListVRFKeyRotations(c *clipkg.Context) error {
+	rotations, err := vrfOracleStore(cli).FindVRFKeyRotations()
+	if err != nil {
+		return cli.errorOut(errors.Wrap(err, "while listing key rotations"))
+	}
+	var rows [][]string
+	for _, rotation := range rotations {
+		completedAt := ""
+		if rotation.CompletedAt.Valid {
+			completedAt = rotation.CompletedAt.Time.Format(time.RFC3339)
+		}
+		rows = append(rows, []string{
+			strconv.FormatUint(rotation.ID, 10),
+			rotation.OldKey,
+			rotation.NewKey,
+			rotation.NewKeyHash,
+			rotation.RegisterTxID,
+			string(rotation.Status),
+			rotation.CreatedAt.Format(time.RFC3339),
+			rotation.GraceEndsAt.Format(time.RFC3339),
+			completedAt,
+		})
+	}
+	fmt.Println("\n🔄 VRF Key Rotations")
+	renderList([]string{"ID", "Old Key", "New Key", "New Key Hash", "Register Tx", "Status", "Registered At", "Grace Ends At", "Old Key Archived At"}, rows)
+	return nil
+}
+
+// ShowVRFPublicKeys prints the vrf public keys encoded the way the EOS contract expects them, the one specified
+// or all the keys in the db. With --register the key is registered in the contract by pushing setpubkey
+func (cli *Client) ShowVRFPublicKeys(c *clipkg.Context) error {
+	var keys []vrfkey.PublicKey
+	if c.IsSet("publicKey") {
+		key, err := getPublicKey(c)
+		if err != nil {
+			return cli.errorOut(err)
+		}
+		keys = append(keys, key)
+	} else {
+		if c.Bool("register") {
+			return cli.errorOut(errors.New("must specify the public key of the key to register"))
+		}
+		dbKeys, err := vrfOracleStore(cli).VRFKeyStore.ListKeys()
+		if err != nil {
+			return cli.errorOut(errors.Wrap(err, "while listing keys"))
+		}
+		for _, key := range dbKeys {
+			keys = append(keys, *key)
+		}
+	}
+	var rows [][]string
+	for _, key := range keys {
+		encoded, keyHash, err := svrf.EOSPublicKey(key)
+		if err != nil {
+			return cli.errorOut(err)
+		}
+		rows = append(rows, []string{key.String(), encoded, keyHash.Hex()[2:]})
+	}
+	fmt.Println("\n🔑 VRF Keys for the EOS contract")
+	renderList([]string{"Compressed Public Key", "EOS Public Key (use this to register the key in the contract)", "Key Hash (use this to select the key in requests)"}, rows)
+	if !c.Bool("register") {
+		return nil
+	}
+	contract, err := vrfContract(cli)
+	if err != nil {
+		return cli.errorOut(err)
+	}
+	txID, err := svrf.NewVRFKeyRotator(nil, contract).Register(keys[0])
+	if err != nil {
+		return cli.errorOut(err)
+	}
+	fmt.Printf("Registered key %v in contract %v, transaction: %v\n", keys[0], contract.ContractName, txID)
+	return nil
+}
+
+// VerifyVRFProofs verifies the proofs of a setrand action, taken from a json file, the first argument
+// or the setrand actions of a transaction fetched from an EOS node. The final seed is recomputed from
+// the seed and the block id, and the proofs must have been generated with a trusted key: the public key
+// specified or, if none is, one of the keys in the key store of the node. If count is specified the values
+// derived from the output of each proof are printed as well
+func (cli *Client) VerifyVRFProofs(c *clipkg.Context) error {
+	trustedKeys, err := cli.trustedVRFKeys(c)
+	if err != nil {
+		return cli.errorOut(err)
+	}
+	if c.IsSet("count") && (c.Uint("count") == 0 || c.Uint("count") > vrf.MaxExpandedValues) {
+		return cli.errorOut(fmt.Errorf("count must be between 1 and %v", vrf.MaxExpandedValues))
+	}
+	proofs, err := cli.vrfProofsToVerify(c)
+	if err != nil {
+		return cli.errorOut(err)
+	}
+	if len(proofs) == 0 {
+		return cli.errorOut(errors.New("no proofs found"))
+	}
+	var rows [][]string
+	invalid := 0
+	for _, proof := range proofs {
+		result := "Valid"
+		if err := verifyWithTrustedKeys(proof, trustedKeys); err != nil {
+			result = err.Error()
+			invalid++
+		}
+		row := []string{
+			proof.Seed.String(),
+			strconv.FormatUint(proof.BlockNum, 10),
+			proof.BlockID,
+			proof.FinalSeed,
+			strconv.FormatUint(proof.OutputU64, 10),
+			result,
+		}
+		if c.IsSet("count") {
+			values, err := proof.Values(uint16(c.Uint("count")), c.Uint64("min"), c.Uint64("max"), c.Bool("unique"))
+			if err != nil {
+				return cli.errorOut(errors.Wrap(err, "while deriving values"))
+			}
+			row = append(row, fmt.Sprint(values))
+		}
+		rows = append(rows, row)
+	}
+	headers := []string{"Seed", "Block Num", "Block ID", "Final Seed", "Output U64", "Result"}
+	if c.IsSet("count") {
+		headers = append(headers, "Values")
+	}
+	fmt.Println("\n🎲 VRF Proofs")
+	renderList(headers, rows)
+	if invalid > 0 {
+		return cli.errorOut(fmt.Errorf("%v of %v proofs are invalid", invalid, len(proofs)))
+	}
+	return nil
+}
+
+// trustedVRFKeys the keys the proofs must have been generated with, the public key specified or the keys in the key store
+func (cli *Client) trustedVRFKeys(c *clipkg.Context) ([]kyber.Point, error) {
+	var keys []vrfkey.PublicKey
+	if c.IsSet("publicKey") {
+		key, err := vrfkey.NewPublicKeyFromHex(c.String("publicKey"))
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid public key")
+		}
+		keys = append(keys, key)
+	} else {
+		dbKeys, err := vrfOracleStore(cli).VRFKeyStore.ListKeys()
+		if err != nil {
+			return nil, errors.Wrap(err, "while listing keys")
+		}
+		if len(dbKeys) == 0 {
+			return nil, errors.New("must specify the public key the proofs must have been generated with, there are no keys in the key store")
+		}
+		for _, key := range dbKeys {
+			keys = append(keys, *key)
+		}
+	}
+	points := make([]kyber.Point, 0, len(keys))
+	for _, key := range keys {
+		point, err := key.Point()
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid public key: %v", key)
+		}
+		points = append(points, point)
+	}
+	return points, nil
+}
+
+// verifyWithTrustedKeys verifies the proof against the trusted key it was generated with, a proof generated
+// with any other key is rejected
+func verifyWithTrustedKeys(proof *vrf.EOSProofResponse, keys []kyber.Point) error {
+	p, err := proof.Proof()
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if key.Equal(p.PublicKey) {
+			return proof.Verify(key)
+		}
+	}
+	return fmt.Errorf("Proof public key: %v is not trusted", p.PublicKey)
+}
+
+func (cli *Client) vrfProofsToVerify(c *clipkg.Context) ([]*vrf.EOSProofResponse, error) {
+	switch {
+	case c.IsSet("tx"):
+		url := cli.Config.EOSURL()
+		if c.IsSet("url") {
+			url = c.String("url")
+		}
+		raw, err := eosc.New(url).GetTransactionRaw(context.Background(), c.String("tx"))
+		if err != nil {
+			return nil, errors.Wrapf(err, "while fetching transaction %v", c.String("tx"))
+		}
+		return setRandProofsFromTransaction(raw, cli.Config.VRFSeedType())
+	case c.IsSet("file"):
+		data, err := ioutil.ReadFile(c.String("file"))
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read file %s", c.String("file"))
+		}
+		return decodeVRFProofs(data, cli.Config.VRFSeedType())
+	case c.NArg() > 0:
+		return decodeVRFProofs([]byte(c.Args().First()), cli.Config.VRFSeedType())
+	}
+	return nil, errors.New("must specify the proofs json, a file with it using --file or a transaction id using --tx")
+}
+
+// chainVRFProof an EOSProofResponse as rendered by the chain, which renders large 64 bit integers as strings,
+// the seed is parsed once its type is known
+type chainVRFProof struct {
+	BlockNum   eosc.Uint64     `json:"block_num"`
+	BlockID    string          `json:"block_id"`
+	Seed       json.RawMessage `json:"seed"`
+	FinalSeed  string          `json:"final_seed"`
+	PublicKey  string          `json:"public_key"`
+	Gamma      string          `json:"gamma"`
+	C          string          `json:"c"`
+	S          string          `json:"s"`
+	OutputU256 string          `json:"output_u256"`
+	OutputU64  eosc.Uint64     `json:"output_u64"`
+}
+
+func (m *chainVRFProof) EOSProofResponse(seedType string) (*vrf.EOSProofResponse, error) {
+	seed := vrf.EOSSeed{Type: seedType}
+	if err := json.Unmarshal(m.Seed, &seed); err != nil {
+		return nil, errors.Wrap(err, "while decoding proof seed")
+	}
+	return &vrf.EOSProofResponse{
+		BlockNum:   uint64(m.BlockNum),
+		BlockID:    m.BlockID,
+		Seed:       seed,
+		FinalSeed:  m.FinalSeed,
+		PublicKey:  m.PublicKey,
+		Gamma:      m.Gamma,
+		C:          m.C,
+		S:          m.S,
+		OutputU256: m.OutputU256,
+		OutputU64:  uint64(m.OutputU64),
+	}, nil
+}
+
+// decodeVRFProofs decodes a proof, an array of proofs or setrand action data, whose seeds are of seedType
+func decodeVRFProofs(data []byte, seedType string) ([]*vrf.EOSProofResponse, error) {
+	data = bytes.TrimSpace(data)
+	var chainProofs []*chainVRFProof
+	if len(data) > 0 && data[0] == '[' {
+		if err := json.Unmarshal(data, &chainProofs); err != nil {
+			return nil, errors.Wrap(err, "while decoding proofs")
+		}
+	} else {
+		var setRand struct {
+			Proofs []*chainVRFProof `json:"proofs"`
+		}
+		if err := json.Unmarshal(data, &setRand); err != nil {
+			return nil, errors.Wrap(err, "while decoding proofs")
+		}
+		chainProofs = setRand.Proofs
+		if chainProofs == nil {
+			chainProof := &chainVRFProof{}
+			if err := json.Unmarshal(data, chainProof); err != nil {
+				return nil, errors.Wrap(err, "while decoding proof")
+			}
+			chainProofs = []*chainVRFProof{chainProof}
+		}
+	}
+	proofs := make([]*vrf.EOSProofResponse, 0, len(chainProofs))
+	for _, chainProof := range chainProofs {
+		proof, err := chainProof.EOSProofResponse(seedType)
+		if err != nil {
+			return nil, err
+		}
+		proofs = append(proofs, proof)
+	}
+	return proofs, nil
+}
+
+// setRandProofsFromTransaction returns the proofs of the setrand actions of a get_transaction response
+func setRandProofsFromTransaction(raw json.RawMessage, seedType string) ([]*vrf.EOSProofResponse, error) {
+	var tx struct {
+		Trx struct {
+			Trx struct {
+				Actions []struct {
+					Name string          `json:"name"`
+					Data json.RawMessage `json:"data"`
+				} `json:"actions"`
+			} `json:"trx"`
+		} `json:"trx"`
+	}
+	if err := json.Unmarshal(raw, &tx); err != nil {
+		return nil, errors.Wrap(err, "while decoding transaction")
+	}
+	var proofs []*vrf.EOSProofResponse
+	for _, action := range tx.Trx.Trx.Actions {
+		if action.Name != "setrand" {
+			continue
+		}
+		actionProofs, err := decodeVRFProofs(action.Data, seedType)
+		if err != nil {
+			return nil, err
+		}
+		proofs = append(proofs, actionProofs...)
+	}
+	return proofs, nil
+}
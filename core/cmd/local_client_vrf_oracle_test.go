@@ -0,0 +1,393 @@
+package cmd_test
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	eosc "github.com/eoscanada/eos-go"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/urfave/cli"
+
+	"github.com/sebastianmontero/vrf-oracle/core/cmd"
+	"github.com/sebastianmontero/vrf-oracle/core/internal/cltest"
+	"github.com/sebastianmontero/vrf-oracle/core/internal/mocks"
+	"github.com/sebastianmontero/vrf-oracle/core/services/vrf"
+	"github.com/sebastianmontero/vrf-oracle/core/store"
+	"github.com/sebastianmontero/vrf-oracle/core/store/models"
+	"github.com/sebastianmontero/vrf-oracle/core/store/models/vrfkey"
+	"github.com/sebastianmontero/vrf-oracle/core/store/orm"
+)
+
+var (
+	testVRFKey      = vrfkey.NewPrivateKeyXXXTestingOnly(big.NewInt(2))
+	untrustedVRFKey = vrfkey.NewPrivateKeyXXXTestingOnly(big.NewInt(3))
+	maxUint64Seed   = new(big.Int).SetUint64(^uint64(0))
+	uint128Seed     = new(big.Int).Add(new(big.Int).Lsh(big.NewInt(1), 100), big.NewInt(5))
+)
+
+// newVRFOracleClient returns a client whose vrf oracle commands use the store
+func newVRFOracleClient(t *testing.T) (*cmd.Client, *store.Store, func()) {
+	store, cleanup := cltest.NewStore(t)
+	app := new(mocks.Application)
+	app.On("GetStore").Return(store)
+	client := &cmd.Client{
+		Config:     store.Config,
+		AppFactory: cltest.InstanceAppFactory{App: app},
+	}
+	return client, store, cleanup
+}
+
+const testContractKey = "5KQwrPbwdL6PhXujxW37FSSQZ1JiwsST4cqQzDeyXtP79zkvFD3"
+
+// stubEOSNode a node that accepts the pushed transactions and records their actions, unless reject is set
+type stubEOSNode struct {
+	mu      sync.Mutex
+	reject  bool
+	actions []string
+}
+
+func (m *stubEOSNode) pushedActions() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]string{}, m.actions...)
+}
+
+func (m *stubEOSNode) serve(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		switch r.URL.Path {
+		case "/v1/chain/get_info":
+			fmt.Fprintf(w, `{"chain_id": "%064x", "head_block_num": 1000, "head_block_id": "%08x%056x", "last_irreversible_block_num": 990}`, 1, 1000, 0)
+		case "/v1/chain/get_required_keys":
+			fmt.Fprint(w, `{"required_keys": ["EOS6MRyAjQq8ud7hVNYcfnVPJqcVpscN5So8BhtHuGYqET5GDW5CV"]}`)
+		case "/v1/chain/push_transaction":
+			if m.reject {
+				w.WriteHeader(http.StatusInternalServerError)
+				fmt.Fprint(w, `{"code": 500, "message": "Internal Service Error", "error": {"code": 3050003, "name": "eosio_assert_message_exception", "what": "eosio_assert_message assertion failure", "details": []}}`)
+				return
+			}
+			var packed eosc.PackedTransaction
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&packed))
+			signed, err := packed.Unpack()
+			require.NoError(t, err)
+			for _, action := range signed.Actions {
+				m.actions = append(m.actions, string(action.Name))
+			}
+			id, err := packed.ID()
+			require.NoError(t, err)
+			fmt.Fprintf(w, `{"transaction_id": "%v", "processed": {"id": "%v", "block_num": 1001}}`, id, id)
+		default:
+			require.FailNow(t, "Unexpected call", r.URL.Path)
+		}
+	}))
+}
+
+// useStubEOSNode points the client to the node, pushing the actions to the vrf contract with the contract key
+func useStubEOSNode(client *cmd.Client, server *httptest.Server) {
+	client.Config.Set("EOS_URL", server.URL)
+	client.Config.Set("VRF_CONTRACT", "vrf")
+	client.Config.Set("VRF_CONTRACT_KEY", testContractKey)
+}
+
+func newTestVRFProof(t *testing.T, seed *big.Int) *vrf.EOSProofResponse {
+	preSeed, err := vrf.BigToSeed(seed)
+	require.NoError(t, err)
+	proof, err := testVRFKey.EOSProof(vrf.PreSeedData{
+		PreSeed:   preSeed,
+		BlockHash: common.HexToHash("0x0000abcd"),
+		BlockNum:  10,
+	})
+	require.NoError(t, err)
+	return proof
+}
+
+// chainRenderedProof renders the proof the way the chain does, with the seed rendered as its abi type,
+// large 64 bit integers as strings and checksums without 0x prefix
+func chainRenderedProof(t *testing.T, proof *vrf.EOSProofResponse, seed string, tamper func(map[string]interface{})) string {
+	rendered := map[string]interface{}{
+		"block_num":   proof.BlockNum,
+		"block_id":    strings.TrimPrefix(proof.BlockID, "0x"),
+		"seed":        json.RawMessage(seed),
+		"final_seed":  proof.FinalSeed,
+		"public_key":  proof.PublicKey,
+		"gamma":       proof.Gamma,
+		"c":           proof.C,
+		"s":           proof.S,
+		"output_u256": proof.OutputU256,
+		"output_u64":  strconv.FormatUint(proof.OutputU64, 10),
+	}
+	if tamper != nil {
+		tamper(rendered)
+	}
+	data, err := json.Marshal(rendered)
+	require.NoError(t, err)
+	return string(data)
+}
+
+func chainRenderedUint128(t *testing.T, value *big.Int) string {
+	lo := new(big.Int).And(value, new(big.Int).SetUint64(^uint64(0)))
+	hi := new(big.Int).Rsh(value, 64)
+	data, err := json.Marshal(eosc.Uint128{Lo: lo.Uint64(), Hi: hi.Uint64()})
+	require.NoError(t, err)
+	return string(data)
+}
+
+func setRandData(proofs ...string) string {
+	return fmt.Sprintf(`{"assoc_id":"1","caller":"alice","proofs":[%v]}`, strings.Join(proofs, ","))
+}
+
+func transactionWithActions(actions ...string) string {
+	return fmt.Sprintf(`{"id":"abcd","trx":{"receipt":{"status":"executed"},"trx":{"expiration":"2021-04-10T00:00:00","actions":[%v]}},"block_num":11}`, strings.Join(actions, ","))
+}
+
+func action(name, data string) string {
+	return fmt.Sprintf(`{"account":"vrf","name":"%v","authorization":[{"actor":"vrf","permission":"active"}],"data":%v}`, name, data)
+}
+
+func TestClient_DecodeVRFProofs(t *testing.T) {
+	proof42 := newTestVRFProof(t, big.NewInt(42))
+	proofMaxUint64 := newTestVRFProof(t, maxUint64Seed)
+	proofUint128 := newTestVRFProof(t, uint128Seed)
+	point, err := testVRFKey.PublicKey.Point()
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		data     string
+		seedType string
+		seeds    []*big.Int
+	}{
+		{"uint64 seed rendered as a number", chainRenderedProof(t, proof42, "42", nil), vrf.EOSSeedType_UINT64, []*big.Int{big.NewInt(42)}},
+		{"uint64 seed rendered as a string", chainRenderedProof(t, proofMaxUint64, `"18446744073709551615"`, nil), vrf.EOSSeedType_UINT64, []*big.Int{maxUint64Seed}},
+		{"uint128 seed rendered as little endian hex", chainRenderedProof(t, proofUint128, chainRenderedUint128(t, uint128Seed), nil), vrf.EOSSeedType_UINT128, []*big.Int{uint128Seed}},
+		{"uint128 seed rendered as a decimal string", chainRenderedProof(t, proofUint128, `"`+uint128Seed.Text(10)+`"`, nil), vrf.EOSSeedType_UINT128, []*big.Int{uint128Seed}},
+		{"array of proofs", "[" + chainRenderedProof(t, proof42, "42", nil) + "," + chainRenderedProof(t, proofMaxUint64, `"18446744073709551615"`, nil) + "]", vrf.EOSSeedType_UINT64, []*big.Int{big.NewInt(42), maxUint64Seed}},
+		{"setrand action data", setRandData(chainRenderedProof(t, proofUint128, chainRenderedUint128(t, uint128Seed), nil)), vrf.EOSSeedType_UINT128, []*big.Int{uint128Seed}},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			proofs, err := cmd.ExportedDecodeVRFProofs([]byte(test.data), test.seedType)
+			require.NoError(t, err)
+			require.Len(t, proofs, len(test.seeds))
+			for i, proof := range proofs {
+				assert.Equal(t, test.seedType, proof.Seed.Type)
+				assert.Equal(t, 0, test.seeds[i].Cmp(proof.Seed.Value))
+				assert.NoError(t, proof.Verify(point))
+			}
+		})
+	}
+}
+
+func TestClient_DecodeVRFProofsErrors(t *testing.T) {
+	proofUint128 := newTestVRFProof(t, uint128Seed)
+
+	tests := []struct {
+		name     string
+		data     string
+		seedType string
+	}{
+		{"seed that does not fit in the seed type", chainRenderedProof(t, proofUint128, `"`+uint128Seed.Text(10)+`"`, nil), vrf.EOSSeedType_UINT64},
+		{"seed that is not a number", chainRenderedProof(t, proofUint128, `"abc"`, nil), vrf.EOSSeedType_UINT128},
+		{"malformed json", `{"proofs":[`, vrf.EOSSeedType_UINT64},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			_, err := cmd.ExportedDecodeVRFProofs([]byte(test.data), test.seedType)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestClient_SetRandProofsFromTransaction(t *testing.T) {
+	proof42 := newTestVRFProof(t, big.NewInt(42))
+	proofMaxUint64 := newTestVRFProof(t, maxUint64Seed)
+	transfer := action("transfer", `{"from":"alice","to":"bob","quantity":"1.0000 EOS","memo":""}`)
+
+	tests := []struct {
+		name   string
+		tx     string
+		proofs int
+	}{
+		{"setrand actions", transactionWithActions(
+			action("setrand", setRandData(chainRenderedProof(t, proof42, "42", nil))),
+			transfer,
+			action("setrand", setRandData(chainRenderedProof(t, proofMaxUint64, `"18446744073709551615"`, nil))),
+		), 2},
+		{"without setrand actions", transactionWithActions(transfer), 0},
+		{"without actions", transactionWithActions(), 0},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			proofs, err := cmd.ExportedSetRandProofsFromTransaction(json.RawMessage(test.tx), vrf.EOSSeedType_UINT64)
+			require.NoError(t, err)
+			assert.Len(t, proofs, test.proofs)
+		})
+	}
+}
+
+func TestClient_VerifyVRFProofs(t *testing.T) {
+	proof42 := newTestVRFProof(t, big.NewInt(42))
+	proofMaxUint64 := newTestVRFProof(t, maxUint64Seed)
+	valid := chainRenderedProof(t, proof42, "42", nil)
+	tampered := chainRenderedProof(t, proof42, "42", func(rendered map[string]interface{}) {
+		rendered["output_u64"] = strconv.FormatUint(proof42.OutputU64+1, 10)
+	})
+	transfer := action("transfer", `{"from":"alice","to":"bob","quantity":"1.0000 EOS","memo":""}`)
+	withSetRand := transactionWithActions(action("setrand", setRandData(valid, chainRenderedProof(t, proofMaxUint64, `"18446744073709551615"`, nil))))
+
+	tests := []struct {
+		name      string
+		publicKey vrfkey.PublicKey
+		args      []string
+		tx        string
+		wantError bool
+	}{
+		{"valid proof", testVRFKey.PublicKey, []string{valid}, "", false},
+		{"valid setrand action data", testVRFKey.PublicKey, []string{setRandData(valid)}, "", false},
+		{"tampered proof", testVRFKey.PublicKey, []string{tampered}, "", true},
+		{"valid and tampered proofs", testVRFKey.PublicKey, []string{"[" + valid + "," + tampered + "]"}, "", true},
+		{"untrusted key", untrustedVRFKey.PublicKey, []string{valid}, "", true},
+		{"deriving values", testVRFKey.PublicKey, []string{"--count", "3", "--max", "10", valid}, "", false},
+		{"tx with setrand actions", testVRFKey.PublicKey, nil, withSetRand, false},
+		{"tx without setrand actions", testVRFKey.PublicKey, nil, transactionWithActions(transfer), true},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			eosNode := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "/v1/history/get_transaction", r.URL.Path)
+				_, err := w.Write([]byte(test.tx))
+				assert.NoError(t, err)
+			}))
+			defer eosNode.Close()
+
+			config := orm.NewConfig()
+			config.Set("VRF_SEED_TYPE", vrf.EOSSeedType_UINT64)
+			client := cmd.Client{Config: config}
+
+			set := flag.NewFlagSet("test", 0)
+			set.String("publicKey", "", "")
+			set.String("file", "", "")
+			set.String("tx", "", "")
+			set.String("url", "", "")
+			set.Uint("count", 0, "")
+			set.Uint64("min", 0, "")
+			set.Uint64("max", 0, "")
+			set.Bool("unique", false, "")
+			args := []string{"--publicKey", test.publicKey.String()}
+			if test.tx != "" {
+				args = append(args, "--tx", "abcd", "--url", eosNode.URL)
+			}
+			require.NoError(t, set.Parse(append(args, test.args...)))
+
+			err := client.VerifyVRFProofs(cli.NewContext(nil, set, nil))
+			if test.wantError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestClient_ListAndReplayVRFDeadLetters(t *testing.T) {
+	t.Parallel()
+	client, store, cleanup := newVRFOracleClient(t)
+	defer cleanup()
+
+	newDeadLetter := func(cursor string) *models.VRFDeadLetter {
+		deadLetter := &models.VRFDeadLetter{
+			Code:      "vrf",
+			JobTable:  "jobs",
+			Scope:     "user1",
+			Operation: "OPERATION_INSERT",
+			ForkStep:  "STEP_NEW",
+			NewData:   `{"assoc_id":"a"}`,
+			BlockNum:  395235,
+			BlockHash: "000607e21b8d9da0ade735bcd19a9f8812335b510482d4b9f8c53753e0ebe9c8",
+			Cursor:    cursor,
+			Error:     "Malformed delta, error unmarshalling",
+			Status:    models.VRFDeadLetterStatus_FAILED,
+		}
+		require.NoError(t, store.CreateVRFDeadLetter(deadLetter, nil))
+		return deadLetter
+	}
+	dl1 := newDeadLetter("c1")
+	newDeadLetter("c2")
+
+	set := flag.NewFlagSet("deadletters", 0)
+	set.String("status", "", "")
+	require.NoError(t, client.ListVRFDeadLetters(cli.NewContext(nil, set, nil)))
+
+	replay := func(args ...string) error {
+		set := flag.NewFlagSet("replay", 0)
+		set.Bool("all", false, "")
+		require.NoError(t, set.Parse(args))
+		return client.ReplayVRFDeadLetters(cli.NewContext(nil, set, nil))
+	}
+	assert.Error(t, replay())
+	assert.Error(t, replay("x"))
+
+	require.NoError(t, replay(strconv.FormatUint(dl1.ID, 10)))
+	deadLetters, err := store.FindVRFDeadLetters(models.VRFDeadLetterStatus_REPLAY)
+	require.NoError(t, err)
+	require.Len(t, deadLetters, 1)
+	assert.Equal(t, dl1.ID, deadLetters[0].ID)
+
+	require.NoError(t, replay("--all"))
+	deadLetters, err = store.FindVRFDeadLetters(models.VRFDeadLetterStatus_REPLAY)
+	require.NoError(t, err)
+	assert.Len(t, deadLetters, 2)
+}
+
+func TestClient_ShowVRFPublicKeys(t *testing.T) {
+	t.Parallel()
+	client, store, cleanup := newVRFOracleClient(t)
+	defer cleanup()
+	node := &stubEOSNode{}
+	server := node.serve(t)
+	defer server.Close()
+	useStubEOSNode(client, server)
+
+	key, err := store.VRFKeyStore.CreateKey(cltest.Password)
+	require.NoError(t, err)
+
+	pubkey := func(args ...string) error {
+		set := flag.NewFlagSet("pubkey", 0)
+		set.String("publicKey", "", "")
+		set.Bool("register", false, "")
+		require.NoError(t, set.Parse(args))
+		return client.ShowVRFPublicKeys(cli.NewContext(nil, set, nil))
+	}
+	require.NoError(t, pubkey())
+	require.NoError(t, pubkey("--publicKey", key.String()))
+	assert.Error(t, pubkey("--publicKey", "x"))
+	assert.Empty(t, node.pushedActions())
+
+	//Registering requires selecting the key
+	assert.Error(t, pubkey("--register"))
+	assert.Empty(t, node.pushedActions())
+
+	require.NoError(t, pubkey("--publicKey", key.String(), "--register"))
+	assert.Equal(t, []string{"setpubkey"}, node.pushedActions())
+
+	node.mu.Lock()
+	node.reject = true
+	node.mu.Unlock()
+	assert.Error(t, pubkey("--publicKey", key.String(), "--register"))
+	assert.Equal(t, []string{"setpubkey"}, node.pushedActions())
+}
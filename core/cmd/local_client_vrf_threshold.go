@@ -0,0 +1,147 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strconv"
+
+	"github.com/pkg/errors"
+	clipkg "github.com/urfave/cli"
+
+	"github.com/sebastianmontero/vrf-oracle/core/services/vrf"
+	"github.com/sebastianmontero/vrf-oracle/core/store/models/vrfkey"
+	"github.com/sebastianmontero/vrf-oracle/core/utils"
+)
+
+// CreateVRFKeyShares creates a threshold vrf group key and writes its shares to the directory specified, each
+// one encrypted with the password in the password file. The group secret is not stored anywhere, so each share
+// file must be imported by a different oracle and then deleted
+func (cli *Client) CreateVRFKeyShares(c *clipkg.Context) error {
+	password, err := getPassword(c)
+	if err != nil {
+		return cli.errorOut(err)
+	}
+	dir := c.String("dir")
+	if dir == "" {
+		return cli.errorOut(errors.New("must specify the directory to write the shares to"))
+	}
+	shares, err := vrfkey.GenerateKeyShares(c.Int("threshold"), c.Int("nodes"))
+	if err != nil {
+		return cli.errorOut(err)
+	}
+	for _, keyShare := range shares {
+		path := filepath.Join(dir, fmt.Sprintf("share-%v.json", keyShare.Index))
+		if !noFileToOverwrite(path) {
+			return cli.errorOut(fmt.Errorf("share file %s already exists", path))
+		}
+		encrypted, err := keyShare.Encrypt(string(password), utils.GetScryptParams(cli.Config))
+		if err != nil {
+			return cli.errorOut(err)
+		}
+		err = encrypted.WriteToDisk(path)
+		if err != nil {
+			return cli.errorOut(errors.Wrapf(err, "while writing share %v", keyShare.Index))
+		}
+	}
+	fmt.Printf(`Created %v shares of group key %s, any %v of them produce its vrf output.
+
+Share i was written to %s, each oracle must import a different share with:
+
+chainlink local vrf threshold import -f <share_file> -p <password_file>
+
+and set VRF_THRESHOLD_GROUP_KEY=%s and VRF_THRESHOLD_PEER_IDS to the p2p peer ids of the oracles, in the order of their shares.
+The following command registers the group key in the vrf contract:
+
+chainlink local vrf threshold register -gk %s
+`, len(shares), shares[0].GroupPublicKey(), shares[0].Threshold(), filepath.Join(dir, "share-<i>.json"),
+		shares[0].GroupPublicKey(), shares[0].GroupPublicKey())
+	return nil
+}
+
+// ImportVRFKeyShare decrypts a share file with the password in the password file and stores it in the db
+// protected by VRF_KEY_STORE_PASSWORD, the password the oracle unlocks its keys with
+func (cli *Client) ImportVRFKeyShare(c *clipkg.Context) error {
+	password, shareJSON, err := getPasswordAndKeyFile(c)
+	if err != nil {
+		return cli.errorOut(err)
+	}
+	if cli.Config.VRFKeyStorePassword() == "" {
+		return cli.errorOut(errors.New("must set VRF_KEY_STORE_PASSWORD"))
+	}
+	var encrypted vrfkey.EncryptedKeyShare
+	if err := json.Unmarshal(shareJSON, &encrypted); err != nil {
+		return cli.errorOut(errors.Wrap(err, "invalid share file"))
+	}
+	keyShare, err := encrypted.Decrypt(string(password))
+	if err != nil {
+		return cli.errorOut(err)
+	}
+	reEncrypted, err := keyShare.Encrypt(cli.Config.VRFKeyStorePassword(), utils.GetScryptParams(cli.Config))
+	if err != nil {
+		return cli.errorOut(err)
+	}
+	err = vrfOracleStore(cli).CreateEncryptedVRFKeyShare(reEncrypted)
+	if err != nil {
+		return cli.errorOut(errors.Wrap(err, "while storing share, the db may already hold a share of the group key"))
+	}
+	fmt.Printf("Imported share %v of group key %s\n", keyShare.Index, keyShare.GroupPublicKey())
+	return nil
+}
+
+// ListVRFKeyShares lists the shares of threshold vrf group keys in the db
+func (cli *Client) ListVRFKeyShares(c *clipkg.Context) error {
+	shares, err := vrfOracleStore(cli).FindEncryptedVRFKeyShares()
+	if err != nil {
+		return cli.errorOut(errors.Wrap(err, "while listing key shares"))
+	}
+	var rows [][]string
+	for _, keyShare := range shares {
+		rows = append(rows, []string{
+			keyShare.GroupPublicKey.String(),
+			strconv.Itoa(keyShare.Index),
+			fmt.Sprintf("%v of %v", len(keyShare.Commitments), keyShare.Nodes),
+			keyShare.PublicKey.String(),
+		})
+	}
+	fmt.Println("\n🔑 VRF Key Shares")
+	renderList([]string{"Group Public Key", "Share", "Threshold", "Share Public Key"}, rows)
+	return nil
+}
+
+// RegisterVRFGroupKey registers the group key of a share in the db in the contract, by pushing setgroupkey with
+// the commitments to the polynomial the key was shared with, which verify the partial proofs of the shares
+func (cli *Client) RegisterVRFGroupKey(c *clipkg.Context) error {
+	if !c.IsSet("groupKey") {
+		return cli.errorOut(errors.New("must specify the group key to register"))
+	}
+	groupKey, err := vrfkey.NewPublicKeyFromHex(c.String("groupKey"))
+	if err != nil {
+		return cli.errorOut(errors.Wrap(err, "failed to parse group key"))
+	}
+	keyShare, err := vrfOracleStore(cli).FindEncryptedVRFKeyShare(groupKey)
+	if err != nil {
+		return cli.errorOut(errors.Wrap(err, "while finding key share"))
+	}
+	if keyShare == nil {
+		return cli.errorOut(fmt.Errorf("there is no share of group key %s in the db", groupKey))
+	}
+	commitments := make([]string, 0, len(keyShare.Commitments))
+	for _, commitment := range keyShare.Commitments {
+		point, err := commitment.Point()
+		if err != nil {
+			return cli.errorOut(err)
+		}
+		commitments = append(commitments, vrf.EncodeEOSPublicKey(point))
+	}
+	contract, err := vrfContract(cli)
+	if err != nil {
+		return cli.errorOut(err)
+	}
+	resp, err := contract.SetGroupKey(commitments)
+	if err != nil {
+		return cli.errorOut(errors.Wrap(err, "while pushing setgroupkey"))
+	}
+	fmt.Printf("Registered group key %v in contract %v, transaction: %v\n", groupKey, contract.ContractName, resp.TransactionID)
+	return nil
+}
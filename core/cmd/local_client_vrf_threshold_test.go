@@ -0,0 +1,86 @@
+package cmd_test
+
+import (
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/urfave/cli"
+
+	"github.com/sebastianmontero/vrf-oracle/core/internal/cltest"
+	"github.com/sebastianmontero/vrf-oracle/core/store/models/vrfkey"
+)
+
+func TestClient_VRFKeyShares(t *testing.T) {
+	t.Parallel()
+	client, store, cleanup := newVRFOracleClient(t)
+	defer cleanup()
+	node := &stubEOSNode{}
+	server := node.serve(t)
+	defer server.Close()
+	useStubEOSNode(client, server)
+	client.Config.Set("VRF_KEY_STORE_PASSWORD", cltest.Password)
+	dir, err := ioutil.TempDir("", "vrf-key-shares")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	keygen := func(threshold, nodes int) error {
+		set := flag.NewFlagSet("keygen", 0)
+		set.Int("threshold", threshold, "")
+		set.Int("nodes", nodes, "")
+		set.String("dir", dir, "")
+		set.String("password", "../internal/fixtures/correct_password.txt", "")
+		return client.CreateVRFKeyShares(cli.NewContext(nil, set, nil))
+	}
+	assert.Error(t, keygen(4, 3))
+	require.NoError(t, keygen(2, 3))
+	sharePaths, err := filepath.Glob(filepath.Join(dir, "share-*.json"))
+	require.NoError(t, err)
+	require.Len(t, sharePaths, 3)
+	//The shares are not overwritten
+	assert.Error(t, keygen(2, 3))
+
+	sharePath := filepath.Join(dir, "share-0.json")
+	shareJSON, err := ioutil.ReadFile(sharePath)
+	require.NoError(t, err)
+	var encrypted vrfkey.EncryptedKeyShare
+	require.NoError(t, json.Unmarshal(shareJSON, &encrypted))
+	assert.Equal(t, 3, encrypted.Nodes)
+
+	importShare := func(password string) error {
+		set := flag.NewFlagSet("import", 0)
+		set.String("file", "", "")
+		set.String("password", "", "")
+		require.NoError(t, set.Parse([]string{"--file", sharePath, "--password", password}))
+		return client.ImportVRFKeyShare(cli.NewContext(nil, set, nil))
+	}
+	assert.Error(t, importShare("../internal/fixtures/incorrect_password.txt"))
+	require.NoError(t, importShare("../internal/fixtures/correct_password.txt"))
+	//The db holds one share of each group key
+	assert.Error(t, importShare("../internal/fixtures/correct_password.txt"))
+	shares, err := store.FindEncryptedVRFKeyShares()
+	require.NoError(t, err)
+	require.Len(t, shares, 1)
+	assert.Equal(t, encrypted.GroupPublicKey, shares[0].GroupPublicKey)
+	assert.Equal(t, 0, shares[0].Index)
+	assert.Len(t, shares[0].Commitments, 2)
+
+	require.NoError(t, client.ListVRFKeyShares(cli.NewContext(nil, flag.NewFlagSet("list", 0), nil)))
+
+	register := func(args ...string) error {
+		set := flag.NewFlagSet("register", 0)
+		set.String("groupKey", "", "")
+		require.NoError(t, set.Parse(args))
+		return client.RegisterVRFGroupKey(cli.NewContext(nil, set, nil))
+	}
+	assert.Error(t, register())
+	assert.Error(t, register("--groupKey", testVRFKey.PublicKey.String()))
+	assert.Empty(t, node.pushedActions())
+	require.NoError(t, register("--groupKey", encrypted.GroupPublicKey.String()))
+	assert.Equal(t, []string{"setgroupkey"}, node.pushedActions())
+}
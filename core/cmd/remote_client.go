@@ -552,6 +552,48 @@ func (cli *Client) ShowTransaction(c *clipkg.Context) (err error) {
 	return err
 }
 
+// RetryVRFRequest processes the last job of a VRF request again
+func (cli *Client) RetryVRFRequest(c *clipkg.Context) (err error) {
+	if !c.Args().Present() {
+		return cli.errorOut(errors.New("Must pass the ID of the VRF request to retry"))
+	}
+	resp, err := cli.HTTP.Post("/v2/vrf_requests/"+c.Args().First()+"/retry", nil)
+	if err != nil {
+		return cli.errorOut(err)
+	}
+	defer func() {
+		if cerr := resp.Body.Close(); cerr != nil {
+			err = multierr.Append(err, cerr)
+		}
+	}()
+	var req presenters.VRFRequest
+	err = cli.renderAPIResponse(resp, &req)
+	return err
+}
+
+// CancelVRFRequest cancels a VRF request
+func (cli *Client) CancelVRFRequest(c *clipkg.Context) (err error) {
+	if !c.Args().Present() {
+		return cli.errorOut(errors.New("Must pass the ID of the VRF request to cancel"))
+	}
+	request, err := json.Marshal(web.VRFRequestCancel{Reason: c.String("reason")})
+	if err != nil {
+		return cli.errorOut(err)
+	}
+	resp, err := cli.HTTP.Post("/v2/vrf_requests/"+c.Args().First()+"/cancel", bytes.NewReader(request))
+	if err != nil {
+		return cli.errorOut(err)
+	}
+	defer func() {
+		if cerr := resp.Body.Close(); cerr != nil {
+			err = multierr.Append(err, cerr)
+		}
+	}()
+	var req presenters.VRFRequest
+	err = cli.renderAPIResponse(resp, &req)
+	return err
+}
+
 // IndexTxAttempts returns the list of transactions in descending order,
 // taking an optional page parameter
 func (cli *Client) IndexTxAttempts(c *clipkg.Context) error {
@@ -18,6 +18,7 @@ import (
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/jinzhu/gorm"
+	"github.com/lib/pq"
 	"github.com/pelletier/go-toml"
 	"github.com/sebastianmontero/vrf-oracle/core/auth"
 	"github.com/sebastianmontero/vrf-oracle/core/cmd"
@@ -1134,6 +1135,100 @@ func TestClient_CancelJobRun(t *testing.T) {
 	assert.NotNil(t, runs[0].FinishedAt)
 }
 
+func mustInsertVRFRequestWithJob(t *testing.T, store *store.Store) (*models.VRFRequest, *models.VRFRequestJob) {
+	req := &models.VRFRequest{
+		AssocID:   1,
+		BlockNum:  100,
+		BlockHash: "000607e21b8d9da0ade735bcd19a9f8812335b510482d4b9f8c53753e0ebe9c8",
+		Seeds:     pq.StringArray([]string{"2"}),
+		Count:     1,
+		Caller:    "user1",
+		Type:      models.VRFRequestType_IMMEDIATE,
+		Status:    models.VRFRequestStatus_ACTIVE,
+	}
+	require.NoError(t, store.CreateVRFRequest(req, nil))
+	job := models.NewVRFRequestJob(req)
+	require.NoError(t, store.SaveVRFRequestJob(job))
+	return req, job
+}
+
+func TestClient_RetryVRFRequest(t *testing.T) {
+	t.Parallel()
+
+	rpcClient, gethClient, _, assertMocksCalled := cltest.NewEthMocksWithStartupAssertions(t)
+	defer assertMocksCalled()
+	app, cleanup := cltest.NewApplication(t,
+		eth.NewClientWith(rpcClient, gethClient),
+	)
+	defer cleanup()
+	require.NoError(t, app.Start())
+
+	req, job := mustInsertVRFRequestWithJob(t, app.Store)
+	client, r := app.NewClientAndRenderer()
+
+	retry := func(args ...string) error {
+		set := flag.NewFlagSet("retry", 0)
+		require.NoError(t, set.Parse(args))
+		return client.RetryVRFRequest(cli.NewContext(nil, set, nil))
+	}
+	assert.Error(t, retry())
+	//The job has not failed
+	assert.Error(t, retry(strconv.FormatUint(req.ID, 10)))
+	assert.Error(t, retry(strconv.FormatUint(req.ID+1, 10)))
+	assert.Empty(t, r.Renders)
+
+	run := models.NewVRFRequestRun(job)
+	run.UpdateStatus(models.VRFRequestRunStatus_FAILED, "Calling SetRand on contract failed", 0)
+	require.NoError(t, app.Store.SaveVRFRequestRun(run, true))
+
+	require.NoError(t, retry(strconv.FormatUint(req.ID, 10)))
+	require.Len(t, r.Renders, 1)
+	preq := r.Renders[0].(*presenters.VRFRequest)
+	assert.Equal(t, req.ID, preq.ID)
+	assert.Equal(t, models.VRFRequestStatus(models.VRFRequestStatus_ACTIVE), preq.Status)
+	require.Len(t, preq.Jobs, 2)
+	assert.Equal(t, models.VRFRequestJobStatus(models.VRFRequestJobStatus_FAILED), preq.Jobs[0].Status)
+	assert.Equal(t, models.VRFRequestJobStatus(models.VRFRequestJobStatus_ACTIVE), preq.Jobs[1].Status)
+}
+
+func TestClient_CancelVRFRequest(t *testing.T) {
+	t.Parallel()
+
+	rpcClient, gethClient, _, assertMocksCalled := cltest.NewEthMocksWithStartupAssertions(t)
+	defer assertMocksCalled()
+	app, cleanup := cltest.NewApplication(t,
+		eth.NewClientWith(rpcClient, gethClient),
+	)
+	defer cleanup()
+	require.NoError(t, app.Start())
+
+	req, _ := mustInsertVRFRequestWithJob(t, app.Store)
+	client, r := app.NewClientAndRenderer()
+
+	cancel := func(args ...string) error {
+		set := flag.NewFlagSet("cancel", 0)
+		set.String("reason", "", "")
+		require.NoError(t, set.Parse(args))
+		return client.CancelVRFRequest(cli.NewContext(nil, set, nil))
+	}
+	assert.Error(t, cancel("--reason", "contract redeployed"))
+	assert.Error(t, cancel("--reason", "contract redeployed", strconv.FormatUint(req.ID+1, 10)))
+	assert.Empty(t, r.Renders)
+
+	require.NoError(t, cancel("--reason", "contract redeployed", strconv.FormatUint(req.ID, 10)))
+	require.Len(t, r.Renders, 1)
+	preq := r.Renders[0].(*presenters.VRFRequest)
+	assert.Equal(t, models.VRFRequestStatus(models.VRFRequestStatus_CANCELLED), preq.Status)
+	require.Len(t, preq.Jobs, 1)
+	assert.Equal(t, models.VRFRequestJobStatus(models.VRFRequestJobStatus_FAILED), preq.Jobs[0].Status)
+	require.Len(t, preq.Jobs[0].Runs, 1)
+	assert.Equal(t, "Cancelled by an operator: contract redeployed", preq.Jobs[0].Runs[0].StatusMsg)
+
+	//A cancelled request can not be cancelled again
+	assert.Error(t, cancel(strconv.FormatUint(req.ID, 10)))
+	assert.Len(t, r.Renders, 1)
+}
+
 func TestClient_P2P_CreateKey(t *testing.T) {
 	t.Parallel()
 
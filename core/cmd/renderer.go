@@ -69,6 +69,8 @@ func (rt RendererTable) Render(v interface{}) error {
 		return rt.renderEthTxs(*typed)
 	case *presenters.EthTx:
 		return rt.renderEthTx(*typed)
+	case *presenters.VRFRequest:
+		return rt.renderVRFRequest(*typed)
 	case *presenters.ExternalInitiatorAuthentication:
 		return rt.renderExternalInitiatorAuthentication(*typed)
 	case *web.ConfigPatchResponse:
@@ -373,6 +375,46 @@ func (rt RendererTable) renderEthTxs(txs []presenters.EthTx) error {
 	return nil
 }
 
+func (rt RendererTable) renderVRFRequest(req presenters.VRFRequest) error {
+	table := rt.newTable([]string{"ID", "Assoc ID", "Caller", "Type", "Status", "Block Num"})
+	table.Append([]string{
+		req.GetID(),
+		strconv.FormatUint(req.AssocID, 10),
+		req.Caller,
+		string(req.Type),
+		string(req.Status),
+		strconv.FormatUint(req.BlockNum, 10),
+	})
+	render("VRF Request", table)
+
+	jobs := rt.newTable([]string{"Job ID", "Block Num", "Status", "Retries", "Run ID", "Run Status", "Status Msg", "Tx ID"})
+	for _, job := range req.Jobs {
+		if len(job.Runs) == 0 {
+			jobs.Append([]string{
+				strconv.FormatUint(job.ID, 10),
+				strconv.FormatUint(job.BlockNum, 10),
+				string(job.Status),
+				strconv.Itoa(int(job.Retries)),
+				"", "", "", "",
+			})
+		}
+		for _, run := range job.Runs {
+			jobs.Append([]string{
+				strconv.FormatUint(job.ID, 10),
+				strconv.FormatUint(job.BlockNum, 10),
+				string(job.Status),
+				strconv.Itoa(int(job.Retries)),
+				strconv.FormatUint(run.ID, 10),
+				string(run.Status),
+				run.StatusMsg,
+				run.TxID,
+			})
+		}
+	}
+	render("Jobs", jobs)
+	return nil
+}
+
 func (rt RendererTable) renderConfigPatchResponse(config *web.ConfigPatchResponse) error {
 	table := rt.newTable([]string{"Config", "Old Value", "New Value"})
 	table.Append([]string{
@@ -1,14 +1,18 @@
 package main
 
 import (
-	pbbstream "github.com/dfuse-io/pbgo/dfuse/bstream/v1"
+	"fmt"
+	"net/http"
 
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/sebastianmontero/dfuse-firehose-client/dfclient"
 	"github.com/sebastianmontero/vrf-oracle/core/gracefulpanic"
 	"github.com/sebastianmontero/vrf-oracle/core/logger"
 	"github.com/sebastianmontero/vrf-oracle/core/services/eos"
 	"github.com/sebastianmontero/vrf-oracle/core/services/eos/contracts"
 	"github.com/sebastianmontero/vrf-oracle/core/services/eth"
+	"github.com/sebastianmontero/vrf-oracle/core/services/offchainreporting"
 	"github.com/sebastianmontero/vrf-oracle/core/services/svrf"
 
 	"github.com/sebastianmontero/vrf-oracle/core/services/postgres"
@@ -17,10 +21,16 @@ import (
 	"github.com/sebastianmontero/vrf-oracle/core/store/models/vrfkey"
 	"github.com/sebastianmontero/vrf-oracle/core/store/orm"
 	"github.com/sebastianmontero/vrf-oracle/core/streamhandlers"
+	"github.com/sebastianmontero/vrf-oracle/core/utils"
+	"github.com/sebastianmontero/vrf-oracle/core/web"
 )
 
 func main() {
 	config := orm.NewConfig()
+	err := config.Validate()
+	if err != nil {
+		logger.Panic("Invalid configuration: ", err)
+	}
 	advisoryLocker := postgres.NewAdvisoryLock(config.DatabaseURL())
 	shutdownSignal := gracefulpanic.NewSignal()
 	store := strpkg.NewStore(config, &eth.NullClient{}, advisoryLocker, shutdownSignal, strpkg.StandardKeyStoreGen)
@@ -28,10 +38,13 @@ func main() {
 	store.Start()
 	defer store.Close()
 
-	key, err := getVRFKey(store, config)
+	keyLoader := svrf.NewVRFKeyLoader(store, config.VRFKeyStorePassword(), config.VRFDefaultKey(), config.VRFKeyReloadInterval())
+	err = keyLoader.Load()
 	if err != nil {
-		logger.Panic("Error getting vrf key: ", err)
+		logger.Panic("Error getting vrf keys: ", err)
 	}
+	keyLoader.Start()
+	defer keyLoader.Stop()
 
 	client, err := dfclient.NewDfClient(config.FirehoseEndpoint(), config.DFuseAPIKey(), config.EOSURL(), nil)
 	if err != nil {
@@ -42,43 +55,164 @@ func main() {
 	if err != nil {
 		logger.Panic("Error getting cursor: ", err)
 	}
-	eos, err := eos.New(config.EOSURL(), []string{config.VRFContractKey()})
+	eos, err := getEOS(config, store)
 	if err != nil {
 		logger.Panic("Error creating eos instance: ", err)
 	}
+	eos.TxManager.Start()
+	defer eos.TxManager.Stop()
+	if config.VRFMetricsPort() > 0 {
+		go serveMetrics(config.VRFMetricsPort())
+	}
+	if config.VRFAPIPort() > 0 {
+		go serveAPI(store, config.VRFAPIPort())
+	}
 	vrfContract := contracts.NewVRF(config.VRFContract(), eos)
+	vrfContract.Actor = config.VRFFulfilmentActor()
+	vrfContract.Permission = config.VRFFulfilmentPermission()
 	vrfRequestChannel := make(chan *models.VRFRequest, 20)
-	vrfResolver := svrf.NewVRFResolver(store, vrfContract, &key)
+	renter, err := getResourceRenter(store, eos, config)
+	if err != nil {
+		logger.Panic("Error creating resource renter: ", err)
+	}
+	vrfResolver := svrf.NewVRFResolver(store, vrfContract, keyLoader.Keys, renter)
+	if config.VRFThresholdGroupKey() != "" {
+		peerWrapper, threshold, err := getThreshold(store, vrfContract, config)
+		if err != nil {
+			logger.Panic("Error creating vrf threshold mode: ", err)
+		}
+		defer peerWrapper.Close()
+		vrfResolver.Threshold = threshold
+	}
+	err = vrfResolver.Start()
+	if err != nil {
+		logger.Panic("Error starting vrf resolver: ", err)
+	}
+	defer vrfResolver.Stop()
 	go vrfResolver.Consume(vrfRequestChannel)
 	logger.Infof("Cursor: %v", cursor)
+	deltaHandler := &streamhandlers.VRFDeltaHandler{
+		JobTable:         config.VRFJobTable(),
+		WaitIrreversible: config.VRFWaitIrreversible(),
+		Store:            store,
+		Consumer:         vrfRequestChannel,
+	}
+	deadLetterReplayer := streamhandlers.NewVRFDeadLetterReplayer(store, deltaHandler)
+	deadLetterReplayer.Start()
+	defer deadLetterReplayer.Stop()
 	deltaRequest := &dfclient.DeltaStreamRequest{
 		StartBlockNum:      config.VRFStartBlockNum(),
 		StartCursor:        cursor.Cursor,
 		StopBlockNum:       0,
-		ForkSteps:          []pbbstream.ForkStep{pbbstream.ForkStep_STEP_NEW},
+		ForkSteps:          deltaHandler.ForkSteps(),
 		ReverseUndoOps:     true,
 		HeartBeatFrequency: uint(config.VRFHeartBeatFrequency()),
 	}
 	logger.Infof("Contract: %v, Table: %v, Loglevel: %v", config.VRFContract(), config.VRFJobTable(), config.LogLevel())
 	// deltaRequest.AddTables("eosio.token", []string{"balance"})
 	deltaRequest.AddTables(config.VRFContract(), []string{config.VRFJobTable()})
-	client.DeltaStream(deltaRequest, &streamhandlers.VRFDeltaHandler{
-		JobTable: config.VRFJobTable(),
-		Store:    store,
-		Consumer: vrfRequestChannel,
-	})
+	client.DeltaStream(deltaRequest, deltaHandler)
 }
 
-func getVRFKey(store *strpkg.Store, config *orm.Config) (vrfkey.PublicKey, error) {
+func serveMetrics(port uint16) {
+	logger.Infof("Serving metrics on port: %v", port)
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	err := http.ListenAndServe(fmt.Sprintf(":%v", port), mux)
+	if err != nil {
+		logger.Errorf("Error serving metrics: %v", err)
+	}
+}
+
+//serveAPI serves the vrf requests routes, the node can not serve them while the oracle holds the advisory lock
+func serveAPI(store *strpkg.Store, port uint16) {
+	logger.Infof("Serving vrf requests API on port: %v", port)
+	gin.SetMode(store.Config.LogLevel().ForGin())
+	err := http.ListenAndServe(fmt.Sprintf(":%v", port), web.VRFOracleRouter(store))
+	if err != nil {
+		logger.Errorf("Error serving vrf requests API: %v", err)
+	}
+}
 
-	keyStore := store.VRFKeyStore
-	keys, err := keyStore.Unlock(config.VRFKeyStorePassword())
+//getEOS the contract key is optional when the fulfilment keys are set, as the contract itself does not push transactions,
+//the transactions are pushed through a TxManager so that they are pushed again if they are dropped
+func getEOS(config *orm.Config, store *strpkg.Store) (*eos.EOS, error) {
+	keys := []string{}
+	if config.VRFContractKey() != "" {
+		keys = append(keys, config.VRFContractKey())
+	}
+	eosAPI, err := eos.New(config.EOSURL(), keys)
 	if err != nil {
-		return vrfkey.PublicKey{}, err
+		return nil, err
 	}
-	if len(keys) > 0 {
-		return keys[0], err
+	for _, key := range config.VRFFulfilmentKeys() {
+		err = eosAPI.AddRotatingKey(key)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to add fulfilment key: %v", err)
+		}
+	}
+	eosAPI.TxManager = eos.NewTxManager(store, eosAPI)
+	return eosAPI, nil
+}
+
+//getThreshold decrypts the share of the group key and creates the endpoint through which the partial proofs are
+//exchanged, on the p2p peer of the key in the OCR key store, which is unlocked with the vrf key store password as well
+func getThreshold(store *strpkg.Store, vrfContract *contracts.VRF, config *orm.Config) (*offchainreporting.SingletonPeerWrapper, *svrf.VRFThreshold, error) {
+	groupKey, err := vrfkey.NewPublicKeyFromHex(config.VRFThresholdGroupKey())
+	if err != nil {
+		return nil, nil, fmt.Errorf("Invalid group key: %v", err)
+	}
+	encrypted, err := store.FindEncryptedVRFKeyShare(groupKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Failed to find share of group key: %v, error: %v", groupKey, err)
+	}
+	if encrypted == nil {
+		return nil, nil, fmt.Errorf("There is no share of group key: %v", groupKey)
+	}
+	keyShare, err := encrypted.Decrypt(config.VRFKeyStorePassword())
+	if err != nil {
+		return nil, nil, err
+	}
+	ocrKeyStore := offchainreporting.NewKeyStore(store.DB, utils.GetScryptParams(config))
+	err = ocrKeyStore.Unlock(config.VRFKeyStorePassword())
+	if err != nil {
+		return nil, nil, fmt.Errorf("Failed to unlock p2p keys: %v", err)
+	}
+	peerWrapper := offchainreporting.NewSingletonPeerWrapper(ocrKeyStore, config, store.DB)
+	err = peerWrapper.Start()
+	if err != nil {
+		return nil, nil, fmt.Errorf("Failed to start p2p peer: %v", err)
+	}
+	if peerWrapper.Peer == nil {
+		return nil, nil, fmt.Errorf("There are no p2p keys")
+	}
+	bootstrappers, err := config.P2PBootstrapPeers(nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	endpoint, err := svrf.NewVRFThresholdEndpoint(peerWrapper.Peer, keyShare, config.VRFThresholdPeerIDs(), bootstrappers)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Failed to create threshold endpoint: %v", err)
+	}
+	logger.Infof("Threshold mode, share: %v of group key: %v, threshold: %v of %v", keyShare.Index, groupKey, keyShare.Threshold(), keyShare.Nodes)
+	threshold, err := svrf.NewVRFThreshold(keyShare, endpoint, vrfContract, config.VRFThresholdTimeout())
+	return peerWrapper, threshold, err
+}
+
+func getResourceRenter(store *strpkg.Store, eosAPI *eos.EOS, config *orm.Config) (*eos.ResourceRenter, error) {
+	if config.EOSRentMode() == "" {
+		return nil, nil
 	}
-	logger.Info("There are no VRF keys, creating one")
-	return store.VRFKeyStore.CreateKey(config.VRFKeyStorePassword())
+	return eos.NewResourceRenter(
+		store,
+		eosAPI,
+		config.EOSRentMode(),
+		config.EOSRentPayer(),
+		config.EOSRentPayerKey(),
+		config.VRFFulfilmentActor(),
+		config.EOSRentPayment(),
+		config.EOSRentDailyBudget(),
+		config.EOSPowerupDays(),
+		config.EOSPowerupCPUFrac(),
+		config.EOSPowerupNetFrac())
 }
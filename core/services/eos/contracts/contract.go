@@ -27,3 +27,22 @@ func (m *Contract) DebugTrx(action string, actor string, data interface{}) (*eos
 		data,
 	)
 }
+
+func (m *Contract) Action(action string, actor string, data interface{}) *eosc.Action {
+	return eos.BuildAction(
+		m.ContractName,
+		action,
+		actor,
+		data,
+	)
+}
+
+func (m *Contract) ActionWithPermission(action string, actor string, permission string, data interface{}) *eosc.Action {
+	return eos.BuildActionWithPermission(
+		m.ContractName,
+		action,
+		actor,
+		permission,
+		data,
+	)
+}
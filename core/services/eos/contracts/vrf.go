@@ -1,27 +1,36 @@
 package contracts
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"strings"
 
 	eosc "github.com/eoscanada/eos-go"
 	"github.com/sebastianmontero/vrf-oracle/core/services/eos"
 	"github.com/sebastianmontero/vrf-oracle/core/services/vrf"
 )
 
+//VRF the vrf contract, setrand is pushed with the permission of the fulfilment actor,
+//which by default is the active permission of the contract
 type VRF struct {
 	*Contract
+	Actor      string
+	Permission string
 }
 
 func NewVRF(contract string, eos *eos.EOS) *VRF {
 	return &VRF{
-		&Contract{
+		Contract: &Contract{
 			ContractName: contract,
 			EOS:          eos,
 		},
+		Actor:      contract,
+		Permission: "active",
 	}
 }
 
-func (m *VRF) RequestRand(assocId uint64, seeds []uint64, caller string) (*eosc.PushTransactionFullResp, error) {
+func (m *VRF) RequestRand(assocId uint64, seeds []vrf.EOSSeed, caller string) (*eosc.PushTransactionFullResp, error) {
 	return m.SimpleTrx(
 		"requestrand",
 		caller,
@@ -33,31 +42,280 @@ func (m *VRF) RequestRand(assocId uint64, seeds []uint64, caller string) (*eosc.
 	)
 }
 
-func (m *VRF) SetRand(assocId uint64, caller string, proofs []*vrf.EOSProofResponse) (*eosc.PushTransactionFullResp, error) {
-	return m.SimpleTrx(
+func (m *VRF) SetRand(assocId uint64, caller string, proofs []*vrf.EOSProofResponse, values []*RandValues) (*eosc.PushTransactionFullResp, error) {
+	return m.EOS.Trx(m.SetRandAction(assocId, caller, proofs, values))
+}
+
+//SetRandAction builds a setrand action so that several of them can be pushed in one transaction,
+//values are the values derived from each proof, they are only sent if set, as a binary extension
+//of the action so that contracts that don't derive values are not affected
+func (m *VRF) SetRandAction(assocId uint64, caller string, proofs []*vrf.EOSProofResponse, values []*RandValues) *eosc.Action {
+	var data interface{} = &setRand{
+		AssocID: assocId,
+		Caller:  eosc.AccountName(caller),
+		Proofs:  proofs,
+	}
+	if values != nil {
+		data = &setRandWithValues{
+			AssocID: assocId,
+			Caller:  eosc.AccountName(caller),
+			Proofs:  proofs,
+			Values:  values,
+		}
+	}
+	return m.ActionWithPermission(
 		"setrand",
+		m.Actor,
+		m.Permission,
+		data,
+	)
+}
+
+//SetRandThreshold pushes a setrandtsh action, see SetRandThresholdAction
+func (m *VRF) SetRandThreshold(assocId uint64, caller string, proofs []*vrf.EOSThresholdProofResponse, values []*RandValues) (*eosc.PushTransactionFullResp, error) {
+	return m.EOS.Trx(m.SetRandThresholdAction(assocId, caller, proofs, values))
+}
+
+//SetRandThresholdAction builds a setrandtsh action, which fulfils a request with proofs combined from the partial
+//proofs of the shares of a threshold group key, values are sent as in SetRandAction
+func (m *VRF) SetRandThresholdAction(assocId uint64, caller string, proofs []*vrf.EOSThresholdProofResponse, values []*RandValues) *eosc.Action {
+	var data interface{} = &setRandThreshold{
+		AssocID: assocId,
+		Caller:  eosc.AccountName(caller),
+		Proofs:  proofs,
+	}
+	if values != nil {
+		data = &setRandThresholdWithValues{
+			AssocID: assocId,
+			Caller:  eosc.AccountName(caller),
+			Proofs:  proofs,
+			Values:  values,
+		}
+	}
+	return m.ActionWithPermission(
+		"setrandtsh",
+		m.Actor,
+		m.Permission,
+		data,
+	)
+}
+
+//SetGroupKey registers a threshold group key in the contract, commitments are the commitments to the polynomial
+//the key was shared with encoded as in EncodeEOSPublicKey, the first one is the group public key. It is pushed
+//with the active permission of the contract
+func (m *VRF) SetGroupKey(commitments []string) (*eosc.PushTransactionFullResp, error) {
+	return m.SimpleTrx(
+		"setgroupkey",
 		m.ContractName,
-		&setRand{
+		&setGroupKey{
+			Commitments: commitments,
+		},
+	)
+}
+
+//SetPubKey registers a vrf public key in the contract, publicKey is the encoded point as in EncodeEOSPublicKey,
+//it is pushed with the active permission of the contract
+func (m *VRF) SetPubKey(publicKey string, keyHash []byte) (*eosc.PushTransactionFullResp, error) {
+	return m.SimpleTrx(
+		"setpubkey",
+		m.ContractName,
+		&setPubKey{
+			PublicKey: publicKey,
+			KeyHash:   eosc.Checksum256(keyHash),
+		},
+	)
+}
+
+//ContributeAction builds a setrand action for quorum mode, it carries the hash of the key that generated the
+//proofs so that the contract can tell the contributions of each oracle apart, the values are always sent as
+//the key hash follows them in the binary extension
+func (m *VRF) ContributeAction(assocId uint64, caller string, proofs []*vrf.EOSProofResponse, values []*RandValues, keyHash []byte) *eosc.Action {
+	if values == nil {
+		values = []*RandValues{}
+	}
+	return m.ActionWithPermission(
+		"setrand",
+		m.Actor,
+		m.Permission,
+		&setRandContribution{
 			AssocID: assocId,
 			Caller:  eosc.AccountName(caller),
 			Proofs:  proofs,
+			Values:  values,
+			KeyHash: eosc.Checksum256(keyHash),
+		},
+	)
+}
+
+//Contribution the hashes of the keys whose proofs the contract has received for the current round
+//of a request, in quorum mode
+type Contribution struct {
+	AssocID   eosc.Uint64        `json:"assoc_id"`
+	KeyHashes []eosc.Checksum256 `json:"key_hashes"`
+}
+
+//Contains returns true if the key already contributed
+func (m *Contribution) Contains(keyHash []byte) bool {
+	for _, hash := range m.KeyHashes {
+		if bytes.Equal(hash, keyHash) {
+			return true
+		}
+	}
+	return false
+}
+
+//FindContribution returns the contributions to the request from the contribs table, nil if there are none
+func (m *VRF) FindContribution(caller string, assocId uint64) (*Contribution, error) {
+	contribution := &Contribution{}
+	found, err := m.EOS.GetTableRow(m.ContractName, caller, "contribs", assocId, contribution)
+	if err != nil || !found {
+		return nil, err
+	}
+	return contribution, nil
+}
+
+//Job a request of the jobs table, the seeds are rendered as the abi type of the seeds of the contract
+type Job struct {
+	AssocID   eosc.Uint64       `json:"assoc_id"`
+	Seeds     []json.RawMessage `json:"seeds"`
+	Frequency string            `json:"frequency"`
+}
+
+//DecimalSeeds parses the seeds as seedType and returns their decimal representation
+func (m *Job) DecimalSeeds(seedType string) ([]string, error) {
+	seeds := make([]string, 0, len(m.Seeds))
+	for _, seed := range m.Seeds {
+		parsed, err := vrf.ParseEOSSeed(strings.Trim(string(seed), `"`), seedType)
+		if err != nil {
+			return nil, err
+		}
+		seeds = append(seeds, parsed.String())
+	}
+	return seeds, nil
+}
+
+//FindJob returns the request of the caller from the jobs table, nil if it is not there
+func (m *VRF) FindJob(jobTable, caller string, assocId uint64) (*Job, error) {
+	job := &Job{}
+	found, err := m.EOS.GetTableRow(m.ContractName, caller, jobTable, assocId, job)
+	if err != nil || !found {
+		return nil, err
+	}
+	return job, nil
+}
+
+//SetCommitAction builds a setcommit action, which commits the hashes of the secrets of the commit-reveal
+//strategy, one per seed
+func (m *VRF) SetCommitAction(assocId uint64, caller string, commitments [][]byte) *eosc.Action {
+	hashes := make([]eosc.Checksum256, 0, len(commitments))
+	for _, commitment := range commitments {
+		hashes = append(hashes, eosc.Checksum256(commitment))
+	}
+	return m.ActionWithPermission(
+		"setcommit",
+		m.Actor,
+		m.Permission,
+		&setCommit{
+			AssocID:     assocId,
+			Caller:      eosc.AccountName(caller),
+			Commitments: hashes,
 		},
 	)
 }
 
+//RevealAction builds a reveal action, which reveals the committed secrets, the contract checks them against
+//the commitments and mixes each of them with the id of the block of the request to get the final values
+func (m *VRF) RevealAction(assocId uint64, caller string, blockID []byte, secrets [][]byte) *eosc.Action {
+	revealed := make([]eosc.Checksum256, 0, len(secrets))
+	for _, secret := range secrets {
+		revealed = append(revealed, eosc.Checksum256(secret))
+	}
+	return m.ActionWithPermission(
+		"reveal",
+		m.Actor,
+		m.Permission,
+		&reveal{
+			AssocID: assocId,
+			Caller:  eosc.AccountName(caller),
+			BlockID: eosc.Checksum256(blockID),
+			Secrets: revealed,
+		},
+	)
+}
+
+//SetRands pushes several setrand actions in one transaction
+func (m *VRF) SetRands(actions ...*eosc.Action) (*eosc.PushTransactionFullResp, error) {
+	return m.EOS.Trx(actions...)
+}
+
 type setRand struct {
 	AssocID uint64                  `json:"assoc_id"`
 	Caller  eosc.AccountName        `json:"caller"`
 	Proofs  []*vrf.EOSProofResponse `json:"proofs"`
 }
 
+//RandValues values derived from the output of a proof
+type RandValues struct {
+	Values []uint64 `json:"values"`
+}
+
+type setRandWithValues struct {
+	AssocID uint64                  `json:"assoc_id"`
+	Caller  eosc.AccountName        `json:"caller"`
+	Proofs  []*vrf.EOSProofResponse `json:"proofs"`
+	Values  []*RandValues           `json:"values"`
+}
+
 func (m *setRand) String() string {
 	return fmt.Sprintf("\nsetRand{\n\tAssocID: %v, \n\tCaller %v, \n\tProofs: %v\n}", m.AssocID, m.Caller, m.Proofs)
 }
 
+type setRandThreshold struct {
+	AssocID uint64                           `json:"assoc_id"`
+	Caller  eosc.AccountName                 `json:"caller"`
+	Proofs  []*vrf.EOSThresholdProofResponse `json:"proofs"`
+}
+
+type setRandThresholdWithValues struct {
+	AssocID uint64                           `json:"assoc_id"`
+	Caller  eosc.AccountName                 `json:"caller"`
+	Proofs  []*vrf.EOSThresholdProofResponse `json:"proofs"`
+	Values  []*RandValues                    `json:"values"`
+}
+
+type setGroupKey struct {
+	Commitments []string `json:"commitments"`
+}
+
+type setRandContribution struct {
+	AssocID uint64                  `json:"assoc_id"`
+	Caller  eosc.AccountName        `json:"caller"`
+	Proofs  []*vrf.EOSProofResponse `json:"proofs"`
+	Values  []*RandValues           `json:"values"`
+	KeyHash eosc.Checksum256        `json:"key_hash"`
+}
+
+type setCommit struct {
+	AssocID     uint64             `json:"assoc_id"`
+	Caller      eosc.AccountName   `json:"caller"`
+	Commitments []eosc.Checksum256 `json:"commitments"`
+}
+
+type reveal struct {
+	AssocID uint64             `json:"assoc_id"`
+	Caller  eosc.AccountName   `json:"caller"`
+	BlockID eosc.Checksum256   `json:"block_id"`
+	Secrets []eosc.Checksum256 `json:"secrets"`
+}
+
+type setPubKey struct {
+	PublicKey string           `json:"public_key"`
+	KeyHash   eosc.Checksum256 `json:"key_hash"`
+}
+
 type requestRand struct {
 	AssocID uint64           `json:"assoc_id"`
-	Seeds   []uint64         `json:"seeds"`
+	Seeds   []vrf.EOSSeed    `json:"seeds"`
 	Caller  eosc.AccountName `json:"caller"`
 }
 
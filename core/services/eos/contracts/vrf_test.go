@@ -1,6 +1,9 @@
 package contracts_test
 
 import (
+	"encoding/hex"
+	"encoding/json"
+	"math/big"
 	"testing"
 
 	eostest "github.com/digital-scarcity/eos-go-test"
@@ -17,19 +20,39 @@ func TestSetRand(t *testing.T) {
 	t.Log("\nEnvironment Setup complete\n")
 	eos, err := eos.New(testingEndpoint, []string{eostest.DefaultKey()})
 	assert.NoError(t, err)
+	seed, err := vrf.NewEOSSeed(big.NewInt(1), vrf.EOSSeedType_UINT64)
+	assert.NoError(t, err)
+	seeds := []vrf.EOSSeed{seed}
 	vrf := contracts.NewVRF(string(env.VRF), eos)
 	assocID := uint64(1)
-	seeds := []uint64{1}
 	response, err := vrf.RequestRand(assocID, seeds, string(env.Caller))
 	t.Log("Request Rand Response: ", response)
 	assert.NoError(t, err)
 	proofs := createProofs(assocID, seeds)
-	response, err = vrf.SetRand(assocID, string(env.Caller), proofs)
+	response, err = vrf.SetRand(assocID, string(env.Caller), proofs, nil)
 	t.Log("Set Rand Response: ", response)
 	assert.NoError(t, err)
 }
 
-func createProofs(assocID uint64, seeds []uint64) []*vrf.EOSProofResponse {
+func TestContribution(t *testing.T) {
+	contribution := &contracts.Contribution{}
+	err := json.Unmarshal([]byte(`{
+		"assoc_id": "18446744073709551615",
+		"key_hashes": [
+			"c0a6c424ac7157ae408398df7e5f4552091a69125d5dfcb7b8c2659029395bdf",
+			"0000000000000000000000000000000000000000000000000000000000000001"
+		]
+	}`), contribution)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(18446744073709551615), uint64(contribution.AssocID))
+	assert.Len(t, contribution.KeyHashes, 2)
+	keyHash, _ := hex.DecodeString("c0a6c424ac7157ae408398df7e5f4552091a69125d5dfcb7b8c2659029395bdf")
+	assert.True(t, contribution.Contains(keyHash))
+	keyHash[0] = 0
+	assert.False(t, contribution.Contains(keyHash))
+}
+
+func createProofs(assocID uint64, seeds []vrf.EOSSeed) []*vrf.EOSProofResponse {
 	proofs := make([]*vrf.EOSProofResponse, 0, len(seeds))
 	for _, seed := range seeds {
 		proofs = append(proofs, &vrf.EOSProofResponse{
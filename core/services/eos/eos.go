@@ -3,6 +3,9 @@ package eos
 import (
 	"context"
 	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
 
 	"github.com/eoscanada/eos-go"
 	eosc "github.com/eoscanada/eos-go"
@@ -11,13 +14,18 @@ import (
 
 type EOS struct {
 	API *eosc.API
+	//TxManager if set, the transactions are pushed through it so that they are tracked until they are irreversible
+	TxManager *TxManager
+	signer    *RoundRobinSigner
 }
 
 func New(url string, pkeys []string) (*EOS, error) {
 	api := eosc.New(url)
-	api.SetSigner(&eosc.KeyBag{})
+	signer := NewRoundRobinSigner()
+	api.SetSigner(signer)
 	eos := &EOS{
-		API: api,
+		API:    api,
+		signer: signer,
 	}
 	for _, pkey := range pkeys {
 		err := eos.AddKey(pkey)
@@ -33,17 +41,117 @@ func (m *EOS) AddKey(pkey string) error {
 	return m.API.Signer.ImportPrivateKey(context.Background(), pkey)
 }
 
+//AddRotatingKey adds a key that is used in turns with the other rotating keys to sign transactions
+func (m *EOS) AddRotatingKey(pkey string) error {
+	return m.signer.ImportRotatingKey(context.Background(), pkey)
+}
+
+// LastIrreversibleBlock returns the number and id of the last irreversible block
+func (m *EOS) LastIrreversibleBlock() (uint64, string, error) {
+	info, err := m.API.GetInfo(context.Background())
+	if err != nil {
+		return 0, "", err
+	}
+	return uint64(info.LastIrreversibleBlockNum), info.LastIrreversibleBlockID.String(), nil
+}
+
+// HeadBlock returns the number of the head block of the node
+func (m *EOS) HeadBlock() (uint64, error) {
+	info, err := m.API.GetInfo(context.Background())
+	if err != nil {
+		return 0, err
+	}
+	return uint64(info.HeadBlockNum), nil
+}
+
+//Trx signs and pushes the actions in one transaction, the BlockNum of the response is the block
+//the transaction was included in
 func (m *EOS) Trx(actions ...*eosc.Action) (*eosc.PushTransactionFullResp, error) {
 	for _, action := range actions {
 		logger.Infof("Trx Account: %v Name: %v, Authorization: %v, Data: %v", action.Account, action.Name, action.Authorization, action.ActionData)
 		// js, _ := json.Marshal(action.ActionData)
 		// logger.Infof("Data json: %v", string(js))
 	}
-	return m.API.SignPushActions(context.Background(), actions...)
+	if m.TxManager != nil {
+		return m.TxManager.Trx(actions...)
+	}
+	trx, err := m.signTrx(actions, 0)
+	if err != nil {
+		return nil, err
+	}
+	return m.pushTrx(trx)
+}
+
+//signedTrx a signed transaction ready to be pushed, HeadBlockNum is the head block when it was signed,
+//the transaction can only be included in later blocks
+type signedTrx struct {
+	ID           string
+	HeadBlockNum uint64
+	Expiration   time.Time
+	packed       *eosc.PackedTransaction
+}
+
+//signTrx signs the actions in one transaction that expires after expiration, the default expiration
+//is used if it is 0
+func (m *EOS) signTrx(actions []*eosc.Action, expiration time.Duration) (*signedTrx, error) {
+	ctx := context.Background()
+	txOpts := &eosc.TxOptions{}
+	if err := txOpts.FillFromChain(ctx, m.API); err != nil {
+		return nil, err
+	}
+	tx := eosc.NewTransaction(actions, txOpts)
+	if expiration > 0 {
+		tx.SetExpiration(expiration)
+	}
+	_, packedTx, err := m.API.SignTransaction(ctx, tx, txOpts.ChainID, txOpts.Compress)
+	if err != nil {
+		return nil, err
+	}
+	id, err := packedTx.ID()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get transaction id: %v", err)
+	}
+	return &signedTrx{
+		ID:           id.String(),
+		HeadBlockNum: uint64(eosc.BlockNum(txOpts.HeadBlockID.String())),
+		Expiration:   tx.Expiration.Time,
+		packed:       packedTx,
+	}, nil
+}
+
+func (m *EOS) pushTrx(trx *signedTrx) (*eosc.PushTransactionFullResp, error) {
+	raw, err := m.API.PushTransactionRaw(context.Background(), trx.packed)
+	if err != nil {
+		return nil, err
+	}
+	return decodePushResponse(raw)
+}
+
+//decodePushResponse nodeos reports the block the transaction was included in inside the processed
+//trace, which eos-go does not decode
+func decodePushResponse(raw json.RawMessage) (*eosc.PushTransactionFullResp, error) {
+	resp := &eosc.PushTransactionFullResp{}
+	err := json.Unmarshal(raw, resp)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to decode push transaction response: %v", err)
+	}
+	if resp.BlockNum == 0 {
+		var processed struct {
+			Processed struct {
+				BlockNum uint32 `json:"block_num"`
+			} `json:"processed"`
+		}
+		err = json.Unmarshal(raw, &processed)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to decode push transaction response: %v", err)
+		}
+		resp.BlockNum = processed.Processed.BlockNum
+	}
+	return resp, nil
 }
 
 func (m *EOS) SimpleTrx(contract, action, actor string, data interface{}) (*eosc.PushTransactionFullResp, error) {
-	return m.Trx(buildAction(contract, action, actor, data))
+	return m.Trx(BuildAction(contract, action, actor, data))
 }
 
 func (m *EOS) DebugTrx(contract, action, actor string, data interface{}) (*eosc.PushTransactionFullResp, error) {
@@ -52,7 +160,7 @@ func (m *EOS) DebugTrx(contract, action, actor string, data interface{}) (*eosc.
 		return nil, err
 	}
 
-	tx := eosc.NewTransaction([]*eosc.Action{buildAction(contract, action, actor, data)}, txOpts)
+	tx := eosc.NewTransaction([]*eosc.Action{BuildAction(contract, action, actor, data)}, txOpts)
 	signedTx, packedTx, err := m.API.SignTransaction(context.Background(), tx, txOpts.ChainID, eos.CompressionNone)
 	if err != nil {
 		return nil, err
@@ -67,14 +175,70 @@ func (m *EOS) DebugTrx(contract, action, actor string, data interface{}) (*eosc.
 	return m.API.PushTransaction(context.Background(), packedTx)
 }
 
-func buildAction(contract, action, actor string, data interface{}) *eosc.Action {
+//GetTableRow gets the row of the table with the primary key, returns false if there is none
+func (m *EOS) GetTableRow(code, scope, table string, primaryKey uint64, row interface{}) (bool, error) {
+	key := strconv.FormatUint(primaryKey, 10)
+	resp, err := m.API.GetTableRows(context.Background(), eosc.GetTableRowsRequest{
+		Code:       code,
+		Scope:      scope,
+		Table:      table,
+		LowerBound: key,
+		UpperBound: key,
+		Limit:      1,
+		JSON:       true,
+	})
+	if err != nil {
+		return false, err
+	}
+	var rows []json.RawMessage
+	err = resp.JSONToStructs(&rows)
+	if err != nil {
+		return false, err
+	}
+	if len(rows) == 0 {
+		return false, nil
+	}
+	return true, json.Unmarshal(rows[0], row)
+}
+
+//Block a block and the ids of the transactions it includes
+type Block struct {
+	Num   uint64
+	Time  time.Time
+	TxIDs map[string]bool
+}
+
+//GetBlock returns the block with the ids of the transactions it includes
+func (m *EOS) GetBlock(blockNum uint64) (*Block, error) {
+	block, err := m.API.GetBlockByNum(context.Background(), uint32(blockNum))
+	if err != nil {
+		return nil, err
+	}
+	txIDs := make(map[string]bool, len(block.Transactions))
+	for _, receipt := range block.Transactions {
+		txIDs[receipt.Transaction.ID.String()] = true
+	}
+	return &Block{
+		Num:   blockNum,
+		Time:  block.Timestamp.Time,
+		TxIDs: txIDs,
+	}, nil
+}
+
+//BuildAction builds an action authorized by the active permission of the actor
+func BuildAction(contract, action, actor string, data interface{}) *eosc.Action {
+	return BuildActionWithPermission(contract, action, actor, "active", data)
+}
+
+//BuildActionWithPermission builds an action authorized by the specified permission of the actor
+func BuildActionWithPermission(contract, action, actor, permission string, data interface{}) *eosc.Action {
 	return &eosc.Action{
 		Account: eosc.AN(contract),
 		Name:    eosc.ActN(action),
 		Authorization: []eosc.PermissionLevel{
 			{
 				Actor:      eosc.AccountName(actor),
-				Permission: eosc.PN("active"),
+				Permission: eosc.PN(permission),
 			},
 		},
 		ActionData: eosc.NewActionData(data),
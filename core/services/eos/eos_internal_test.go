@@ -0,0 +1,30 @@
+package eos
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEOS_DecodePushResponse(t *testing.T) {
+	raw := json.RawMessage(`{
+		"transaction_id": "6c5d8c4fc1a9e4de0f23a7a2e0fd3fe6b0aa9e5e0c4a1d1a6bd8c59d0d4e2d7a",
+		"processed": {
+			"id": "6c5d8c4fc1a9e4de0f23a7a2e0fd3fe6b0aa9e5e0c4a1d1a6bd8c59d0d4e2d7a",
+			"block_num": 395300,
+			"block_time": "2021-04-08T00:00:00.500",
+			"producer_block_id": null,
+			"receipt": {"status": "executed", "cpu_usage_us": 312, "net_usage_words": 40},
+			"action_traces": []
+		}
+	}`)
+	resp, err := decodePushResponse(raw)
+	require.NoError(t, err)
+	assert.Equal(t, "6c5d8c4fc1a9e4de0f23a7a2e0fd3fe6b0aa9e5e0c4a1d1a6bd8c59d0d4e2d7a", resp.TransactionID)
+	assert.Equal(t, uint32(395300), resp.BlockNum)
+
+	_, err = decodePushResponse(json.RawMessage(`[]`))
+	assert.Error(t, err)
+}
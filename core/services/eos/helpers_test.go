@@ -0,0 +1,5 @@
+package eos
+
+func (m *TxManager) ExportedCheckTxs() {
+	m.checkTxs()
+}
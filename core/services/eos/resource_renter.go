@@ -0,0 +1,211 @@
+package eos
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	eosc "github.com/eoscanada/eos-go"
+	"github.com/sebastianmontero/vrf-oracle/core/logger"
+	strpkg "github.com/sebastianmontero/vrf-oracle/core/store"
+	"github.com/sebastianmontero/vrf-oracle/core/store/models"
+)
+
+const (
+	RentMode_POWERUP = "powerup"
+	RentMode_REX     = "rex"
+
+	Resource_CPU = "cpu"
+	Resource_NET = "net"
+)
+
+//rentInterval time during which failed pushes are retried without renting again,
+//avoids renting once per failed transaction when several fail at the same time
+const rentInterval = 30 * time.Second
+
+//exhaustionErrors names of the chain errors that can be solved by renting the resource
+var exhaustionErrors = map[string]string{
+	"tx_cpu_usage_exceeded":     Resource_CPU,
+	"leeway_deadline_exception": Resource_CPU,
+	"deadline_exception":        Resource_CPU,
+	"tx_net_usage_exceeded":     Resource_NET,
+}
+
+//ExhaustedResource returns the resource whose exhaustion caused the error, empty if the error was not
+//caused by resource exhaustion
+func ExhaustedResource(err error) string {
+	var apiErr eosc.APIError
+	if !errors.As(err, &apiErr) {
+		return ""
+	}
+	return exhaustionErrors[apiErr.ErrorStruct.Name]
+}
+
+//ResourceRenter rents CPU and NET for the receiver account when a transaction fails because of resource
+//exhaustion, using the powerup action or REX. The rentals are paid by the payer account, if its key is set
+//they are pushed with a signer of their own, and the amount spent each day is limited by the daily budget.
+//Each rental is recorded, the amount spent during the current day is loaded from them when the renter is
+//created so that restarts do not reset the budget. For powerup rentals Payment is the max payment, and is
+//what is counted against the budget
+type ResourceRenter struct {
+	Store       *strpkg.Store
+	EOS         *EOS
+	Mode        string
+	Payer       string
+	Receiver    string
+	Payment     eosc.Asset
+	DailyBudget eosc.Asset
+	PowerupDays uint32
+	CPUFrac     uint64
+	NetFrac     uint64
+	mu          sync.Mutex
+	day         time.Time
+	spent       eosc.Asset
+	rentedAt    time.Time
+}
+
+func NewResourceRenter(store *strpkg.Store, eos *EOS, mode, payer, payerKey, receiver, payment, dailyBudget string, powerupDays uint32, cpuFrac, netFrac uint64) (*ResourceRenter, error) {
+	if mode != RentMode_POWERUP && mode != RentMode_REX {
+		return nil, fmt.Errorf("Unknown rent mode: %v, valid modes are: %v, %v", mode, RentMode_POWERUP, RentMode_REX)
+	}
+	paymentAsset, err := eosc.NewAssetFromString(payment)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid rent payment: %v, error: %v", payment, err)
+	}
+	budgetAsset, err := eosc.NewAssetFromString(dailyBudget)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid rent daily budget: %v, error: %v", dailyBudget, err)
+	}
+	if paymentAsset.Symbol != budgetAsset.Symbol {
+		return nil, fmt.Errorf("Rent payment: %v and daily budget: %v must have the same symbol", payment, dailyBudget)
+	}
+	//The payer key gets its own signer, if it was added to the signer of the fulfilment transactions it
+	//could be used to sign them
+	if payerKey != "" {
+		eos, err = New(eos.API.BaseURL, []string{payerKey})
+		if err != nil {
+			return nil, fmt.Errorf("Failed to add rent payer key: %v", err)
+		}
+	}
+	day := today()
+	spent, err := store.SumEOSRentalPayments(payer, budgetAsset.Symbol.Symbol, day)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to load the amount spent today on rentals: %v", err)
+	}
+	return &ResourceRenter{
+		Store:       store,
+		EOS:         eos,
+		Mode:        mode,
+		Payer:       payer,
+		Receiver:    receiver,
+		Payment:     paymentAsset,
+		DailyBudget: budgetAsset,
+		PowerupDays: powerupDays,
+		CPUFrac:     cpuFrac,
+		NetFrac:     netFrac,
+		day:         day,
+		spent:       eosc.Asset{Amount: eosc.Int64(spent), Symbol: budgetAsset.Symbol},
+	}, nil
+}
+
+//Retry calls push and if it fails because of resource exhaustion rents the resource and calls it again,
+//a nil renter just calls push
+func (m *ResourceRenter) Retry(push func() error) error {
+	err := push()
+	if m == nil || err == nil {
+		return err
+	}
+	resource := ExhaustedResource(err)
+	if resource == "" {
+		return err
+	}
+	rentErr := m.Rent(resource)
+	if rentErr != nil {
+		logger.Errorf("Failed to rent %v for account: %v, error: %v", resource, m.Receiver, rentErr)
+		return err
+	}
+	return push()
+}
+
+//Rent rents the resource for the receiver if it was not rented recently and the daily budget allows it
+func (m *ResourceRenter) Rent(resource string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now().UTC()
+	if now.Sub(m.rentedAt) < rentInterval {
+		return nil
+	}
+	if day := today(); !day.Equal(m.day) {
+		m.day = day
+		m.spent = eosc.Asset{Symbol: m.DailyBudget.Symbol}
+	}
+	if m.spent.Add(m.Payment).Amount > m.DailyBudget.Amount {
+		return fmt.Errorf("Daily budget: %v exhausted, spent: %v", m.DailyBudget, m.spent)
+	}
+	logger.Infof("Renting %v for account: %v using %v, payment: %v", resource, m.Receiver, m.Mode, m.Payment)
+	resp, err := m.EOS.Trx(m.action(resource))
+	if err != nil {
+		return err
+	}
+	m.spent = m.spent.Add(m.Payment)
+	m.rentedAt = now
+	err = m.Store.CreateEOSRental(&models.EOSRental{
+		Payer:    m.Payer,
+		Receiver: m.Receiver,
+		Resource: resource,
+		Mode:     m.Mode,
+		TxID:     resp.TransactionID,
+		Amount:   int64(m.Payment.Amount),
+		Symbol:   m.Payment.Symbol.Symbol,
+	})
+	if err != nil {
+		logger.Errorf("Failed to record rental of %v for account: %v, error: %v", resource, m.Receiver, err)
+	}
+	logger.Infof("Rented %v for account: %v, spent today: %v of %v", resource, m.Receiver, m.spent, m.DailyBudget)
+	return nil
+}
+
+//today the start of the current day in UTC, the daily budget is reset at midnight UTC
+func today() time.Time {
+	return time.Now().UTC().Truncate(24 * time.Hour)
+}
+
+func (m *ResourceRenter) action(resource string) *eosc.Action {
+	if m.Mode == RentMode_POWERUP {
+		return BuildAction("eosio", "powerup", m.Payer, &powerup{
+			Payer:      eosc.AccountName(m.Payer),
+			Receiver:   eosc.AccountName(m.Receiver),
+			Days:       m.PowerupDays,
+			NetFrac:    int64(m.NetFrac),
+			CPUFrac:    int64(m.CPUFrac),
+			MaxPayment: m.Payment,
+		})
+	}
+	action := "rentcpu"
+	if resource == Resource_NET {
+		action = "rentnet"
+	}
+	return BuildAction("eosio", action, m.Payer, &rentRex{
+		From:        eosc.AccountName(m.Payer),
+		Receiver:    eosc.AccountName(m.Receiver),
+		LoanPayment: m.Payment,
+		LoanFund:    eosc.Asset{Symbol: m.Payment.Symbol},
+	})
+}
+
+type powerup struct {
+	Payer      eosc.AccountName `json:"payer"`
+	Receiver   eosc.AccountName `json:"receiver"`
+	Days       uint32           `json:"days"`
+	NetFrac    int64            `json:"net_frac"`
+	CPUFrac    int64            `json:"cpu_frac"`
+	MaxPayment eosc.Asset       `json:"max_payment"`
+}
+
+type rentRex struct {
+	From        eosc.AccountName `json:"from"`
+	Receiver    eosc.AccountName `json:"receiver"`
+	LoanPayment eosc.Asset       `json:"loan_payment"`
+	LoanFund    eosc.Asset       `json:"loan_fund"`
+}
@@ -0,0 +1,103 @@
+package eos_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	eosc "github.com/eoscanada/eos-go"
+	"github.com/sebastianmontero/vrf-oracle/core/internal/cltest"
+	"github.com/sebastianmontero/vrf-oracle/core/services/eos"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func apiError(name string) eosc.APIError {
+	apiErr := eosc.APIError{Code: 500, Message: "Internal Service Error"}
+	apiErr.ErrorStruct.Name = name
+	return apiErr
+}
+
+func TestEOS_ExhaustedResource(t *testing.T) {
+	assert.Equal(t, eos.Resource_CPU, eos.ExhaustedResource(apiError("tx_cpu_usage_exceeded")))
+	assert.Equal(t, eos.Resource_CPU, eos.ExhaustedResource(apiError("deadline_exception")))
+	assert.Equal(t, eos.Resource_NET, eos.ExhaustedResource(apiError("tx_net_usage_exceeded")))
+	assert.Equal(t, eos.Resource_NET, eos.ExhaustedResource(fmt.Errorf("Push failed: %w", apiError("tx_net_usage_exceeded"))))
+	assert.Equal(t, "", eos.ExhaustedResource(apiError("eosio_assert_message_exception")))
+	assert.Equal(t, "", eos.ExhaustedResource(errors.New("tx_cpu_usage_exceeded")))
+}
+
+func TestEOS_NilResourceRenterRetry(t *testing.T) {
+	var renter *eos.ResourceRenter
+	calls := 0
+	err := renter.Retry(func() error {
+		calls++
+		return apiError("tx_cpu_usage_exceeded")
+	})
+	assert.Error(t, err)
+	assert.Equal(t, 1, calls)
+}
+
+func TestEOS_NewResourceRenter(t *testing.T) {
+	t.Parallel()
+	store, cleanup := cltest.NewStore(t)
+	defer cleanup()
+
+	_, err := eos.NewResourceRenter(store, nil, "stake", "payer", "", "vrf", "1.0000 TLOS", "10.0000 TLOS", 1, 1, 1)
+	assert.Error(t, err)
+	_, err = eos.NewResourceRenter(store, nil, eos.RentMode_POWERUP, "payer", "", "vrf", "1.0000 TLOS", "10.0000 EOS", 1, 1, 1)
+	assert.Error(t, err)
+	renter, err := eos.NewResourceRenter(store, nil, eos.RentMode_REX, "payer", "", "vrf", "1.0000 TLOS", "10.0000 TLOS", 1, 1, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(10000), int64(renter.Payment.Amount))
+}
+
+func TestEOS_ResourceRenterDailyBudget(t *testing.T) {
+	t.Parallel()
+	store, cleanup := cltest.NewStore(t)
+	defer cleanup()
+	chain := newStubChain(100, 101)
+	server := chain.serve(t)
+	defer server.Close()
+	eosAPI, err := eos.New(server.URL, []string{testPrivateKey})
+	require.NoError(t, err)
+
+	renter, err := eos.NewResourceRenter(store, eosAPI, eos.RentMode_POWERUP, "payer", "", "vrf", "1.0000 TLOS", "1.5000 TLOS", 1, 1, 1)
+	require.NoError(t, err)
+	require.NoError(t, renter.Rent(eos.Resource_CPU))
+	require.Len(t, chain.pushedTxs(), 1)
+
+	//The amount spent today is loaded when the oracle restarts
+	renter, err = eos.NewResourceRenter(store, eosAPI, eos.RentMode_POWERUP, "payer", "", "vrf", "1.0000 TLOS", "1.5000 TLOS", 1, 1, 1)
+	require.NoError(t, err)
+	assert.EqualError(t, renter.Rent(eos.Resource_CPU), "Daily budget: 1.5000 TLOS exhausted, spent: 1.0000 TLOS")
+	assert.Len(t, chain.pushedTxs(), 1)
+
+	//The budget of another payer is not affected
+	renter, err = eos.NewResourceRenter(store, eosAPI, eos.RentMode_POWERUP, "payer2", "", "vrf", "1.0000 TLOS", "1.5000 TLOS", 1, 1, 1)
+	require.NoError(t, err)
+	assert.NoError(t, renter.Rent(eos.Resource_CPU))
+	assert.Len(t, chain.pushedTxs(), 2)
+}
+
+func TestEOS_ResourceRenterSignsWithItsOwnKey(t *testing.T) {
+	t.Parallel()
+	store, cleanup := cltest.NewStore(t)
+	defer cleanup()
+	chain := newStubChain(100, 101)
+	server := chain.serve(t)
+	defer server.Close()
+	eosAPI, err := eos.New(server.URL, nil)
+	require.NoError(t, err)
+
+	renter, err := eos.NewResourceRenter(store, eosAPI, eos.RentMode_POWERUP, "payer", testPrivateKey, "vrf", "1.0000 TLOS", "1.5000 TLOS", 1, 1, 1)
+	require.NoError(t, err)
+	require.NoError(t, renter.Rent(eos.Resource_CPU))
+	assert.Len(t, chain.pushedTxs(), 1)
+
+	//The payer key can not sign the fulfilment transactions
+	keys, err := eosAPI.API.Signer.AvailableKeys(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+}
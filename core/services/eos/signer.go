@@ -0,0 +1,65 @@
+package eos
+
+import (
+	"context"
+	"sync"
+
+	eosc "github.com/eoscanada/eos-go"
+	"github.com/eoscanada/eos-go/ecc"
+)
+
+//RoundRobinSigner signs with the static keys and one of the rotating keys, the rotating key used changes
+//with every transaction. The chain only asks for the keys it is offered, so if the rotating keys are the
+//keys of a permission with threshold 1 each transaction is signed with the next one
+type RoundRobinSigner struct {
+	static   *eosc.KeyBag
+	rotating *eosc.KeyBag
+	mu       sync.Mutex
+	next     int
+}
+
+func NewRoundRobinSigner() *RoundRobinSigner {
+	return &RoundRobinSigner{
+		static:   eosc.NewKeyBag(),
+		rotating: eosc.NewKeyBag(),
+	}
+}
+
+//ImportPrivateKey adds a static key
+func (m *RoundRobinSigner) ImportPrivateKey(ctx context.Context, wifPrivKey string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.static.ImportPrivateKey(ctx, wifPrivKey)
+}
+
+//ImportRotatingKey adds a rotating key
+func (m *RoundRobinSigner) ImportRotatingKey(ctx context.Context, wifPrivKey string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.rotating.ImportPrivateKey(ctx, wifPrivKey)
+}
+
+//AvailableKeys returns the static keys and the next rotating key
+func (m *RoundRobinSigner) AvailableKeys(ctx context.Context) ([]ecc.PublicKey, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	keys, err := m.static.AvailableKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(m.rotating.Keys) > 0 {
+		keys = append(keys, m.rotating.Keys[m.next%len(m.rotating.Keys)].PublicKey())
+		m.next++
+	}
+	return keys, nil
+}
+
+//Sign signs with the required keys, which can be any of the static or rotating keys
+func (m *RoundRobinSigner) Sign(ctx context.Context, tx *eosc.SignedTransaction, chainID []byte, requiredKeys ...ecc.PublicKey) (*eosc.SignedTransaction, error) {
+	m.mu.Lock()
+	keys := make([]*ecc.PrivateKey, 0, len(m.static.Keys)+len(m.rotating.Keys))
+	keys = append(keys, m.static.Keys...)
+	keys = append(keys, m.rotating.Keys...)
+	m.mu.Unlock()
+	return (&eosc.KeyBag{Keys: keys}).Sign(ctx, tx, chainID, requiredKeys...)
+}
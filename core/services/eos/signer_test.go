@@ -0,0 +1,31 @@
+package eos_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/eoscanada/eos-go/ecc"
+	"github.com/sebastianmontero/vrf-oracle/core/services/eos"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEOS_RoundRobinSigner(t *testing.T) {
+	ctx := context.Background()
+	signer := eos.NewRoundRobinSigner()
+	keys := make([]*ecc.PrivateKey, 0, 3)
+	for i := 0; i < 3; i++ {
+		key, err := ecc.NewRandomPrivateKey()
+		require.NoError(t, err)
+		keys = append(keys, key)
+	}
+	require.NoError(t, signer.ImportPrivateKey(ctx, keys[0].String()))
+	require.NoError(t, signer.ImportRotatingKey(ctx, keys[1].String()))
+	require.NoError(t, signer.ImportRotatingKey(ctx, keys[2].String()))
+
+	for _, expected := range []*ecc.PrivateKey{keys[1], keys[2], keys[1]} {
+		available, err := signer.AvailableKeys(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, []ecc.PublicKey{keys[0].PublicKey(), expected.PublicKey()}, available)
+	}
+}
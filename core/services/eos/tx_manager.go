@@ -0,0 +1,278 @@
+package eos
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	eosc "github.com/eoscanada/eos-go"
+	"github.com/sebastianmontero/vrf-oracle/core/logger"
+	strpkg "github.com/sebastianmontero/vrf-oracle/core/store"
+	"github.com/sebastianmontero/vrf-oracle/core/store/models"
+)
+
+//TxManager pushes transactions and tracks them until they are included in an irreversible block, a successful
+//push does not guarantee that, as a microfork can drop the transaction. Transactions are persisted before they
+//are pushed, and polled by getting the block they were included in, as get_transaction requires the history
+//plugin. Once the last irreversible block is past the expiration of a transaction that was not included, the
+//transaction can no longer be included, only then its actions are signed again with a new expiration and pushed,
+//so that they are never included twice. For the same reason a failed push only fails the transaction when the
+//node rejected it, if the push failed in any other way the node may have relayed it
+type TxManager struct {
+	Store  *strpkg.Store
+	EOS    *EOS
+	chStop chan struct{}
+	wg     sync.WaitGroup
+}
+
+func NewTxManager(store *strpkg.Store, eos *EOS) *TxManager {
+	return &TxManager{
+		Store: store,
+		EOS:   eos,
+	}
+}
+
+//Start starts polling for the inclusion of the pushed transactions
+func (m *TxManager) Start() {
+	m.chStop = make(chan struct{})
+	m.wg.Add(1)
+	go m.run()
+}
+
+//Stop stops polling and waits for the transaction being checked
+func (m *TxManager) Stop() {
+	close(m.chStop)
+	m.wg.Wait()
+}
+
+//rejectionErrors names of the chain errors with which a node rejects a transaction without relaying it, pushing
+//it again can not change the outcome. Resource exhaustion is included so that the caller can rent and push again
+var rejectionErrors = map[string]bool{
+	"eosio_assert_message_exception": true,
+	"eosio_assert_code_exception":    true,
+	"expired_tx_exception":           true,
+	"tx_duplicate":                   true,
+	"unsatisfied_authorization":      true,
+}
+
+//isRejection returns true if the push failed because the node rejected the transaction
+func isRejection(err error) bool {
+	var apiErr eosc.APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	return rejectionErrors[apiErr.ErrorStruct.Name] || ExhaustedResource(err) != ""
+}
+
+//Trx signs the actions in one transaction, persists it and pushes it, the transaction is marked as failed if
+//the node rejects it. If the outcome of the push is unknown the transaction is left to be checked and the
+//response reports it at the head block it was signed at
+func (m *TxManager) Trx(actions ...*eosc.Action) (*eosc.PushTransactionFullResp, error) {
+	data, err := encodeActions(actions)
+	if err != nil {
+		return nil, err
+	}
+	trx, err := m.EOS.signTrx(actions, m.Store.Config.EOSTxExpiration())
+	if err != nil {
+		return nil, err
+	}
+	tx := models.NewEOSTx(trx.ID, data, trx.HeadBlockNum, trx.Expiration)
+	err = m.Store.SaveEOSTx(tx)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to save transaction: %v, error: %v", trx.ID, err)
+	}
+	return m.push(tx, trx)
+}
+
+func (m *TxManager) push(tx *models.EOSTx, trx *signedTrx) (*eosc.PushTransactionFullResp, error) {
+	resp, pushErr := m.EOS.pushTrx(trx)
+	switch {
+	case pushErr == nil:
+		tx.Pushed(uint64(resp.BlockNum))
+	case isRejection(pushErr):
+		tx.Fail(pushErr.Error())
+	default:
+		//It is looked for from the head block on once it expires, and signed again if it was not included
+		logger.Warnf("Push of transaction: %v failed, checking whether it is included once it expires, error: %v", tx.CurrentTxID, pushErr)
+		tx.Pushed(tx.HeadBlockNum)
+		resp = &eosc.PushTransactionFullResp{
+			TransactionID: trx.ID,
+			BlockNum:      uint32(trx.HeadBlockNum),
+		}
+		pushErr = nil
+	}
+	err := m.Store.SaveEOSTx(tx)
+	if err != nil {
+		logger.Errorf("Failed to save pushed transaction: %v, error: %v", tx.CurrentTxID, err)
+	}
+	return resp, pushErr
+}
+
+func (m *TxManager) run() {
+	defer m.wg.Done()
+	ticker := time.NewTicker(m.Store.Config.EOSTxCheckInterval())
+	defer ticker.Stop()
+	for {
+		select {
+		case <-m.chStop:
+			return
+		case <-ticker.C:
+			m.checkTxs()
+		}
+	}
+}
+
+func (m *TxManager) checkTxs() {
+	lib, _, err := m.EOS.LastIrreversibleBlock()
+	if err != nil {
+		logger.Errorf("Failed to get last irreversible block: %v", err)
+		return
+	}
+	txs, err := m.Store.FindUnconfirmedEOSTxs(lib)
+	if err != nil {
+		logger.Errorf("Failed to find unconfirmed transactions: %v", err)
+		return
+	}
+	//Transactions pushed at the same time share the blocks, they are fetched once per pass
+	blocks := make(map[uint64]*Block)
+	for _, tx := range txs {
+		select {
+		case <-m.chStop:
+			return
+		default:
+		}
+		err := m.check(tx, lib, blocks)
+		if err != nil {
+			logger.Errorf("Failed to check transaction: %v, error: %v", tx.CurrentTxID, err)
+		}
+	}
+}
+
+func (m *TxManager) check(tx *models.EOSTx, lib uint64, blocks map[uint64]*Block) error {
+	block, err := m.block(tx.BlockNum, blocks)
+	if err != nil {
+		return err
+	}
+	if block.TxIDs[tx.CurrentTxID] {
+		return m.confirm(tx, block.Num)
+	}
+	libBlock, err := m.block(lib, blocks)
+	if err != nil {
+		return err
+	}
+	//The transaction could still be included in a block that is not irreversible yet
+	if !libBlock.Time.After(tx.ExpiresAt) {
+		return nil
+	}
+	//A microfork can move the transaction to any block after the one it was signed at, even one before the
+	//block reported by the push
+	for blockNum := tx.HeadBlockNum + 1; blockNum <= lib; blockNum++ {
+		block, err := m.block(blockNum, blocks)
+		if err != nil {
+			return err
+		}
+		if block.Time.After(tx.ExpiresAt) {
+			break
+		}
+		if block.TxIDs[tx.CurrentTxID] {
+			return m.confirm(tx, block.Num)
+		}
+	}
+	return m.resubmit(tx)
+}
+
+func (m *TxManager) confirm(tx *models.EOSTx, blockNum uint64) error {
+	logger.Infof("Transaction: %v is irreversible, block: %v", tx.CurrentTxID, blockNum)
+	tx.Confirm(blockNum)
+	return m.Store.SaveEOSTx(tx)
+}
+
+//resubmit signs the actions of an expired transaction again and pushes them, the new attempt is persisted
+//before it is pushed so that it is tracked even if the oracle stops in between. Like the first push, it only
+//fails the transaction if the node rejects it
+func (m *TxManager) resubmit(tx *models.EOSTx) error {
+	if tx.Attempts >= m.Store.Config.EOSTxMaxAttempts() {
+		logger.Warnf("Transaction: %v expired without being included, giving up after %v attempts", tx.CurrentTxID, tx.Attempts)
+		tx.Fail(fmt.Sprintf("Expired without being included after %v attempts", tx.Attempts))
+		return m.Store.SaveEOSTx(tx)
+	}
+	actions, err := decodeActions(tx.Actions)
+	if err != nil {
+		tx.Fail(err.Error())
+		return m.Store.SaveEOSTx(tx)
+	}
+	trx, err := m.EOS.signTrx(actions, m.Store.Config.EOSTxExpiration())
+	if err != nil {
+		return fmt.Errorf("Failed to sign transaction again: %v", err)
+	}
+	logger.Warnf("Transaction: %v expired without being included, pushing it again as: %v", tx.CurrentTxID, trx.ID)
+	tx.Resign(trx.ID, trx.HeadBlockNum, trx.Expiration)
+	err = m.Store.SaveEOSTx(tx)
+	if err != nil {
+		return fmt.Errorf("Failed to save transaction: %v, error: %v", trx.ID, err)
+	}
+	_, err = m.push(tx, trx)
+	return err
+}
+
+func (m *TxManager) block(blockNum uint64, blocks map[uint64]*Block) (*Block, error) {
+	block, ok := blocks[blockNum]
+	if !ok {
+		var err error
+		block, err = m.EOS.GetBlock(blockNum)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to get block: %v, error: %v", blockNum, err)
+		}
+		blocks[blockNum] = block
+	}
+	return block, nil
+}
+
+//storedAction an action with its data serialized, so that it can be persisted and signed again
+type storedAction struct {
+	Account       eosc.AccountName       `json:"account"`
+	Name          eosc.ActionName        `json:"name"`
+	Authorization []eosc.PermissionLevel `json:"authorization"`
+	Data          eosc.HexBytes          `json:"data"`
+}
+
+func encodeActions(actions []*eosc.Action) (models.JSON, error) {
+	stored := make([]*storedAction, 0, len(actions))
+	for _, action := range actions {
+		data, err := action.ActionData.EncodeActionData()
+		if err != nil {
+			return models.JSON{}, fmt.Errorf("Failed to encode action: %v, error: %v", action.Name, err)
+		}
+		stored = append(stored, &storedAction{
+			Account:       action.Account,
+			Name:          action.Name,
+			Authorization: action.Authorization,
+			Data:          data,
+		})
+	}
+	data, err := json.Marshal(stored)
+	if err != nil {
+		return models.JSON{}, fmt.Errorf("Failed to encode actions: %v", err)
+	}
+	return models.ParseJSON(data)
+}
+
+func decodeActions(data models.JSON) ([]*eosc.Action, error) {
+	var stored []*storedAction
+	err := json.Unmarshal(data.Bytes(), &stored)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to decode actions: %v", err)
+	}
+	actions := make([]*eosc.Action, 0, len(stored))
+	for _, action := range stored {
+		actions = append(actions, &eosc.Action{
+			Account:       action.Account,
+			Name:          action.Name,
+			Authorization: action.Authorization,
+			ActionData:    eosc.NewActionDataFromHexData(action.Data),
+		})
+	}
+	return actions, nil
+}
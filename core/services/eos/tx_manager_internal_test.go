@@ -0,0 +1,43 @@
+package eos
+
+import (
+	"testing"
+
+	eosc "github.com/eoscanada/eos-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTxManager_EncodeActions(t *testing.T) {
+	type setRand struct {
+		AssocID uint64           `json:"assoc_id"`
+		Caller  eosc.AccountName `json:"caller"`
+	}
+	action := BuildActionWithPermission("vrforacle", "setrand", "fulfiller", "fulfil", &setRand{
+		AssocID: 5,
+		Caller:  "user1",
+	})
+	expected, err := action.ActionData.EncodeActionData()
+	require.NoError(t, err)
+
+	data, err := encodeActions([]*eosc.Action{action})
+	require.NoError(t, err)
+	assert.Equal(t, "setrand", data.Get("0.name").String())
+
+	actions, err := decodeActions(data)
+	require.NoError(t, err)
+	require.Len(t, actions, 1)
+	assert.Equal(t, action.Account, actions[0].Account)
+	assert.Equal(t, action.Name, actions[0].Name)
+	assert.Equal(t, action.Authorization, actions[0].Authorization)
+	encoded, err := actions[0].ActionData.EncodeActionData()
+	require.NoError(t, err)
+	assert.Equal(t, expected, encoded)
+
+	//The decoded actions are signed with the same data
+	packed, err := eosc.MarshalBinary(actions[0])
+	require.NoError(t, err)
+	original, err := eosc.MarshalBinary(action)
+	require.NoError(t, err)
+	assert.Equal(t, original, packed)
+}
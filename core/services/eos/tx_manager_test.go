@@ -0,0 +1,430 @@
+package eos_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	eosc "github.com/eoscanada/eos-go"
+	"github.com/sebastianmontero/vrf-oracle/core/internal/cltest"
+	"github.com/sebastianmontero/vrf-oracle/core/services/eos"
+	strpkg "github.com/sebastianmontero/vrf-oracle/core/store"
+	"github.com/sebastianmontero/vrf-oracle/core/store/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testPrivateKey = "5KQwrPbwdL6PhXujxW37FSSQZ1JiwsST4cqQzDeyXtP79zkvFD3"
+	testPublicKey  = "EOS6MRyAjQq8ud7hVNYcfnVPJqcVpscN5So8BhtHuGYqET5GDW5CV"
+)
+
+//stubChain a node whose head, last irreversible block and blocks are set by the test, pushes succeed and
+//report the transaction as included in the reported block unless pushErr is set, blocks from expiredFrom on
+//are past the expiration of the transactions
+type stubChain struct {
+	mu          sync.Mutex
+	head        uint64
+	lib         uint64
+	reported    uint64
+	expiredFrom uint64
+	included    map[uint64][]string
+	pushed      []string
+	//pushErr the name of the chain error pushes are rejected with, pushes time out if it is pushTimeout
+	pushErr string
+}
+
+const pushTimeout = "timeout"
+
+func newStubChain(head, reported uint64) *stubChain {
+	return &stubChain{
+		head:        head,
+		lib:         head,
+		reported:    reported,
+		expiredFrom: ^uint64(0),
+		included:    make(map[uint64][]string),
+	}
+}
+
+func stubBlockID(blockNum uint64) string {
+	return fmt.Sprintf("%08x%056x", blockNum, 0)
+}
+
+func (m *stubChain) include(blockNum uint64, txID string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.included[blockNum] = append(m.included[blockNum], txID)
+}
+
+func (m *stubChain) set(lib, expiredFrom uint64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.lib = lib
+	m.expiredFrom = expiredFrom
+}
+
+func (m *stubChain) report(blockNum uint64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.reported = blockNum
+}
+
+func (m *stubChain) failPushes(pushErr string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.pushErr = pushErr
+}
+
+func (m *stubChain) pushedTxs() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]string{}, m.pushed...)
+}
+
+func (m *stubChain) blockTime(blockNum uint64) string {
+	blockTime := time.Now().Add(-time.Hour)
+	if blockNum >= m.expiredFrom {
+		blockTime = time.Now().Add(time.Hour)
+	}
+	return blockTime.UTC().Format(eosc.BlockTimestampFormat)
+}
+
+func (m *stubChain) serve(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		switch r.URL.Path {
+		case "/v1/chain/get_info":
+			fmt.Fprintf(w, `{
+				"chain_id": "%064x",
+				"head_block_num": %v,
+				"head_block_id": "%v",
+				"head_block_time": "%v",
+				"last_irreversible_block_num": %v,
+				"last_irreversible_block_id": "%v"
+			}`, 1, m.head, stubBlockID(m.head), m.blockTime(m.head), m.lib, stubBlockID(m.lib))
+		case "/v1/chain/get_required_keys":
+			fmt.Fprintf(w, `{"required_keys": ["%v"]}`, testPublicKey)
+		case "/v1/chain/push_transaction":
+			var packed eosc.PackedTransaction
+			require.NoError(t, json.Unmarshal(body, &packed))
+			id, err := packed.ID()
+			require.NoError(t, err)
+			m.pushed = append(m.pushed, id.String())
+			switch m.pushErr {
+			case "":
+			case pushTimeout:
+				w.WriteHeader(http.StatusGatewayTimeout)
+				fmt.Fprint(w, "upstream request timeout")
+				return
+			default:
+				w.WriteHeader(http.StatusInternalServerError)
+				fmt.Fprintf(w, `{"code": 500, "message": "Internal Service Error", "error": {"code": 3050003, "name": "%v", "what": "Rejected", "details": []}}`, m.pushErr)
+				return
+			}
+			fmt.Fprintf(w, `{"transaction_id": "%v", "processed": {"id": "%v", "block_num": %v}}`, id, id, m.reported)
+		case "/v1/chain/get_block":
+			var req struct {
+				BlockNumOrID string `json:"block_num_or_id"`
+			}
+			require.NoError(t, json.Unmarshal(body, &req))
+			blockNum, err := strconv.ParseUint(req.BlockNumOrID, 10, 64)
+			require.NoError(t, err)
+			receipts := make([]string, 0, len(m.included[blockNum]))
+			for _, txID := range m.included[blockNum] {
+				receipts = append(receipts, fmt.Sprintf(`{"status": "executed", "cpu_usage_us": 100, "net_usage_words": 10, "trx": "%v"}`, txID))
+			}
+			fmt.Fprintf(w, `{"id": "%v", "block_num": %v, "timestamp": "%v", "producer": "eosio", "transactions": [%v]}`,
+				stubBlockID(blockNum), blockNum, m.blockTime(blockNum), strings.Join(receipts, ","))
+		default:
+			require.FailNow(t, "Unexpected call", r.URL.Path)
+		}
+	}))
+}
+
+type testSetRandData struct {
+	AssocID uint64 `json:"assoc_id"`
+}
+
+func newTxManager(t *testing.T, store *strpkg.Store, server *httptest.Server) (*eos.EOS, *eos.TxManager) {
+	eosAPI, err := eos.New(server.URL, []string{testPrivateKey})
+	require.NoError(t, err)
+	manager := eos.NewTxManager(store, eosAPI)
+	eosAPI.TxManager = manager
+	return eosAPI, manager
+}
+
+//pushTx pushes a transaction through the manager and returns it as stored
+func pushTx(t *testing.T, store *strpkg.Store, eosAPI *eos.EOS) *models.EOSTx {
+	resp, err := eosAPI.Trx(eos.BuildAction("vrf", "setrand", "oracle", &testSetRandData{AssocID: 1}))
+	require.NoError(t, err)
+	return findTx(t, store, resp.TransactionID)
+}
+
+func findTx(t *testing.T, store *strpkg.Store, txID string) *models.EOSTx {
+	txs, err := store.FindEOSTxs(txID)
+	require.NoError(t, err)
+	require.Len(t, txs, 1)
+	return txs[0]
+}
+
+func TestTxManager_ConfirmedInReportedBlock(t *testing.T) {
+	t.Parallel()
+	store, cleanup := cltest.NewStore(t)
+	defer cleanup()
+	chain := newStubChain(100, 102)
+	server := chain.serve(t)
+	defer server.Close()
+	eosAPI, manager := newTxManager(t, store, server)
+
+	tx := pushTx(t, store, eosAPI)
+	assert.Equal(t, uint64(100), tx.HeadBlockNum)
+	assert.Equal(t, uint64(102), tx.BlockNum)
+	chain.include(102, tx.TxID)
+
+	//The reported block is not irreversible yet
+	chain.set(101, ^uint64(0))
+	manager.ExportedCheckTxs()
+	assert.Equal(t, models.EOSTxStatus(models.EOSTxStatus_UNCONFIRMED), findTx(t, store, tx.TxID).Status)
+
+	chain.set(110, ^uint64(0))
+	manager.ExportedCheckTxs()
+	tx = findTx(t, store, tx.TxID)
+	assert.Equal(t, models.EOSTxStatus(models.EOSTxStatus_CONFIRMED), tx.Status)
+	assert.Equal(t, uint64(102), tx.BlockNum)
+	assert.True(t, tx.ConfirmedAt.Valid)
+	assert.Len(t, chain.pushedTxs(), 1)
+}
+
+func TestTxManager_FoundInOtherBlock(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name     string
+		blockNum uint64
+	}{
+		{"later block", 107},
+		//A microfork moved it to a block between the one it was signed at and the reported one
+		{"earlier block", 101},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			store, cleanup := cltest.NewStore(t)
+			defer cleanup()
+			chain := newStubChain(100, 105)
+			server := chain.serve(t)
+			defer server.Close()
+			eosAPI, manager := newTxManager(t, store, server)
+
+			tx := pushTx(t, store, eosAPI)
+			chain.include(test.blockNum, tx.TxID)
+
+			//It is only looked for in other blocks once the transaction can no longer be included
+			chain.set(110, ^uint64(0))
+			manager.ExportedCheckTxs()
+			assert.Equal(t, models.EOSTxStatus(models.EOSTxStatus_UNCONFIRMED), findTx(t, store, tx.TxID).Status)
+
+			chain.set(120, 110)
+			manager.ExportedCheckTxs()
+			tx = findTx(t, store, tx.TxID)
+			assert.Equal(t, models.EOSTxStatus(models.EOSTxStatus_CONFIRMED), tx.Status)
+			assert.Equal(t, test.blockNum, tx.BlockNum)
+			assert.Equal(t, uint32(1), tx.Attempts)
+			assert.Len(t, chain.pushedTxs(), 1)
+		})
+	}
+}
+
+func TestTxManager_ExpiredAndResigned(t *testing.T) {
+	t.Parallel()
+	store, cleanup := cltest.NewStore(t)
+	defer cleanup()
+	chain := newStubChain(100, 102)
+	server := chain.serve(t)
+	defer server.Close()
+	eosAPI, manager := newTxManager(t, store, server)
+
+	tx := pushTx(t, store, eosAPI)
+	chain.set(120, 110)
+	chain.report(122)
+	//A different expiration so that the new attempt gets a different id even if it is signed at the same head
+	store.Config.Set("EOS_TX_EXPIRATION", time.Minute)
+	manager.ExportedCheckTxs()
+
+	pushed := chain.pushedTxs()
+	require.Len(t, pushed, 2)
+	tx = findTx(t, store, tx.TxID)
+	assert.Equal(t, models.EOSTxStatus(models.EOSTxStatus_UNCONFIRMED), tx.Status)
+	assert.Equal(t, uint32(2), tx.Attempts)
+	assert.Equal(t, pushed[0], tx.TxID)
+	assert.Equal(t, pushed[1], tx.CurrentTxID)
+	assert.NotEqual(t, tx.TxID, tx.CurrentTxID)
+	assert.Equal(t, uint64(122), tx.BlockNum)
+
+	chain.include(122, tx.CurrentTxID)
+	chain.set(125, ^uint64(0))
+	manager.ExportedCheckTxs()
+	tx = findTx(t, store, tx.TxID)
+	assert.Equal(t, models.EOSTxStatus(models.EOSTxStatus_CONFIRMED), tx.Status)
+	assert.Equal(t, uint64(122), tx.BlockNum)
+}
+
+func TestTxManager_GiveUpAfterMaxAttempts(t *testing.T) {
+	t.Parallel()
+	store, cleanup := cltest.NewStore(t)
+	defer cleanup()
+	store.Config.Set("EOS_TX_MAX_ATTEMPTS", 1)
+	chain := newStubChain(100, 102)
+	server := chain.serve(t)
+	defer server.Close()
+	eosAPI, manager := newTxManager(t, store, server)
+
+	tx := pushTx(t, store, eosAPI)
+	chain.set(120, 110)
+	manager.ExportedCheckTxs()
+
+	tx = findTx(t, store, tx.TxID)
+	assert.Equal(t, models.EOSTxStatus(models.EOSTxStatus_FAILED), tx.Status)
+	assert.Equal(t, "Expired without being included after 1 attempts", tx.Error)
+	assert.Len(t, chain.pushedTxs(), 1)
+
+	//Failed transactions are no longer checked
+	txs, err := store.FindUnconfirmedEOSTxs(120)
+	require.NoError(t, err)
+	assert.Empty(t, txs)
+}
+
+func TestTxManager_Rejected(t *testing.T) {
+	t.Parallel()
+	tests := []string{"eosio_assert_message_exception", "expired_tx_exception", "tx_duplicate"}
+	for _, pushErr := range tests {
+		pushErr := pushErr
+		t.Run(pushErr, func(t *testing.T) {
+			t.Parallel()
+			store, cleanup := cltest.NewStore(t)
+			defer cleanup()
+			chain := newStubChain(100, 102)
+			chain.failPushes(pushErr)
+			server := chain.serve(t)
+			defer server.Close()
+			eosAPI, manager := newTxManager(t, store, server)
+
+			_, err := eosAPI.Trx(eos.BuildAction("vrf", "setrand", "oracle", &testSetRandData{AssocID: 1}))
+			require.Error(t, err)
+			pushed := chain.pushedTxs()
+			require.Len(t, pushed, 1)
+			tx := findTx(t, store, pushed[0])
+			assert.Equal(t, models.EOSTxStatus(models.EOSTxStatus_FAILED), tx.Status)
+			assert.Contains(t, tx.Error, "Rejected")
+
+			//Rejected transactions are not pushed again
+			chain.set(120, 110)
+			manager.ExportedCheckTxs()
+			assert.Len(t, chain.pushedTxs(), 1)
+		})
+	}
+}
+
+func TestTxManager_PushOutcomeUnknown(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name     string
+		included bool
+	}{
+		{"relayed before the error", true},
+		{"not relayed", false},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			store, cleanup := cltest.NewStore(t)
+			defer cleanup()
+			chain := newStubChain(100, 102)
+			chain.failPushes(pushTimeout)
+			server := chain.serve(t)
+			defer server.Close()
+			eosAPI, manager := newTxManager(t, store, server)
+
+			resp, err := eosAPI.Trx(eos.BuildAction("vrf", "setrand", "oracle", &testSetRandData{AssocID: 1}))
+			require.NoError(t, err)
+			assert.Equal(t, uint32(100), resp.BlockNum)
+			tx := findTx(t, store, resp.TransactionID)
+			assert.Equal(t, models.EOSTxStatus(models.EOSTxStatus_UNCONFIRMED), tx.Status)
+			assert.Equal(t, tx.HeadBlockNum, tx.BlockNum)
+			if test.included {
+				chain.include(103, tx.TxID)
+			}
+
+			//It is left alone until it expires
+			chain.failPushes("")
+			chain.set(110, ^uint64(0))
+			manager.ExportedCheckTxs()
+			assert.Equal(t, models.EOSTxStatus(models.EOSTxStatus_UNCONFIRMED), findTx(t, store, tx.TxID).Status)
+			assert.Len(t, chain.pushedTxs(), 1)
+
+			chain.set(120, 110)
+			store.Config.Set("EOS_TX_EXPIRATION", time.Minute)
+			manager.ExportedCheckTxs()
+			tx = findTx(t, store, tx.TxID)
+			if test.included {
+				assert.Equal(t, models.EOSTxStatus(models.EOSTxStatus_CONFIRMED), tx.Status)
+				assert.Equal(t, uint64(103), tx.BlockNum)
+				assert.Len(t, chain.pushedTxs(), 1)
+			} else {
+				assert.Equal(t, models.EOSTxStatus(models.EOSTxStatus_UNCONFIRMED), tx.Status)
+				assert.Equal(t, uint32(2), tx.Attempts)
+				assert.Len(t, chain.pushedTxs(), 2)
+			}
+		})
+	}
+}
+
+func TestTxManager_ResubmitFails(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name    string
+		pushErr string
+		status  models.EOSTxStatus
+	}{
+		{"rejected", "eosio_assert_message_exception", models.EOSTxStatus_FAILED},
+		//The new attempt is checked once it expires
+		{"timed out", pushTimeout, models.EOSTxStatus_UNCONFIRMED},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			store, cleanup := cltest.NewStore(t)
+			defer cleanup()
+			chain := newStubChain(100, 102)
+			server := chain.serve(t)
+			defer server.Close()
+			eosAPI, manager := newTxManager(t, store, server)
+
+			tx := pushTx(t, store, eosAPI)
+			chain.set(120, 110)
+			chain.failPushes(test.pushErr)
+			store.Config.Set("EOS_TX_EXPIRATION", time.Minute)
+			manager.ExportedCheckTxs()
+
+			pushed := chain.pushedTxs()
+			require.Len(t, pushed, 2)
+			tx = findTx(t, store, tx.TxID)
+			assert.Equal(t, test.status, tx.Status)
+			assert.Equal(t, uint32(2), tx.Attempts)
+			assert.Equal(t, pushed[1], tx.CurrentTxID)
+			assert.Equal(t, tx.HeadBlockNum, tx.BlockNum)
+		})
+	}
+}
@@ -0,0 +1,11 @@
+package svrf
+
+import "github.com/sebastianmontero/vrf-oracle/core/store/models"
+
+func (m *VRFResolver) ExportedResolved(job *models.VRFRequestJob) (string, error) {
+	return m.resolved(job)
+}
+
+func (m *VRFKeyLoader) ExportedReload() error {
+	return m.reload()
+}
@@ -0,0 +1,157 @@
+package svrf
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	eosc "github.com/eoscanada/eos-go"
+	"github.com/sebastianmontero/vrf-oracle/core/logger"
+	"github.com/sebastianmontero/vrf-oracle/core/services/eos"
+)
+
+var errBatcherStopped = errors.New("VRF batcher stopped")
+
+//vrfFulfilment a setrand action waiting to be pushed, the result of the push is sent through the result channel
+type vrfFulfilment struct {
+	action *eosc.Action
+	size   int
+	result chan *vrfPushResult
+}
+
+//vrfPushResult the transaction the action was pushed in or the error pushing it
+type vrfPushResult struct {
+	resp *eosc.PushTransactionFullResp
+	err  error
+}
+
+//VRFBatcher gathers the setrand actions of the jobs being processed during a window or until the size
+//limit is reached and pushes them in one transaction. Batches are split so that the packed size of the
+//actions does not exceed MaxBytes, if a push fails the batch is split in halves and each one is pushed
+//again, that way batches that exceed the CPU/NET limits are reduced and an action that fails does not
+//fail the rest of the batch. If a renter is set resources are rented when a push fails because of
+//resource exhaustion. Jobs are processed by the workers, so the number of workers bounds the
+//size of a batch.
+type VRFBatcher struct {
+	Push     PushActions
+	Renter   *eos.ResourceRenter
+	MaxSize  int
+	MaxBytes int
+	Window   time.Duration
+	queue    chan *vrfFulfilment
+	chStop   chan struct{}
+	wg       sync.WaitGroup
+}
+
+//PushActions pushes the actions in one transaction, VRF.SetRands in the oracle
+type PushActions func(actions ...*eosc.Action) (*eosc.PushTransactionFullResp, error)
+
+func NewVRFBatcher(push PushActions, renter *eos.ResourceRenter, maxSize int, maxBytes int, window time.Duration) *VRFBatcher {
+	return &VRFBatcher{
+		Push:     push,
+		Renter:   renter,
+		MaxSize:  maxSize,
+		MaxBytes: maxBytes,
+		Window:   window,
+		queue:    make(chan *vrfFulfilment),
+	}
+}
+
+//Start starts gathering actions
+func (m *VRFBatcher) Start() {
+	m.chStop = make(chan struct{})
+	m.wg.Add(1)
+	go m.run()
+}
+
+//Stop stops gathering actions, the batch being gathered is pushed before returning
+func (m *VRFBatcher) Stop() {
+	close(m.chStop)
+	m.wg.Wait()
+}
+
+//Fulfil adds the action to the current batch and waits until it is pushed, returns the transaction
+//the action was pushed in
+func (m *VRFBatcher) Fulfil(action *eosc.Action) (*eosc.PushTransactionFullResp, error) {
+	data, err := eosc.MarshalBinary(action)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to pack action: %v, error: %v", action, err)
+	}
+	fulfilment := &vrfFulfilment{
+		action: action,
+		size:   len(data),
+		result: make(chan *vrfPushResult, 1),
+	}
+	select {
+	case <-m.chStop:
+		return nil, errBatcherStopped
+	case m.queue <- fulfilment:
+	}
+	result := <-fulfilment.result
+	return result.resp, result.err
+}
+
+func (m *VRFBatcher) run() {
+	defer m.wg.Done()
+	for {
+		var batch []*vrfFulfilment
+		select {
+		case <-m.chStop:
+			return
+		case fulfilment := <-m.queue:
+			batch = append(batch, fulfilment)
+		}
+		window := time.NewTimer(m.Window)
+	gather:
+		for len(batch) < m.MaxSize {
+			select {
+			case <-m.chStop:
+				break gather
+			case <-window.C:
+				break gather
+			case fulfilment := <-m.queue:
+				batch = append(batch, fulfilment)
+			}
+		}
+		window.Stop()
+		m.flush(batch)
+	}
+}
+
+//flush splits the batch into chunks whose packed size does not exceed MaxBytes and pushes them
+func (m *VRFBatcher) flush(batch []*vrfFulfilment) {
+	start, size := 0, 0
+	for i, fulfilment := range batch {
+		if i > start && size+fulfilment.size > m.MaxBytes {
+			m.push(batch[start:i])
+			start, size = i, 0
+		}
+		size += fulfilment.size
+	}
+	m.push(batch[start:])
+}
+
+func (m *VRFBatcher) push(batch []*vrfFulfilment) {
+	actions := make([]*eosc.Action, 0, len(batch))
+	for _, fulfilment := range batch {
+		actions = append(actions, fulfilment.action)
+	}
+	logger.Infof("Pushing %v setrand actions in one transaction", len(actions))
+	var resp *eosc.PushTransactionFullResp
+	err := m.Renter.Retry(func() error {
+		var err error
+		resp, err = m.Push(actions...)
+		return err
+	})
+	if err != nil && len(batch) > 1 {
+		logger.Infof("Failed to push batch of %v setrand actions, splitting it, error: %v", len(batch), err)
+		half := len(batch) / 2
+		m.push(batch[:half])
+		m.push(batch[half:])
+		return
+	}
+	for _, fulfilment := range batch {
+		fulfilment.result <- &vrfPushResult{resp: resp, err: err}
+	}
+}
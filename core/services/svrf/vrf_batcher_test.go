@@ -0,0 +1,144 @@
+package svrf_test
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	eosc "github.com/eoscanada/eos-go"
+	"github.com/sebastianmontero/vrf-oracle/core/services/eos"
+	"github.com/sebastianmontero/vrf-oracle/core/services/svrf"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testSetRand struct {
+	AssocID uint64 `json:"assoc_id"`
+}
+
+func newSetRandAction(assocID uint64) *eosc.Action {
+	return eos.BuildAction("vrf", "setrand", "oracle", &testSetRand{AssocID: assocID})
+}
+
+//stubPush records the batches pushed, fails those that contain any of the failing actions and
+//returns a transaction id per push
+type stubPush struct {
+	failing map[*eosc.Action]bool
+	mu      sync.Mutex
+	batches [][]*eosc.Action
+}
+
+func (m *stubPush) push(actions ...*eosc.Action) (*eosc.PushTransactionFullResp, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.batches = append(m.batches, actions)
+	for _, action := range actions {
+		if m.failing[action] {
+			return nil, errors.New("assertion failure with message: request not found")
+		}
+	}
+	return &eosc.PushTransactionFullResp{TransactionID: fmt.Sprintf("tx%v", len(m.batches))}, nil
+}
+
+//txOf returns the id of the successful transaction that included the action
+func (m *stubPush) txOf(action *eosc.Action) string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, batch := range m.batches {
+		failed := false
+		included := false
+		for _, a := range batch {
+			failed = failed || m.failing[a]
+			included = included || a == action
+		}
+		if included && !failed {
+			return fmt.Sprintf("tx%v", i+1)
+		}
+	}
+	return ""
+}
+
+type fulfilResult struct {
+	resp *eosc.PushTransactionFullResp
+	err  error
+}
+
+//fulfilAll fulfils the actions concurrently so that they end up in the same batch
+func fulfilAll(batcher *svrf.VRFBatcher, actions []*eosc.Action) []fulfilResult {
+	results := make([]fulfilResult, len(actions))
+	var wg sync.WaitGroup
+	for i, action := range actions {
+		wg.Add(1)
+		go func(i int, action *eosc.Action) {
+			defer wg.Done()
+			resp, err := batcher.Fulfil(action)
+			results[i] = fulfilResult{resp: resp, err: err}
+		}(i, action)
+	}
+	wg.Wait()
+	return results
+}
+
+func TestVRFBatcher_MaxBytes(t *testing.T) {
+	actions := []*eosc.Action{newSetRandAction(1), newSetRandAction(2), newSetRandAction(3), newSetRandAction(4), newSetRandAction(5)}
+	data, err := eosc.MarshalBinary(actions[0])
+	require.NoError(t, err)
+	stub := &stubPush{}
+	//Two actions fit in a transaction
+	batcher := svrf.NewVRFBatcher(stub.push, nil, len(actions), 2*len(data)+1, 5*time.Second)
+	batcher.Start()
+	defer batcher.Stop()
+
+	results := fulfilAll(batcher, actions)
+
+	require.Len(t, stub.batches, 3)
+	sizes := []int{len(stub.batches[0]), len(stub.batches[1]), len(stub.batches[2])}
+	assert.Equal(t, []int{2, 2, 1}, sizes)
+	for i, result := range results {
+		require.NoError(t, result.err)
+		assert.Equal(t, stub.txOf(actions[i]), result.resp.TransactionID)
+	}
+}
+
+func TestVRFBatcher_SplitOnFailure(t *testing.T) {
+	actions := []*eosc.Action{newSetRandAction(1), newSetRandAction(2), newSetRandAction(3), newSetRandAction(4)}
+	stub := &stubPush{failing: map[*eosc.Action]bool{actions[2]: true}}
+	batcher := svrf.NewVRFBatcher(stub.push, nil, len(actions), 1000, 5*time.Second)
+	batcher.Start()
+	defer batcher.Stop()
+
+	results := fulfilAll(batcher, actions)
+
+	//The batch of 4 fails, one of its halves succeeds and the other one is split again
+	require.Len(t, stub.batches, 5)
+	sizes := make([]int, 0, len(stub.batches))
+	for _, batch := range stub.batches {
+		sizes = append(sizes, len(batch))
+	}
+	assert.Equal(t, 4, sizes[0])
+	assert.ElementsMatch(t, []int{4, 2, 2, 1, 1}, sizes)
+	for i, result := range results {
+		if i == 2 {
+			assert.EqualError(t, result.err, "assertion failure with message: request not found")
+			assert.Nil(t, result.resp)
+			continue
+		}
+		require.NoError(t, result.err)
+		assert.Equal(t, stub.txOf(actions[i]), result.resp.TransactionID)
+	}
+}
+
+func TestVRFBatcher_Window(t *testing.T) {
+	stub := &stubPush{}
+	batcher := svrf.NewVRFBatcher(stub.push, nil, 10, 1000, 50*time.Millisecond)
+	batcher.Start()
+	defer batcher.Stop()
+
+	resp, err := batcher.Fulfil(newSetRandAction(1))
+	require.NoError(t, err)
+	assert.Equal(t, "tx1", resp.TransactionID)
+	require.Len(t, stub.batches, 1)
+	assert.Len(t, stub.batches[0], 1)
+}
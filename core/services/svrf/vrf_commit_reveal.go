@@ -0,0 +1,213 @@
+package svrf
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/sebastianmontero/vrf-oracle/core/logger"
+	"github.com/sebastianmontero/vrf-oracle/core/services/eos"
+	"github.com/sebastianmontero/vrf-oracle/core/services/vrf"
+	strpkg "github.com/sebastianmontero/vrf-oracle/core/store"
+	"github.com/sebastianmontero/vrf-oracle/core/store/models"
+	"github.com/sebastianmontero/vrf-oracle/core/utils"
+)
+
+//Commit-reveal strategy, for callers that can not afford verifying the vrf proofs:
+//when the request is processed a random secret is generated for each seed and the keccak256 hash of each
+//secret is committed with setcommit, once the block that included the setcommit transaction is irreversible
+//the VRFRevealer dispatches the job to the worker pool again and the secrets are revealed, the final value of
+//each seed is FinalSeed(secret, id of the block of the request). Revealing before the commit is irreversible
+//would disclose the secrets of a commit that a microfork can still drop
+
+//usesCommitReveal returns true if the requests of any caller are fulfilled with the commit-reveal strategy
+func usesCommitReveal(store *strpkg.Store) bool {
+	strategies, err := store.Config.VRFCallerStrategies()
+	if err != nil {
+		return false
+	}
+	for _, strategy := range strategies {
+		if strategy == models.VRFStrategy_COMMIT_REVEAL {
+			return true
+		}
+	}
+	return false
+}
+
+//commitReveal commits the secrets of the job or reveals them if they were already committed
+func (m *VRFResolver) commitReveal(job *models.VRFRequestJob, run *models.VRFRequestRun) (*models.VRFRequestRun, error) {
+	if job.CommittedAt.Valid {
+		return m.reveal(job, run)
+	}
+	return m.commit(job, run)
+}
+
+func (m *VRFResolver) commit(job *models.VRFRequestJob, run *models.VRFRequestRun) (*models.VRFRequestRun, error) {
+	req := job.VRFRequest
+	if len(job.Secrets) != len(req.Seeds) {
+		secrets, err := generateSecrets(len(req.Seeds))
+		if err != nil {
+			err := fmt.Errorf("Failed to generate secrets: %v", err)
+			m.failRun(run, err, m.maxRetries())
+			return run, err
+		}
+		//The secrets are stored before they are committed so that they can be revealed after a restart
+		job.Secrets = secrets
+		m.saveJob(job)
+	}
+	secrets, err := decodeSecrets(job.Secrets)
+	if err != nil {
+		m.failRun(run, err, 0)
+		return run, err
+	}
+	commitments := make([][]byte, 0, len(secrets))
+	for _, secret := range secrets {
+		commitments = append(commitments, utils.MustHash(string(secret)).Bytes())
+	}
+	resp, err := m.push(m.VRFContract.SetCommitAction(req.AssocID, req.Caller, commitments))
+	if err != nil {
+		err := fmt.Errorf("Calling SetCommit on contract failed: %v", err)
+		m.failRun(run, err, m.maxRetries())
+		return run, err
+	}
+	run.SetTx(resp.TransactionID, uint64(resp.BlockNum))
+	job.CommittedAt.SetValid(time.Now())
+	job.CommitTxBlockNum = run.TxBlockNum
+	run.Commit(fmt.Sprintf("Committed, it will be revealed once block: %v is irreversible", job.CommitTxBlockNum))
+	m.saveRun(run, false)
+	return run, nil
+}
+
+func (m *VRFResolver) reveal(job *models.VRFRequestJob, run *models.VRFRequestRun) (*models.VRFRequestRun, error) {
+	req := job.VRFRequest
+	secrets, err := decodeSecrets(job.Secrets)
+	if err != nil {
+		m.failRun(run, err, 0)
+		return run, err
+	}
+	blockHash := common.HexToHash(job.BlockHash)
+	resp, err := m.push(m.VRFContract.RevealAction(req.AssocID, req.Caller, blockHash.Bytes(), secrets))
+	if err != nil {
+		err := fmt.Errorf("Calling Reveal on contract failed: %v", err)
+		m.failRun(run, err, m.maxRetries())
+		return run, err
+	}
+	run.SetTx(resp.TransactionID, uint64(resp.BlockNum))
+	run.UpdateStatus(
+		models.VRFRequestRunStatus_COMPLETED,
+		fmt.Sprintf("Revealed, final values: %v", RevealedValues(secrets, blockHash)),
+		m.maxRetries())
+	m.saveRun(run, isCompleted(req))
+	return run, nil
+}
+
+//RevealedValues the final value of each secret, mixed with the block id the same way the seed of a vrf proof is
+func RevealedValues(secrets [][]byte, blockHash common.Hash) []*big.Int {
+	values := make([]*big.Int, 0, len(secrets))
+	for _, secret := range secrets {
+		var preSeed vrf.Seed
+		copy(preSeed[:], secret)
+		values = append(values, vrf.FinalSeed(vrf.PreSeedData{
+			PreSeed:   preSeed,
+			BlockHash: blockHash,
+		}))
+	}
+	return values
+}
+
+func generateSecrets(count int) ([]string, error) {
+	secrets := make([]string, 0, count)
+	for i := 0; i < count; i++ {
+		secret := make([]byte, 32)
+		_, err := rand.Read(secret)
+		if err != nil {
+			return nil, err
+		}
+		secrets = append(secrets, hex.EncodeToString(secret))
+	}
+	return secrets, nil
+}
+
+func decodeSecrets(secrets []string) ([][]byte, error) {
+	decoded := make([][]byte, 0, len(secrets))
+	for _, secret := range secrets {
+		data, err := hex.DecodeString(secret)
+		if err != nil || len(data) != 32 {
+			return nil, fmt.Errorf("Invalid secret, expected 32 hex encoded bytes")
+		}
+		decoded = append(decoded, data)
+	}
+	return decoded, nil
+}
+
+//VRFRevealer periodically dispatches to the worker pool the committed jobs of the commit-reveal strategy whose
+//setcommit transaction block has become irreversible
+type VRFRevealer struct {
+	Store    *strpkg.Store
+	EOS      *eos.EOS
+	Resolver *VRFResolver
+	chStop   chan struct{}
+	wg       sync.WaitGroup
+}
+
+func NewVRFRevealer(store *strpkg.Store, eos *eos.EOS, resolver *VRFResolver) *VRFRevealer {
+	return &VRFRevealer{
+		Store:    store,
+		EOS:      eos,
+		Resolver: resolver,
+	}
+}
+
+//Start starts polling for jobs to reveal
+func (m *VRFRevealer) Start() {
+	m.chStop = make(chan struct{})
+	m.wg.Add(1)
+	go m.run()
+}
+
+//Stop stops polling, the jobs already dispatched are left to the worker pool
+func (m *VRFRevealer) Stop() {
+	close(m.chStop)
+	m.wg.Wait()
+}
+
+func (m *VRFRevealer) run() {
+	defer m.wg.Done()
+	ticker := time.NewTicker(m.Store.Config.VRFRevealInterval())
+	defer ticker.Stop()
+	for {
+		select {
+		case <-m.chStop:
+			return
+		case <-ticker.C:
+			m.revealJobs()
+		}
+	}
+}
+
+func (m *VRFRevealer) revealJobs() {
+	lib, _, err := m.EOS.LastIrreversibleBlock()
+	if err != nil {
+		logger.Errorf("Failed to get last irreversible block: %v", err)
+		return
+	}
+	jobs, err := m.Store.FindCommittedVRFRequestJobs(lib)
+	if err != nil {
+		logger.Errorf("Failed to find committed vrf request jobs: %v", err)
+		return
+	}
+	for _, job := range jobs {
+		select {
+		case <-m.chStop:
+			return
+		default:
+		}
+		if m.Resolver.WorkerPool.Dispatch(job) {
+			logger.Infof("Revealing vrf request job: %v, commit block: %v is irreversible", job.ID, job.CommitTxBlockNum)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package svrf
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/sebastianmontero/vrf-oracle/core/logger"
+	strpkg "github.com/sebastianmontero/vrf-oracle/core/store"
+	"github.com/sebastianmontero/vrf-oracle/core/store/models"
+)
+
+//VRFConfirmer marks the completed runs as confirmed once the transaction they pushed is irreversible. The
+//transactions are tracked by the eos TxManager, which pushes them again if they are dropped by a microfork,
+//if it gives up on a transaction the run is failed and its job is processed again by the retry worker
+type VRFConfirmer struct {
+	Store  *strpkg.Store
+	chStop chan struct{}
+	wg     sync.WaitGroup
+}
+
+func NewVRFConfirmer(store *strpkg.Store) *VRFConfirmer {
+	return &VRFConfirmer{
+		Store: store,
+	}
+}
+
+//Start starts polling for runs to confirm
+func (m *VRFConfirmer) Start() {
+	m.chStop = make(chan struct{})
+	m.wg.Add(1)
+	go m.run()
+}
+
+//Stop stops polling and waits for the run being confirmed
+func (m *VRFConfirmer) Stop() {
+	close(m.chStop)
+	m.wg.Wait()
+}
+
+func (m *VRFConfirmer) run() {
+	defer m.wg.Done()
+	ticker := time.NewTicker(m.Store.Config.VRFConfirmInterval())
+	defer ticker.Stop()
+	for {
+		select {
+		case <-m.chStop:
+			return
+		case <-ticker.C:
+			m.confirmRuns()
+		}
+	}
+}
+
+func (m *VRFConfirmer) confirmRuns() {
+	runs, err := m.Store.FindVRFRequestRunsToConfirm()
+	if err != nil {
+		logger.Errorf("Failed to find vrf request runs to confirm: %v", err)
+		return
+	}
+	if len(runs) == 0 {
+		return
+	}
+	//Batched runs share the transaction
+	txIDs := make([]string, 0, len(runs))
+	for _, run := range runs {
+		txIDs = append(txIDs, run.TxID)
+	}
+	txs, err := m.Store.FindEOSTxs(txIDs...)
+	if err != nil {
+		logger.Errorf("Failed to find transactions of vrf request runs: %v", err)
+		return
+	}
+	txsByID := make(map[string]*models.EOSTx, len(txs))
+	for _, tx := range txs {
+		txsByID[tx.TxID] = tx
+	}
+	for _, run := range runs {
+		select {
+		case <-m.chStop:
+			return
+		default:
+		}
+		err := m.confirm(run, txsByID[run.TxID])
+		if err != nil {
+			logger.Errorf("Failed to confirm vrf request run: %v, transaction: %v, error: %v", run.ID, run.TxID, err)
+		}
+	}
+}
+
+func (m *VRFConfirmer) confirm(run *models.VRFRequestRun, tx *models.EOSTx) error {
+	if tx == nil {
+		return fmt.Errorf("Transaction not found")
+	}
+	if tx.Status == models.EOSTxStatus_FAILED {
+		job := run.VRFRequestJob
+		logger.Warnf("Transaction: %v of vrf request run: %v was dropped, processing job: %v again", tx.CurrentTxID, run.ID, job.ID)
+		run.Drop(fmt.Sprintf("Dropped, transaction: %v was not included in an irreversible block: %v", tx.CurrentTxID, tx.Error))
+		//Recurrent requests are held in memory by the scheduler
+		return m.Store.SaveVRFRequestRun(run, job.VRFRequest.Type != models.VRFRequestType_RECURRENT)
+	}
+	logger.Infof("Transaction: %v of vrf request run: %v is irreversible, block: %v", tx.CurrentTxID, run.ID, tx.BlockNum)
+	//The transaction may have been pushed again with a different id
+	run.TxID = tx.CurrentTxID
+	run.Confirm(tx.BlockNum)
+	return m.Store.SaveVRFRequestRun(run, false)
+}
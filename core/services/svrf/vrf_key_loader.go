@@ -0,0 +1,156 @@
+package svrf
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/sebastianmontero/vrf-oracle/core/logger"
+	strpkg "github.com/sebastianmontero/vrf-oracle/core/store"
+	"github.com/sebastianmontero/vrf-oracle/core/store/models/vrfkey"
+)
+
+//VRFKeyLoader unlocks the vrf keys and selects the default key, which is the configured one, the new key of
+//the last key rotation or the first unlocked key. It checks the keys in the db periodically so that the keys
+//created and archived by a key rotation are picked up without restarting the oracle
+type VRFKeyLoader struct {
+	Store      *strpkg.Store
+	Password   string
+	DefaultKey string
+	Interval   time.Duration
+	Keys       *VRFKeys
+	loaded     map[vrfkey.PublicKey]bool
+	chStop     chan struct{}
+	wg         sync.WaitGroup
+}
+
+func NewVRFKeyLoader(store *strpkg.Store, password, defaultKey string, interval time.Duration) *VRFKeyLoader {
+	return &VRFKeyLoader{
+		Store:      store,
+		Password:   password,
+		DefaultKey: defaultKey,
+		Interval:   interval,
+		Keys:       &VRFKeys{},
+		loaded:     make(map[vrfkey.PublicKey]bool),
+		chStop:     make(chan struct{}),
+	}
+}
+
+//Load unlocks the keys, creating one if there are none
+func (m *VRFKeyLoader) Load() error {
+	keyStore := m.Store.VRFKeyStore
+	keys, err := keyStore.Unlock(m.Password)
+	if err != nil {
+		return err
+	}
+	if len(keys) == 0 {
+		logger.Info("There are no VRF keys, creating one")
+		key, err := keyStore.CreateKey(m.Password)
+		if err != nil {
+			return err
+		}
+		keys = append(keys, key)
+	}
+	defaultKey, err := m.defaultKey(keys)
+	if err != nil {
+		return err
+	}
+	err = m.Keys.Set(defaultKey, keys...)
+	if err != nil {
+		return err
+	}
+	loaded := make(map[vrfkey.PublicKey]bool, len(keys))
+	for _, key := range keys {
+		loaded[key] = true
+		logger.Infof("Unlocked vrf key: %v, key hash: %v, default: %v", key, key.MustHash().Hex(), key == defaultKey)
+	}
+	//Archived keys are no longer selectable, their secret is removed from memory as well
+	for key := range m.loaded {
+		if !loaded[key] {
+			logger.Infof("Vrf key: %v is no longer in the db, forgetting it", key)
+			keyStore.Forget(key)
+		}
+	}
+	m.loaded = loaded
+	return nil
+}
+
+func (m *VRFKeyLoader) Start() {
+	m.wg.Add(1)
+	go m.run()
+}
+
+func (m *VRFKeyLoader) Stop() {
+	close(m.chStop)
+	m.wg.Wait()
+}
+
+func (m *VRFKeyLoader) run() {
+	defer m.wg.Done()
+	ticker := time.NewTicker(m.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-m.chStop:
+			return
+		case <-ticker.C:
+			err := m.reload()
+			if err != nil {
+				logger.Errorf("Failed to reload vrf keys, keeping the loaded ones: %v", err)
+			}
+		}
+	}
+}
+
+//reload only unlocks the keys if they changed, as decrypting them is expensive
+func (m *VRFKeyLoader) reload() error {
+	keys, err := m.Store.VRFKeyStore.ListKeys()
+	if err != nil {
+		return err
+	}
+	changed := len(keys) != len(m.loaded)
+	for _, key := range keys {
+		if !m.loaded[*key] {
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+	logger.Info("Vrf keys changed, reloading them")
+	return m.Load()
+}
+
+func (m *VRFKeyLoader) defaultKey(keys []vrfkey.PublicKey) (vrfkey.PublicKey, error) {
+	if m.DefaultKey != "" {
+		key, err := vrfkey.NewPublicKeyFromHex(m.DefaultKey)
+		if err != nil {
+			return vrfkey.PublicKey{}, fmt.Errorf("Invalid default vrf key: %v, error: %v", m.DefaultKey, err)
+		}
+		if !containsKey(keys, key) {
+			return vrfkey.PublicKey{}, fmt.Errorf("Default vrf key: %v is not one of the unlocked keys", m.DefaultKey)
+		}
+		return key, nil
+	}
+	rotation, err := m.Store.FindLastVRFKeyRotation()
+	if err != nil {
+		return vrfkey.PublicKey{}, fmt.Errorf("Failed to find last vrf key rotation: %v", err)
+	}
+	if rotation != nil {
+		key, err := vrfkey.NewPublicKeyFromHex(rotation.NewKey)
+		if err == nil && containsKey(keys, key) {
+			return key, nil
+		}
+		logger.Warnf("The new key: %v of the last key rotation is not unlocked, using the first unlocked key as default", rotation.NewKey)
+	}
+	return keys[0], nil
+}
+
+func containsKey(keys []vrfkey.PublicKey, key vrfkey.PublicKey) bool {
+	for _, k := range keys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,148 @@
+package svrf
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/sebastianmontero/vrf-oracle/core/logger"
+	"github.com/sebastianmontero/vrf-oracle/core/services/eos/contracts"
+	"github.com/sebastianmontero/vrf-oracle/core/services/vrf"
+	strpkg "github.com/sebastianmontero/vrf-oracle/core/store"
+	"github.com/sebastianmontero/vrf-oracle/core/store/models"
+	"github.com/sebastianmontero/vrf-oracle/core/store/models/vrfkey"
+	null "gopkg.in/guregu/null.v4"
+)
+
+//VRFKeyRotator rotates the oracle's vrf key without downtime:
+//Start creates a new key, registers its public key in the contract and records the rotation, from then on
+//the running oracle uses the new key as default while it keeps the old key unlocked for the requests that
+//selected it. Once the grace period has ended Complete archives the old key, which the oracle then forgets
+type VRFKeyRotator struct {
+	Store       *strpkg.Store
+	VRFContract *contracts.VRF
+}
+
+func NewVRFKeyRotator(store *strpkg.Store, vrfContract *contracts.VRF) *VRFKeyRotator {
+	return &VRFKeyRotator{
+		Store:       store,
+		VRFContract: vrfContract,
+	}
+}
+
+//Start starts a rotation from the old key, the new key is protected by password, which has to unlock the
+//old key as the oracle unlocks all the keys with the same password. The key pinned as default by
+//VRF_DEFAULT_KEY can not be rotated, as the oracle would keep using it as default instead of the new key
+func (m *VRFKeyRotator) Start(oldKey vrfkey.PublicKey, password string, grace time.Duration) (*models.VRFKeyRotation, error) {
+	last, err := m.Store.FindLastVRFKeyRotation()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to find last vrf key rotation: %v", err)
+	}
+	if last != nil && last.Status == models.VRFKeyRotationStatus_GRACE {
+		return nil, fmt.Errorf("Rotation: %v is in progress, it has to be completed before starting another one", last.ID)
+	}
+	if pinned := m.Store.Config.VRFDefaultKey(); pinned != "" {
+		pinnedKey, err := vrfkey.NewPublicKeyFromHex(pinned)
+		if err == nil && pinnedKey == oldKey {
+			return nil, fmt.Errorf("Old key: %v is pinned as default by VRF_DEFAULT_KEY, unset it so that the oracle uses the new key as default", oldKey)
+		}
+	}
+	encryptedOldKey, err := m.Store.VRFKeyStore.GetSpecificKey(oldKey)
+	if err != nil {
+		return nil, fmt.Errorf("Old key: %v not found: %v", oldKey, err)
+	}
+	_, err = encryptedOldKey.Decrypt(password)
+	if err != nil {
+		return nil, fmt.Errorf("The password does not unlock the old key: %v, the oracle would not be able to unlock the new key: %v", oldKey, err)
+	}
+	oldKeyHash, err := oldKey.Hash()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to hash old key: %v", err)
+	}
+	newKey, err := m.Store.VRFKeyStore.CreateKey(password)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create new key: %v", err)
+	}
+	logger.Infof("Created vrf key: %v, registering it in the contract", newKey)
+	txID, err := m.Register(newKey)
+	if err != nil {
+		//The key was never registered so nobody can have selected it
+		deleteErr := m.Store.VRFKeyStore.Delete(newKey)
+		if deleteErr != nil {
+			logger.Errorf("Failed to delete unregistered vrf key: %v, error: %v", newKey, deleteErr)
+		}
+		return nil, err
+	}
+	rotation := &models.VRFKeyRotation{
+		OldKey:       oldKey.String(),
+		OldKeyHash:   oldKeyHash.Hex(),
+		NewKey:       newKey.String(),
+		NewKeyHash:   newKey.MustHash().Hex(),
+		RegisterTxID: txID,
+		Status:       models.VRFKeyRotationStatus_GRACE,
+		GraceEndsAt:  time.Now().Add(grace),
+	}
+	err = m.Store.SaveVRFKeyRotation(rotation)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to save vrf key rotation, new key: %v was registered in tx: %v, error: %v", newKey, txID, err)
+	}
+	return rotation, nil
+}
+
+//Complete archives the old key of the rotation in progress, before the grace period ends only if forced
+func (m *VRFKeyRotator) Complete(force bool) (*models.VRFKeyRotation, error) {
+	rotation, err := m.Store.FindLastVRFKeyRotation()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to find last vrf key rotation: %v", err)
+	}
+	if rotation == nil || rotation.Status != models.VRFKeyRotationStatus_GRACE {
+		return nil, fmt.Errorf("There is no vrf key rotation in progress")
+	}
+	now := time.Now()
+	if !rotation.GraceEnded(now) && !force {
+		return nil, fmt.Errorf("The grace period of rotation: %v ends at: %v", rotation.ID, rotation.GraceEndsAt)
+	}
+	oldKey, err := vrfkey.NewPublicKeyFromHex(rotation.OldKey)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid old key: %v, error: %v", rotation.OldKey, err)
+	}
+	err = m.Store.VRFKeyStore.Archive(oldKey)
+	if err != nil && err != strpkg.AttemptToDeleteNonExistentKeyFromDB {
+		return nil, fmt.Errorf("Failed to archive old key: %v, error: %v", oldKey, err)
+	}
+	rotation.Status = models.VRFKeyRotationStatus_COMPLETED
+	rotation.CompletedAt = null.TimeFrom(now)
+	err = m.Store.SaveVRFKeyRotation(rotation)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to save vrf key rotation: %v", err)
+	}
+	return rotation, nil
+}
+
+//Register registers the public key in the contract so that requests can select it, returns the id of the
+//setpubkey transaction
+func (m *VRFKeyRotator) Register(key vrfkey.PublicKey) (string, error) {
+	encoded, keyHash, err := EOSPublicKey(key)
+	if err != nil {
+		return "", err
+	}
+	resp, err := m.VRFContract.SetPubKey(encoded, keyHash.Bytes())
+	if err != nil {
+		return "", fmt.Errorf("Failed to register key: %v in the contract: %v", key, err)
+	}
+	return resp.TransactionID, nil
+}
+
+//EOSPublicKey returns the public key encoded the way the contract expects it and its hash, which requests
+//use to select the key
+func EOSPublicKey(key vrfkey.PublicKey) (string, common.Hash, error) {
+	point, err := key.Point()
+	if err != nil {
+		return "", common.Hash{}, fmt.Errorf("Invalid public key: %v, error: %v", key, err)
+	}
+	keyHash, err := key.Hash()
+	if err != nil {
+		return "", common.Hash{}, fmt.Errorf("Failed to hash public key: %v, error: %v", key, err)
+	}
+	return vrf.EncodeEOSPublicKey(point), keyHash, nil
+}
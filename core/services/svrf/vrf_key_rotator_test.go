@@ -0,0 +1,178 @@
+package svrf_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sebastianmontero/vrf-oracle/core/internal/cltest"
+	"github.com/sebastianmontero/vrf-oracle/core/services/eos"
+	"github.com/sebastianmontero/vrf-oracle/core/services/eos/contracts"
+	"github.com/sebastianmontero/vrf-oracle/core/services/svrf"
+	strpkg "github.com/sebastianmontero/vrf-oracle/core/store"
+	"github.com/sebastianmontero/vrf-oracle/core/store/models"
+	"github.com/sebastianmontero/vrf-oracle/core/store/models/vrfkey"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+//stubKeyRegistry a node that accepts the setpubkey transactions unless reject is set
+type stubKeyRegistry struct {
+	mu     sync.Mutex
+	reject bool
+	pushed int
+}
+
+func (m *stubKeyRegistry) setReject(reject bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.reject = reject
+}
+
+func (m *stubKeyRegistry) pushes() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.pushed
+}
+
+func (m *stubKeyRegistry) serve(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		switch r.URL.Path {
+		case "/v1/chain/get_info":
+			fmt.Fprintf(w, `{"chain_id": "%064x", "head_block_num": 1000, "head_block_id": "%08x%056x", "last_irreversible_block_num": 990}`, 1, 1000, 0)
+		case "/v1/chain/get_required_keys":
+			fmt.Fprint(w, `{"required_keys": ["EOS6MRyAjQq8ud7hVNYcfnVPJqcVpscN5So8BhtHuGYqET5GDW5CV"]}`)
+		case "/v1/chain/push_transaction":
+			if m.reject {
+				w.WriteHeader(http.StatusInternalServerError)
+				fmt.Fprint(w, `{"code": 500, "message": "Internal Service Error", "error": {"code": 3090003, "name": "unsatisfied_authorization", "what": "Provided keys, permissions, and delays do not satisfy declared authorizations", "details": []}}`)
+				return
+			}
+			m.pushed++
+			fmt.Fprintf(w, `{"transaction_id": "%064x", "processed": {"block_num": 1001}}`, m.pushed)
+		default:
+			require.FailNow(t, "Unexpected call", r.URL.Path)
+		}
+	}))
+}
+
+func newKeyRotator(t *testing.T, store *strpkg.Store, server *httptest.Server) *svrf.VRFKeyRotator {
+	eosAPI, err := eos.New(server.URL, []string{"5KQwrPbwdL6PhXujxW37FSSQZ1JiwsST4cqQzDeyXtP79zkvFD3"})
+	require.NoError(t, err)
+	return svrf.NewVRFKeyRotator(store, contracts.NewVRF("vrf", eosAPI))
+}
+
+//countStoredKeys counts the keys in the db, archived ones included
+func countStoredKeys(t *testing.T, store *strpkg.Store) int {
+	var count int
+	require.NoError(t, store.DB.Unscoped().Model(&vrfkey.EncryptedVRFKey{}).Count(&count).Error)
+	return count
+}
+
+func TestVRFKeyRotator_Start(t *testing.T) {
+	t.Parallel()
+	store, cleanup := cltest.NewStore(t)
+	defer cleanup()
+	registry := &stubKeyRegistry{}
+	server := registry.serve(t)
+	defer server.Close()
+	rotator := newKeyRotator(t, store, server)
+	oldKey, err := store.VRFKeyStore.CreateKey(cltest.Password)
+	require.NoError(t, err)
+
+	_, err = rotator.Start(vrfkey.CreateKey().PublicKey, cltest.Password, time.Hour)
+	assert.Error(t, err, "the old key is not in the db")
+	_, err = rotator.Start(oldKey, "wrong password", time.Hour)
+	assert.Error(t, err, "the oracle could not unlock the new key")
+	assert.Equal(t, 0, registry.pushes())
+	assert.Equal(t, 1, countStoredKeys(t, store))
+
+	//The oracle would keep using the pinned key as default instead of the new key
+	store.Config.Set("VRF_DEFAULT_KEY", oldKey.String())
+	_, err = rotator.Start(oldKey, cltest.Password, time.Hour)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "VRF_DEFAULT_KEY")
+	assert.Equal(t, 0, registry.pushes())
+	assert.Equal(t, 1, countStoredKeys(t, store))
+	store.Config.Set("VRF_DEFAULT_KEY", "")
+
+	//The key that could not be registered is deleted, as nobody can have selected it
+	registry.setReject(true)
+	_, err = rotator.Start(oldKey, cltest.Password, time.Hour)
+	require.Error(t, err)
+	assert.Equal(t, 1, countStoredKeys(t, store))
+	last, err := store.FindLastVRFKeyRotation()
+	require.NoError(t, err)
+	assert.Nil(t, last)
+
+	registry.setReject(false)
+	rotation, err := rotator.Start(oldKey, cltest.Password, time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, 1, registry.pushes())
+	assert.Equal(t, oldKey.String(), rotation.OldKey)
+	assert.Equal(t, models.VRFKeyRotationStatus(models.VRFKeyRotationStatus_GRACE), rotation.Status)
+	assert.NotEmpty(t, rotation.RegisterTxID)
+	newKey, err := vrfkey.NewPublicKeyFromHex(rotation.NewKey)
+	require.NoError(t, err)
+	assert.Equal(t, newKey.MustHash().Hex(), rotation.NewKeyHash)
+	keys, err := store.VRFKeyStore.ListKeys()
+	require.NoError(t, err)
+	assert.Len(t, keys, 2)
+
+	//A second rotation is refused while the first one is in its grace period
+	_, err = rotator.Start(newKey, cltest.Password, time.Hour)
+	require.Error(t, err)
+	assert.Equal(t, 1, registry.pushes())
+	assert.Equal(t, 2, countStoredKeys(t, store))
+}
+
+func TestVRFKeyRotator_Complete(t *testing.T) {
+	t.Parallel()
+	store, cleanup := cltest.NewStore(t)
+	defer cleanup()
+	registry := &stubKeyRegistry{}
+	server := registry.serve(t)
+	defer server.Close()
+	rotator := newKeyRotator(t, store, server)
+	oldKey, err := store.VRFKeyStore.CreateKey(cltest.Password)
+	require.NoError(t, err)
+
+	_, err = rotator.Complete(true)
+	assert.Error(t, err, "there is no rotation in progress")
+
+	rotation, err := rotator.Start(oldKey, cltest.Password, time.Hour)
+	require.NoError(t, err)
+	_, err = rotator.Complete(false)
+	assert.Error(t, err, "the grace period has not ended")
+	keys, err := store.VRFKeyStore.ListKeys()
+	require.NoError(t, err)
+	assert.Len(t, keys, 2)
+
+	completed, err := rotator.Complete(true)
+	require.NoError(t, err)
+	assert.Equal(t, rotation.ID, completed.ID)
+	assert.Equal(t, models.VRFKeyRotationStatus(models.VRFKeyRotationStatus_COMPLETED), completed.Status)
+	assert.True(t, completed.CompletedAt.Valid)
+	//The old key is archived, not deleted
+	keys, err = store.VRFKeyStore.ListKeys()
+	require.NoError(t, err)
+	require.Len(t, keys, 1)
+	assert.Equal(t, rotation.NewKey, keys[0].String())
+	assert.Equal(t, 2, countStoredKeys(t, store))
+	_, err = rotator.Complete(true)
+	assert.Error(t, err, "the rotation was already completed")
+
+	//Once the grace period ends the rotation completes without forcing it
+	newKey, err := vrfkey.NewPublicKeyFromHex(rotation.NewKey)
+	require.NoError(t, err)
+	next, err := rotator.Start(newKey, cltest.Password, 0)
+	require.NoError(t, err)
+	completed, err = rotator.Complete(false)
+	require.NoError(t, err)
+	assert.Equal(t, next.ID, completed.ID)
+}
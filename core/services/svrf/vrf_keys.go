@@ -0,0 +1,83 @@
+package svrf
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/sebastianmontero/vrf-oracle/core/store/models/vrfkey"
+)
+
+//VRFKeys the unlocked vrf keys indexed by the hash of the public key, which is what requests use to
+//select the key that has to generate their proofs, requests without a key hash use the default key
+type VRFKeys struct {
+	mu         sync.RWMutex
+	keys       map[common.Hash]vrfkey.PublicKey
+	defaultKey vrfkey.PublicKey
+}
+
+//Set replaces the keys, the default key is added if it is not part of the keys
+func (m *VRFKeys) Set(defaultKey vrfkey.PublicKey, keys ...vrfkey.PublicKey) error {
+	byHash := make(map[common.Hash]vrfkey.PublicKey, len(keys)+1)
+	for _, key := range append(keys, defaultKey) {
+		hash, err := key.Hash()
+		if err != nil {
+			return fmt.Errorf("Failed to hash vrf key: %v, error: %v", key, err)
+		}
+		byHash[hash] = key
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.keys = byHash
+	m.defaultKey = defaultKey
+	return nil
+}
+
+//Get returns the key with the key hash, or the default key if the key hash is empty
+func (m *VRFKeys) Get(keyHash string) (vrfkey.PublicKey, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if keyHash == "" {
+		return m.defaultKey, nil
+	}
+	if !isHexHash(keyHash) {
+		return vrfkey.PublicKey{}, fmt.Errorf("Invalid key hash: %v", keyHash)
+	}
+	key, ok := m.keys[common.HexToHash(keyHash)]
+	if !ok {
+		return vrfkey.PublicKey{}, fmt.Errorf("Unknown key hash: %v, the key is not unlocked in this oracle", keyHash)
+	}
+	return key, nil
+}
+
+//Default returns the default key
+func (m *VRFKeys) Default() vrfkey.PublicKey {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.defaultKey
+}
+
+//List returns the hashes of the keys
+func (m *VRFKeys) List() []common.Hash {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	hashes := make([]common.Hash, 0, len(m.keys))
+	for hash := range m.keys {
+		hashes = append(hashes, hash)
+	}
+	return hashes
+}
+
+func isHexHash(s string) bool {
+	s = strings.TrimPrefix(s, "0x")
+	if len(s) != 2*common.HashLength {
+		return false
+	}
+	for _, c := range s {
+		if !strings.ContainsRune("0123456789abcdefABCDEF", c) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,93 @@
+package svrf_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sebastianmontero/vrf-oracle/core/internal/cltest"
+	"github.com/sebastianmontero/vrf-oracle/core/services/svrf"
+	"github.com/sebastianmontero/vrf-oracle/core/store/models/vrfkey"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVRFKeys_Get(t *testing.T) {
+	defaultKey := vrfkey.CreateKey().PublicKey
+	other := vrfkey.CreateKey().PublicKey
+	keys := &svrf.VRFKeys{}
+	require.NoError(t, keys.Set(defaultKey, other))
+	otherHash := other.MustHash().Hex()
+
+	tests := []struct {
+		name    string
+		keyHash string
+		key     vrfkey.PublicKey
+		err     string
+	}{
+		{"empty key hash selects the default key", "", defaultKey, ""},
+		{"key hash with 0x prefix", otherHash, other, ""},
+		{"key hash without 0x prefix", otherHash[2:], other, ""},
+		{"upper case key hash", strings.ToUpper(otherHash[2:]), other, ""},
+		{"default key by its hash", defaultKey.MustHash().Hex()[2:], defaultKey, ""},
+		{"key hash that is not hex", strings.Repeat("z", 64), vrfkey.PublicKey{}, "Invalid key hash"},
+		{"short key hash", otherHash[:20], vrfkey.PublicKey{}, "Invalid key hash"},
+		{"long key hash", otherHash + "00", vrfkey.PublicKey{}, "Invalid key hash"},
+		{"unknown key hash", vrfkey.CreateKey().PublicKey.MustHash().Hex(), vrfkey.PublicKey{}, "Unknown key hash"},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			key, err := keys.Get(test.keyHash)
+			if test.err != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), test.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.key, key)
+		})
+	}
+	assert.Equal(t, defaultKey, keys.Default())
+	assert.Len(t, keys.List(), 2)
+}
+
+func TestVRFKeyLoader_Reload(t *testing.T) {
+	t.Parallel()
+	store, cleanup := cltest.NewStore(t)
+	defer cleanup()
+	keyStore := store.VRFKeyStore
+
+	key1, err := keyStore.CreateKey(cltest.Password)
+	require.NoError(t, err)
+	loader := svrf.NewVRFKeyLoader(store, cltest.Password, "", time.Hour)
+	require.NoError(t, loader.Load())
+	_, err = loader.Keys.Get(key1.MustHash().Hex())
+	require.NoError(t, err)
+	assert.Equal(t, key1, loader.Keys.Default())
+
+	//Nothing changed
+	require.NoError(t, loader.ExportedReload())
+	assert.Len(t, loader.Keys.List(), 1)
+
+	key2, err := keyStore.CreateKey(cltest.Password)
+	require.NoError(t, err)
+	_, err = loader.Keys.Get(key2.MustHash().Hex())
+	require.Error(t, err)
+	require.NoError(t, loader.ExportedReload())
+	selected, err := loader.Keys.Get(key2.MustHash().Hex())
+	require.NoError(t, err)
+	assert.Equal(t, key2, selected)
+	assert.Len(t, loader.Keys.List(), 2)
+
+	require.NoError(t, keyStore.Archive(key1))
+	require.NoError(t, loader.ExportedReload())
+	_, err = loader.Keys.Get(key1.MustHash().Hex())
+	require.Error(t, err)
+	assert.Equal(t, key2, loader.Keys.Default())
+	assert.Len(t, loader.Keys.List(), 1)
+	unlocked, err := keyStore.ListKeys()
+	require.NoError(t, err)
+	require.Len(t, unlocked, 1)
+	assert.Equal(t, key2, *unlocked[0])
+}
@@ -1,48 +1,248 @@
 package svrf
 
 import (
+	"encoding/json"
 	"fmt"
 	"math/big"
 	"time"
 
+	eosc "github.com/eoscanada/eos-go"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/jpillora/backoff"
 	"github.com/sebastianmontero/vrf-oracle/core/logger"
+	"github.com/sebastianmontero/vrf-oracle/core/services/eos"
 	"github.com/sebastianmontero/vrf-oracle/core/services/eos/contracts"
 	"github.com/sebastianmontero/vrf-oracle/core/services/vrf"
 	strpkg "github.com/sebastianmontero/vrf-oracle/core/store"
 	"github.com/sebastianmontero/vrf-oracle/core/store/models"
 	"github.com/sebastianmontero/vrf-oracle/core/store/models/vrfkey"
+	null "gopkg.in/guregu/null.v4"
 )
 
 type VRFResolver struct {
 	Store       *strpkg.Store
 	VRFKeyStore *strpkg.VRFKeyStore
 	VRFContract *contracts.VRF
-	publicKey   *vrfkey.PublicKey
+	Scheduler   *VRFScheduler
+	RetryWorker *VRFRetryWorker
+	WorkerPool  *VRFWorkerPool
+	Batcher     *VRFBatcher
+	Monitor     *VRFResourceMonitor
+	Revealer    *VRFRevealer
+	Confirmer   *VRFConfirmer
+	Renter      *eos.ResourceRenter
+	Keys        *VRFKeys
+	Threshold   *VRFThreshold
 }
 
-func NewVRFResolver(store *strpkg.Store, vrfContract *contracts.VRF, publicKey *vrfkey.PublicKey) *VRFResolver {
-	return &VRFResolver{
+//NewVRFResolver creates a resolver, keys are the unlocked keys requests can select by key hash,
+//renter is optional, if set resources are rented when fulfilment fails because of resource exhaustion
+//and when the resource monitor finds the account running out of them
+func NewVRFResolver(store *strpkg.Store, vrfContract *contracts.VRF, keys *VRFKeys, renter *eos.ResourceRenter) *VRFResolver {
+	resolver := &VRFResolver{
 		Store:       store,
 		VRFKeyStore: store.VRFKeyStore,
 		VRFContract: vrfContract,
-		publicKey:   publicKey,
+		Renter:      renter,
+		Keys:        keys,
 	}
+	resolver.Scheduler = NewVRFScheduler(store, vrfContract.EOS, resolver)
+	resolver.RetryWorker = NewVRFRetryWorker(store, resolver)
+	resolver.Confirmer = NewVRFConfirmer(store)
+	resolver.WorkerPool = NewVRFWorkerPool(
+		int(store.Config.VRFWorkers()),
+		store.Config.VRFOrderPerCaller(),
+		func(job *models.VRFRequestJob) {
+			resolver.Process(job)
+		})
+	resolver.Monitor = NewVRFResourceMonitor(
+		vrfContract.EOS,
+		vrfContract.Actor,
+		store.Config.VRFResourceMonitorInterval(),
+		store.Config.VRFResourceWarnPercent(),
+		store.Config.VRFResourcePausePercent(),
+		renter)
+	if usesCommitReveal(store) {
+		resolver.Revealer = NewVRFRevealer(store, vrfContract.EOS, resolver)
+	}
+	if store.Config.VRFBatchSize() > 1 {
+		resolver.Batcher = NewVRFBatcher(
+			vrfContract.SetRands,
+			renter,
+			int(store.Config.VRFBatchSize()),
+			int(store.Config.VRFBatchMaxBytes()),
+			store.Config.VRFBatchWindow())
+	}
+	return resolver
 }
 
+//Start dispatches to the worker pool the requests that were in flight when the oracle stopped, then
+//starts the scheduler in charge of recurrent and scheduled requests, the worker that retries failed jobs
+//and the confirmer of the fulfilment transactions. It does not wait for the recovered jobs, as they may
+//have to wait for the fulfilment account to recover its resources
+func (m *VRFResolver) Start() error {
+	m.Monitor.Start()
+	if m.Batcher != nil {
+		m.Batcher.Start()
+	}
+	if m.Threshold != nil {
+		err := m.Threshold.Start()
+		if err != nil {
+			return err
+		}
+	}
+	m.WorkerPool.Start()
+	err := m.recover()
+	if err != nil {
+		return err
+	}
+	err = m.Scheduler.Start()
+	if err != nil {
+		return err
+	}
+	m.RetryWorker.Start()
+	m.Confirmer.Start()
+	if m.Revealer != nil {
+		m.Revealer.Start()
+	}
+	return nil
+}
+
+//Stop stops the resource monitor and the threshold mode so that no job stays waiting for resources or
+//partial proofs, the retry worker, the confirmer, the revealer, the worker pool, the scheduler and once
+//no job is being processed the batcher
+func (m *VRFResolver) Stop() {
+	m.Monitor.Stop()
+	if m.Threshold != nil {
+		m.Threshold.Stop()
+	}
+	m.RetryWorker.Stop()
+	m.Confirmer.Stop()
+	if m.Revealer != nil {
+		m.Revealer.Stop()
+	}
+	m.WorkerPool.Stop()
+	m.Scheduler.Stop()
+	if m.Batcher != nil {
+		m.Batcher.Stop()
+	}
+}
+
+//recover dispatches the jobs that were waiting to be processed and the immediate requests that were stored
+//but never got a job, recurrent and scheduled requests without jobs are recovered by the scheduler. The jobs
+//that were running count the interruption as a retry and are left to the retry worker, so that a job that
+//stops the oracle is retried with backoff instead of right away on every restart, and eventually fails
+func (m *VRFResolver) recover() error {
+	err := m.Store.FailInterruptedVRFRequestRuns(
+		"Interrupted, the oracle stopped while the run was in progress",
+		m.maxRetries(),
+		time.Now().Add(m.retryBackoff(1)))
+	if err != nil {
+		return fmt.Errorf("Failed to fail interrupted vrf request runs: %v", err)
+	}
+	jobs, err := m.Store.FindQueuedVRFRequestJobs()
+	if err != nil {
+		return fmt.Errorf("Failed to find queued vrf request jobs: %v", err)
+	}
+	reqs, err := m.Store.FindVRFRequestsWithoutJobs(models.VRFRequestType_IMMEDIATE)
+	if err != nil {
+		return fmt.Errorf("Failed to find vrf requests without jobs: %v", err)
+	}
+	logger.Infof("Recovering %v queued vrf request jobs and %v vrf requests without jobs", len(jobs), len(reqs))
+	for _, job := range jobs {
+		m.WorkerPool.Dispatch(job)
+	}
+	for _, req := range reqs {
+		m.Dispatch(models.NewVRFRequestJob(req))
+	}
+	return nil
+}
+
+//Consume dispatches immediate requests to the worker pool, recurrent and scheduled requests
+//are handed over to the scheduler
 func (m *VRFResolver) Consume(c chan *models.VRFRequest) {
 	for req := range c {
-		job := models.NewVRFRequestJob(req)
-		m.saveJob(job)
-		m.Process(job)
+		switch req.Type {
+		case models.VRFRequestType_RECURRENT, models.VRFRequestType_SCHEDULED:
+			m.Scheduler.AddRequest(req)
+		default:
+			m.Dispatch(models.NewVRFRequestJob(req))
+		}
 	}
 }
 
+//Dispatch stores a new job and dispatches it to the worker pool
+func (m *VRFResolver) Dispatch(job *models.VRFRequestJob) {
+	m.saveJob(job)
+	m.WorkerPool.Dispatch(job)
+}
+
+//Process processes the job, returns a nil run if the job was skipped because it is no longer waiting
+//to be processed or revealed, or an operator cancelled its request while it was queued
 func (m *VRFResolver) Process(job *models.VRFRequestJob) (*models.VRFRequestRun, error) {
 	req := job.VRFRequest
+	started, err := m.Store.StartVRFRequestJob(job)
+	if err != nil {
+		err := fmt.Errorf("Failed to start job: %v, error: %v", job.ID, err)
+		logger.Error(err)
+		return nil, err
+	}
+	if !started {
+		logger.Infof("Skipping job: %v, it is no longer waiting or request: %v was cancelled", job.ID, req.ID)
+		return nil, nil
+	}
 	run := models.NewVRFRequestRun(job)
 	m.saveRun(run, false)
+	//After a crash, a retry or when several oracles serve the contract the request may have been resolved already
+	reason, err := m.resolved(job)
+	if err != nil {
+		err := fmt.Errorf("Failed to check whether the request was already resolved: %v", err)
+		m.failRun(run, err, m.maxRetries())
+		return run, err
+	}
+	if reason != "" {
+		logger.Infof("Skipping request: %v, %v", req.ID, reason)
+		run.UpdateStatus(
+			models.VRFRequestRunStatus_COMPLETED,
+			"Skipped, "+reason,
+			m.maxRetries())
+		m.saveRun(run, isCompleted(req))
+		return run, nil
+	}
+	if m.Threshold != nil {
+		return m.processThreshold(job, run)
+	}
+	if m.Store.Config.VRFStrategy(req.Caller) == models.VRFStrategy_COMMIT_REVEAL {
+		return m.commitReveal(job, run)
+	}
+	//The key is not unlocked in this oracle, retrying would not fix it
+	key, err := m.key(req)
+	if err != nil {
+		m.failRun(run, err, 0)
+		return run, err
+	}
+	if m.quorum() > 0 {
+		reason, err := m.skipContribution(req, key)
+		if err != nil {
+			err := fmt.Errorf("Failed to check the contributions to the request: %v", err)
+			m.failRun(run, err, m.maxRetries())
+			return run, err
+		}
+		if reason != "" {
+			logger.Infof("Skipping contribution to request: %v, %v", req.ID, reason)
+			run.UpdateStatus(
+				models.VRFRequestRunStatus_COMPLETED,
+				"Skipped, "+reason,
+				m.maxRetries())
+			m.saveRun(run, isCompleted(req))
+			return run, nil
+		}
+	}
 	proofs := make([]*vrf.EOSProofResponse, 0, len(req.Seeds))
+	var values []*contracts.RandValues
+	if req.Expanded() {
+		values = make([]*contracts.RandValues, 0, len(req.Seeds))
+	}
 	for _, seed := range req.Seeds {
 		bigSeed, ok := big.NewInt(0).SetString(seed, 10)
 		if !ok {
@@ -57,38 +257,238 @@ func (m *VRFResolver) Process(job *models.VRFRequestJob) (*models.VRFRequestRun,
 			m.failRun(run, err, 0)
 			return run, err
 		}
-		proof, err := m.VRFKeyStore.GenerateEOSProof(*m.publicKey, vrf.PreSeedData{
+		proof, err := m.VRFKeyStore.GenerateEOSProof(key, vrf.PreSeedData{
 			PreSeed:   preSeed,
-			BlockHash: common.HexToHash(req.BlockHash),
-			BlockNum:  req.BlockNum,
+			BlockHash: common.HexToHash(job.BlockHash),
+			BlockNum:  job.BlockNum,
 		})
 		if err != nil {
 			err := fmt.Errorf("GenerateEOSProof Failed: %v", err)
 			m.failRun(run, err, m.maxRetries())
 			return run, err
 		}
+		//An invalid proof is an encoding bug, retrying would not fix it
+		err = m.verifyProof(proof, key)
+		if err != nil {
+			m.failRun(run, err, 0)
+			return run, err
+		}
+		err = proof.Seed.SetType(m.Store.Config.VRFSeedType())
+		if err != nil {
+			m.failRun(run, err, 0)
+			return run, err
+		}
+		if req.Expanded() {
+			derived, err := proof.Values(req.ValueCount, req.ValueMin, req.ValueMax, req.UniqueValues)
+			if err != nil {
+				err := fmt.Errorf("Failed to derive values: %v", err)
+				m.failRun(run, err, 0)
+				return run, err
+			}
+			values = append(values, &contracts.RandValues{Values: derived})
+		}
 		proofs = append(proofs, proof)
 	}
+	//The proofs and the key are recorded even if the push fails
+	run.Proofs, err = proofsJSON(proofs)
+	if err != nil {
+		m.failRun(run, err, 0)
+		return run, err
+	}
+	run.KeyHash = key.MustHash().Hex()
 	// logger.Infof("Calling SetRand for request: %v and proofs: %v", req, proofs)
-	time.Sleep(time.Second)
-	_, err := m.VRFContract.SetRand(req.AssocID, req.Caller, proofs)
+	resp, err := m.setRand(req, proofs, values, key)
 	if err != nil {
 		err := fmt.Errorf("Calling SetRand on contract failed: %v", err)
 		m.failRun(run, err, m.maxRetries())
 		return run, err
 	}
+	run.SetTx(resp.TransactionID, uint64(resp.BlockNum))
 	run.UpdateStatus(
 		models.VRFRequestRunStatus_COMPLETED,
 		"Completed successfully",
 		m.maxRetries())
-	m.saveRun(run, true)
+	m.saveRun(run, isCompleted(req))
 	return run, nil
 }
 
+//verifyProof decodes the proof and verifies it against the public key of the key that generated it before it is pushed
+func (m *VRFResolver) verifyProof(proof *vrf.EOSProofResponse, key vrfkey.PublicKey) error {
+	publicKey, err := key.Point()
+	if err != nil {
+		return fmt.Errorf("Failed to decode vrf public key: %v", err)
+	}
+	err = proof.Verify(publicKey)
+	if err != nil {
+		return fmt.Errorf("Generated proof failed verification: %v", err)
+	}
+	return nil
+}
+
+//setRand pushes the proofs, in quorum mode the action carries the hash of the key so that the contract
+//can count the contributions
+func (m *VRFResolver) setRand(req *models.VRFRequest, proofs []*vrf.EOSProofResponse, values []*contracts.RandValues, key vrfkey.PublicKey) (*eosc.PushTransactionFullResp, error) {
+	if m.quorum() > 0 {
+		return m.push(m.VRFContract.ContributeAction(req.AssocID, req.Caller, proofs, values, key.MustHash().Bytes()))
+	}
+	return m.push(m.VRFContract.SetRandAction(req.AssocID, req.Caller, proofs, values))
+}
+
+//push pushes the action directly or through the batcher when batching is enabled, returns the transaction
+//the action was pushed in. It waits while fulfilment is paused because the account is running out of resources
+func (m *VRFResolver) push(action *eosc.Action) (*eosc.PushTransactionFullResp, error) {
+	err := m.Monitor.Wait()
+	if err != nil {
+		return nil, err
+	}
+	if m.Batcher == nil {
+		var resp *eosc.PushTransactionFullResp
+		err = m.Renter.Retry(func() error {
+			var err error
+			resp, err = m.VRFContract.EOS.Trx(action)
+			return err
+		})
+		return resp, err
+	}
+	return m.Batcher.Fulfil(action)
+}
+
+//proofsJSON the proofs as they are recorded in the run
+func proofsJSON(proofs interface{}) (*models.JSON, error) {
+	data, err := json.Marshal(proofs)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to encode proofs: %v", err)
+	}
+	result, err := models.ParseJSON(data)
+	return &result, err
+}
+
+//key returns the key the request selected, in quorum mode every oracle contributes with its default key
+func (m *VRFResolver) key(req *models.VRFRequest) (vrfkey.PublicKey, error) {
+	if m.quorum() > 0 {
+		return m.Keys.Default(), nil
+	}
+	return m.Keys.Get(req.KeyHash)
+}
+
+//resolved returns why the job no longer has to be fulfilled, empty if it has to. The contract erases the row of
+//a request from the jobs table once it resolves it, the delta handler marks the stored request when it sees the
+//removal, so the stored request is checked first. Recurrent requests stay in the jobs table between rounds, so
+//for them the jobs of the request are checked for one at the same block that was already fulfilled. On the first
+//attempt of the job the mark is trusted, later attempts fall back to reading the jobs table, as the stream may
+//not have reached the removal yet, e.g. when another oracle resolved the request after the job started
+func (m *VRFResolver) resolved(job *models.VRFRequestJob) (string, error) {
+	req := job.VRFRequest
+	stored, err := m.Store.FindVRFRequest(req.ID)
+	if err != nil {
+		return "", fmt.Errorf("Failed to find the request: %v", err)
+	}
+	if stored.Removed() {
+		return fmt.Sprintf("it was removed from the jobs table at block: %v", stored.RemovedBlockNum), nil
+	}
+	if req.Type == models.VRFRequestType_RECURRENT {
+		fulfilled, err := m.Store.VRFRequestFulfilledAtBlock(req.ID, job.BlockNum)
+		if err != nil {
+			return "", fmt.Errorf("Failed to check the jobs of the request: %v", err)
+		}
+		if fulfilled {
+			return fmt.Sprintf("the round at block: %v was already fulfilled", job.BlockNum), nil
+		}
+	}
+	//The run of this attempt has already been saved
+	attempts, err := m.Store.CountVRFRequestRuns(job.ID)
+	if err != nil {
+		return "", fmt.Errorf("Failed to count the runs of the job: %v", err)
+	}
+	if attempts <= 1 {
+		return "", nil
+	}
+	return m.resolvedInTable(job)
+}
+
+//resolvedInTable the fallback of resolved, reads the row of the request in the jobs table. A missing row means the
+//request was resolved or removed, and a row whose seeds or frequency differ means the caller replaced the request
+//with a new one under the same assoc id. The node can lag behind the stream, so a missing row only counts once the
+//head of the node has reached the block of the job, until then an error is returned so that the job is retried.
+//It costs a get_table_rows call, plus a get_info call when the row is missing
+func (m *VRFResolver) resolvedInTable(job *models.VRFRequestJob) (string, error) {
+	req := job.VRFRequest
+	row, err := m.VRFContract.FindJob(m.Store.Config.VRFJobTable(), req.Caller, req.AssocID)
+	if err != nil {
+		return "", err
+	}
+	if row == nil {
+		head, err := m.VRFContract.EOS.HeadBlock()
+		if err != nil {
+			return "", fmt.Errorf("Failed to get head block: %v", err)
+		}
+		if head < job.BlockNum {
+			return "", fmt.Errorf("Request not found, the node is at block: %v, behind the block of the job: %v", head, job.BlockNum)
+		}
+		return "it is no longer in the jobs table", nil
+	}
+	seeds, err := row.DecimalSeeds(m.Store.Config.VRFSeedType())
+	if err != nil {
+		return "", fmt.Errorf("Invalid seeds in the jobs table: %v", err)
+	}
+	if row.Frequency != req.Frequency || !equalSeeds(seeds, req.Seeds) {
+		return "it was replaced by a new request in the jobs table", nil
+	}
+	return "", nil
+}
+
+func equalSeeds(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+//skipContribution returns why the oracle should not contribute to the request, empty if it should:
+//the oracle already contributed to the current round or the quorum has already been reached
+func (m *VRFResolver) skipContribution(req *models.VRFRequest, key vrfkey.PublicKey) (string, error) {
+	contribution, err := m.VRFContract.FindContribution(req.Caller, req.AssocID)
+	if err != nil || contribution == nil {
+		return "", err
+	}
+	if contribution.Contains(key.MustHash().Bytes()) {
+		return "already contributed", nil
+	}
+	if len(contribution.KeyHashes) >= int(m.quorum()) {
+		return fmt.Sprintf("quorum of %v reached", m.quorum()), nil
+	}
+	return "", nil
+}
+
+func (m *VRFResolver) quorum() uint8 {
+	return m.Store.Config.VRFQuorum()
+}
+
 func (m *VRFResolver) maxRetries() uint8 {
 	return m.Store.Config.VRFMaxRetries()
 }
 
+//retryBackoff exponential backoff based on the number of retries of the job
+func (m *VRFResolver) retryBackoff(retries uint8) time.Duration {
+	b := &backoff.Backoff{
+		Min:    m.Store.Config.VRFRetryMinBackoff(),
+		Max:    m.Store.Config.VRFRetryMaxBackoff(),
+		Factor: 2,
+	}
+	return b.ForAttempt(float64(retries - 1))
+}
+
+//isCompleted the request is only saved when it is completed, that way recurrent requests which
+//are held in memory by the scheduler don't get out of sync because of optimistic locking
+func isCompleted(req *models.VRFRequest) bool {
+	return req.Status == models.VRFRequestStatus_COMPLETED
+}
+
 func (m *VRFResolver) saveRun(run *models.VRFRequestRun, saveVRFRequest bool) {
 	err := m.Store.SaveVRFRequestRun(run, saveVRFRequest)
 	if err != nil {
@@ -110,5 +510,9 @@ func (m *VRFResolver) failRun(run *models.VRFRequestRun, err error, maxRetries u
 		models.VRFRequestRunStatus_FAILED,
 		err.Error(),
 		maxRetries)
-	m.saveRun(run, true)
+	job := run.VRFRequestJob
+	if job.Status == models.VRFRequestJobStatus_ACTIVE {
+		job.RetryAt = null.TimeFrom(time.Now().Add(m.retryBackoff(job.Retries)))
+	}
+	m.saveRun(run, isCompleted(job.VRFRequest))
 }
@@ -0,0 +1,183 @@
+package svrf
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	eosc "github.com/eoscanada/eos-go"
+	"github.com/sebastianmontero/vrf-oracle/core/services/eos"
+	"github.com/sebastianmontero/vrf-oracle/core/services/eos/contracts"
+	"github.com/sebastianmontero/vrf-oracle/core/services/vrf"
+	strpkg "github.com/sebastianmontero/vrf-oracle/core/store"
+	"github.com/sebastianmontero/vrf-oracle/core/store/models"
+	"github.com/sebastianmontero/vrf-oracle/core/store/models/vrfkey"
+	"github.com/sebastianmontero/vrf-oracle/core/store/orm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+//stubContract serves the rows of the contribs table of the contract keyed by assoc id, the rows of the
+//jobs table keyed by assoc id, each one holding the fields of the row other than the assoc id, and the head
+//block of the node
+type stubContract struct {
+	contribs map[uint64][]string
+	jobs     map[uint64]string
+	head     uint64
+}
+
+func (m *stubContract) serve(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/chain/get_info" {
+			fmt.Fprintf(w, `{"head_block_num": %v}`, m.head)
+			return
+		}
+		require.Equal(t, "/v1/chain/get_table_rows", r.URL.Path)
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		var req eosc.GetTableRowsRequest
+		require.NoError(t, json.Unmarshal(body, &req))
+		var rows []string
+		switch req.Table {
+		case "contribs":
+			for assocID, keyHashes := range m.contribs {
+				if fmt.Sprint(assocID) == req.LowerBound {
+					rows = append(rows, fmt.Sprintf(`{"assoc_id":%v,"key_hashes":["%v"]}`, assocID, strings.Join(keyHashes, `","`)))
+				}
+			}
+		case "jobs":
+			for assocID, fields := range m.jobs {
+				if fmt.Sprint(assocID) == req.LowerBound {
+					rows = append(rows, fmt.Sprintf(`{"assoc_id":%v,%v}`, assocID, fields))
+				}
+			}
+		default:
+			require.FailNow(t, "Unexpected table", req.Table)
+		}
+		fmt.Fprintf(w, `{"rows":[%v],"more":false}`, strings.Join(rows, ","))
+	}))
+}
+
+func newQuorumResolver(t *testing.T, server *httptest.Server, quorum uint8, defaultKey vrfkey.PublicKey) *VRFResolver {
+	config := orm.NewConfig()
+	config.Set("VRF_QUORUM", quorum)
+	eosAPI, err := eos.New(server.URL, nil)
+	require.NoError(t, err)
+	keys := &VRFKeys{}
+	require.NoError(t, keys.Set(defaultKey))
+	return &VRFResolver{
+		Store:       &strpkg.Store{Config: config},
+		VRFContract: contracts.NewVRF("vrf", eosAPI),
+		Keys:        keys,
+		Monitor:     NewVRFResourceMonitor(eosAPI, "vrf", time.Hour, 0, 0, nil),
+	}
+}
+
+func keyHashHex(key vrfkey.PublicKey) string {
+	return key.MustHash().Hex()[2:]
+}
+
+func TestVRFResolver_SkipContribution(t *testing.T) {
+	key := vrfkey.CreateKey().PublicKey
+	other1 := vrfkey.CreateKey().PublicKey
+	other2 := vrfkey.CreateKey().PublicKey
+	contract := &stubContract{contribs: map[uint64][]string{
+		1: {keyHashHex(other1), keyHashHex(key)},
+		2: {keyHashHex(other1), keyHashHex(other2)},
+		3: {keyHashHex(other1)},
+	}}
+	server := contract.serve(t)
+	defer server.Close()
+	resolver := newQuorumResolver(t, server, 2, key)
+
+	tests := []struct {
+		assocID uint64
+		reason  string
+	}{
+		{1, "already contributed"},
+		{2, "quorum of 2 reached"},
+		{3, ""},
+		//No contributions yet
+		{4, ""},
+	}
+	for _, test := range tests {
+		reason, err := resolver.skipContribution(&models.VRFRequest{AssocID: test.assocID, Caller: "user1"}, key)
+		require.NoError(t, err)
+		assert.Equal(t, test.reason, reason, "assoc id: %v", test.assocID)
+	}
+}
+
+func TestVRFResolver_ContributeWithDefaultKey(t *testing.T) {
+	server := (&stubContract{}).serve(t)
+	defer server.Close()
+	defaultKey := vrfkey.CreateKey().PublicKey
+	resolver := newQuorumResolver(t, server, 2, defaultKey)
+	var mu sync.Mutex
+	var pushed []*eosc.Action
+	resolver.Batcher = NewVRFBatcher(func(actions ...*eosc.Action) (*eosc.PushTransactionFullResp, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		pushed = append(pushed, actions...)
+		return &eosc.PushTransactionFullResp{TransactionID: "tx1"}, nil
+	}, nil, 1, 65536, time.Millisecond)
+	resolver.Batcher.Start()
+	defer resolver.Batcher.Stop()
+
+	//In quorum mode every oracle contributes with its default key, even if the request selected another one
+	req := &models.VRFRequest{AssocID: 5, Caller: "user1", KeyHash: keyHashHex(vrfkey.CreateKey().PublicKey)}
+	key, err := resolver.key(req)
+	require.NoError(t, err)
+	assert.Equal(t, defaultKey, key)
+	resp, err := resolver.setRand(req, []*vrf.EOSProofResponse{}, nil, key)
+	require.NoError(t, err)
+	assert.Equal(t, "tx1", resp.TransactionID)
+
+	require.Len(t, pushed, 1)
+	assert.Equal(t, eosc.ActionName("setrand"), pushed[0].Name)
+	data, err := json.Marshal(pushed[0].ActionData.Data)
+	require.NoError(t, err)
+	var contribution struct {
+		AssocID uint64 `json:"assoc_id"`
+		Caller  string `json:"caller"`
+		KeyHash string `json:"key_hash"`
+	}
+	require.NoError(t, json.Unmarshal(data, &contribution))
+	assert.Equal(t, uint64(5), contribution.AssocID)
+	assert.Equal(t, "user1", contribution.Caller)
+	assert.Equal(t, keyHashHex(defaultKey), contribution.KeyHash)
+}
+
+func TestVRFResolver_ResolvedInTable(t *testing.T) {
+	contract := &stubContract{jobs: map[uint64]string{1: `"seeds":[2,3],"frequency":""`}, head: 1000}
+	server := contract.serve(t)
+	defer server.Close()
+	resolver := newQuorumResolver(t, server, 0, vrfkey.CreateKey().PublicKey)
+	newJob := func(assocID, blockNum uint64, seeds ...string) *models.VRFRequestJob {
+		req := &models.VRFRequest{AssocID: assocID, Caller: "user1", Seeds: seeds, Type: models.VRFRequestType_IMMEDIATE}
+		return models.NewVRFRequestJobAtBlock(req, blockNum, "")
+	}
+
+	reason, err := resolver.resolvedInTable(newJob(1, 900, "2", "3"))
+	require.NoError(t, err)
+	assert.Equal(t, "", reason)
+
+	//The caller replaced the request with a new one under the same assoc id
+	reason, err = resolver.resolvedInTable(newJob(1, 900, "2"))
+	require.NoError(t, err)
+	assert.Equal(t, "it was replaced by a new request in the jobs table", reason)
+
+	reason, err = resolver.resolvedInTable(newJob(2, 1000, "2"))
+	require.NoError(t, err)
+	assert.Equal(t, "it is no longer in the jobs table", reason)
+
+	//The node has not reached the block of the job yet, the request may just not be visible
+	reason, err = resolver.resolvedInTable(newJob(2, 1001, "2"))
+	assert.EqualError(t, err, "Request not found, the node is at block: 1000, behind the block of the job: 1001")
+	assert.Equal(t, "", reason)
+}
@@ -0,0 +1,236 @@
+package svrf_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/lib/pq"
+	"github.com/sebastianmontero/vrf-oracle/core/internal/cltest"
+	"github.com/sebastianmontero/vrf-oracle/core/services/eos"
+	"github.com/sebastianmontero/vrf-oracle/core/services/eos/contracts"
+	"github.com/sebastianmontero/vrf-oracle/core/services/svrf"
+	"github.com/sebastianmontero/vrf-oracle/core/store/models"
+	"github.com/sebastianmontero/vrf-oracle/core/store/models/vrfkey"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVRFResolver_CancelQueuedJob(t *testing.T) {
+	t.Parallel()
+	store, cleanup := cltest.NewStore(t)
+	defer cleanup()
+
+	newJob := func(assocID uint64) *models.VRFRequestJob {
+		req := &models.VRFRequest{
+			AssocID:   assocID,
+			BlockNum:  395235,
+			BlockHash: "000607e21b8d9da0ade735bcd19a9f8812335b510482d4b9f8c53753e0ebe9c8",
+			Seeds:     pq.StringArray([]string{"2"}),
+			Count:     1,
+			Caller:    "user1",
+			Type:      models.VRFRequestType_IMMEDIATE,
+			Status:    models.VRFRequestStatus_ACTIVE,
+		}
+		require.NoError(t, store.CreateVRFRequest(req, nil))
+		job := models.NewVRFRequestJob(req)
+		require.NoError(t, store.SaveVRFRequestJob(job))
+		return job
+	}
+	blocker := newJob(1)
+	queued := newJob(2)
+
+	//The skipped job never reaches the contract, so the resolver needs nothing but the store
+	resolver := &svrf.VRFResolver{Store: store}
+	process := newBlockingProcess()
+	runs := make(chan *models.VRFRequestRun, 1)
+	pool := svrf.NewVRFWorkerPool(1, false, func(job *models.VRFRequestJob) {
+		if job.ID == blocker.ID {
+			process.process(job)
+			return
+		}
+		run, err := resolver.Process(job)
+		assert.NoError(t, err)
+		runs <- run
+	})
+	pool.Start()
+	defer pool.Stop()
+
+	require.True(t, pool.Dispatch(blocker))
+	process.waitStarted(t)
+	require.True(t, pool.Dispatch(queued))
+	_, err := store.CancelVRFRequest(queued.VRFRequestID, "Cancelled by an operator")
+	require.NoError(t, err)
+	close(process.release)
+
+	assert.Nil(t, <-runs, "the job of a cancelled request is skipped")
+	req, err := store.FindVRFRequest(queued.VRFRequestID)
+	require.NoError(t, err)
+	assert.Equal(t, models.VRFRequestStatus(models.VRFRequestStatus_CANCELLED), req.Status)
+	jobs, runsByJob, err := store.FindVRFRequestJobsWithRuns(queued.VRFRequestID)
+	require.NoError(t, err)
+	require.Len(t, jobs, 1)
+	assert.Equal(t, models.VRFRequestJobStatus(models.VRFRequestJobStatus_FAILED), jobs[0].Status)
+	require.Len(t, runsByJob[queued.ID], 1)
+	assert.Equal(t, "Cancelled by an operator", runsByJob[queued.ID][0].StatusMsg)
+}
+
+//stubJobsTable a node at block 1000 whose jobs table holds row, empty if it has no rows
+type stubJobsTable struct {
+	mu  sync.Mutex
+	row string
+}
+
+func (m *stubJobsTable) set(row string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.row = row
+}
+
+func (m *stubJobsTable) serve(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		switch r.URL.Path {
+		case "/v1/chain/get_info":
+			fmt.Fprint(w, `{"head_block_num": 1000}`)
+		case "/v1/chain/get_table_rows":
+			fmt.Fprintf(w, `{"rows":[%v],"more":false}`, m.row)
+		default:
+			require.FailNow(t, "Unexpected call", r.URL.Path)
+		}
+	}))
+}
+
+func TestVRFResolver_ResolvedRecurrent(t *testing.T) {
+	t.Parallel()
+	store, cleanup := cltest.NewStore(t)
+	defer cleanup()
+	table := &stubJobsTable{row: `{"assoc_id":1,"seeds":[2],"frequency":"@every 1m"}`}
+	server := table.serve(t)
+	defer server.Close()
+	eosAPI, err := eos.New(server.URL, nil)
+	require.NoError(t, err)
+	resolver := &svrf.VRFResolver{Store: store, VRFContract: contracts.NewVRF("vrf", eosAPI)}
+
+	req := &models.VRFRequest{
+		AssocID:   1,
+		BlockNum:  395235,
+		BlockHash: "000607e21b8d9da0ade735bcd19a9f8812335b510482d4b9f8c53753e0ebe9c8",
+		Seeds:     pq.StringArray([]string{"2"}),
+		Frequency: "@every 1m",
+		Count:     1,
+		Caller:    "user1",
+		Type:      models.VRFRequestType_RECURRENT,
+		Status:    models.VRFRequestStatus_ACTIVE,
+	}
+	require.NoError(t, store.CreateVRFRequest(req, nil))
+	newJob := func(blockNum uint64) *models.VRFRequestJob {
+		job := models.NewVRFRequestJobAtBlock(req, blockNum, "")
+		require.NoError(t, store.SaveVRFRequestJob(job))
+		return job
+	}
+
+	//The row stays in the jobs table after each round
+	job1 := newJob(900)
+	reason, err := resolver.ExportedResolved(job1)
+	require.NoError(t, err)
+	assert.Equal(t, "", reason)
+
+	run := models.NewVRFRequestRun(job1)
+	run.SetTx("6c5d8c4fc1a9e4de0f23a7a2e0fd3fe6b0aa9e5e0c4a1d1a6bd8c59d0d4e2d7a", 901)
+	run.UpdateStatus(models.VRFRequestRunStatus_COMPLETED, "Completed successfully", 3)
+	require.NoError(t, store.SaveVRFRequestRun(run, false))
+	reason, err = resolver.ExportedResolved(newJob(900))
+	require.NoError(t, err)
+	assert.Equal(t, "the round at block: 900 was already fulfilled", reason)
+
+	//The next round has not been fulfilled yet
+	job2 := newJob(960)
+	reason, err = resolver.ExportedResolved(job2)
+	require.NoError(t, err)
+	assert.Equal(t, "", reason)
+
+	//A round completed without pushing anything, as when skipped, does not count as fulfilled
+	run = models.NewVRFRequestRun(job2)
+	run.UpdateStatus(models.VRFRequestRunStatus_COMPLETED, "Skipped, quorum of 2 reached", 3)
+	require.NoError(t, store.SaveVRFRequestRun(run, false))
+	reason, err = resolver.ExportedResolved(newJob(960))
+	require.NoError(t, err)
+	assert.Equal(t, "", reason)
+
+	//The first attempt trusts the mark of the delta handler, later ones fall back to the jobs table
+	retried := func(blockNum uint64) *models.VRFRequestJob {
+		job := newJob(blockNum)
+		for i := 0; i < 2; i++ {
+			run := models.NewVRFRequestRun(job)
+			run.UpdateStatus(models.VRFRequestRunStatus_FAILED, "Failed to push", 3)
+			require.NoError(t, store.SaveVRFRequestRun(run, false))
+		}
+		return job
+	}
+	table.set(`{"assoc_id":1,"seeds":[2],"frequency":"@every 5m"}`)
+	reason, err = resolver.ExportedResolved(retried(980))
+	require.NoError(t, err)
+	assert.Equal(t, "it was replaced by a new request in the jobs table", reason)
+
+	table.set("")
+	reason, err = resolver.ExportedResolved(newJob(980))
+	require.NoError(t, err)
+	assert.Equal(t, "", reason)
+	reason, err = resolver.ExportedResolved(retried(980))
+	require.NoError(t, err)
+	assert.Equal(t, "it is no longer in the jobs table", reason)
+
+	marked, err := store.MarkVRFRequestsRemoved("user1", 1, 990, nil)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), marked)
+	reason, err = resolver.ExportedResolved(newJob(990))
+	require.NoError(t, err)
+	assert.Equal(t, "it was removed from the jobs table at block: 990", reason)
+}
+
+func TestVRFResolver_ProcessUnknownKeyHash(t *testing.T) {
+	t.Parallel()
+	store, cleanup := cltest.NewStore(t)
+	defer cleanup()
+	keys := &svrf.VRFKeys{}
+	require.NoError(t, keys.Set(vrfkey.CreateKey().PublicKey))
+
+	req := &models.VRFRequest{
+		AssocID:   1,
+		BlockNum:  395235,
+		BlockHash: "000607e21b8d9da0ade735bcd19a9f8812335b510482d4b9f8c53753e0ebe9c8",
+		Seeds:     pq.StringArray([]string{"2"}),
+		Count:     1,
+		Caller:    "user1",
+		KeyHash:   vrfkey.CreateKey().PublicKey.MustHash().Hex()[2:],
+		Type:      models.VRFRequestType_IMMEDIATE,
+		Status:    models.VRFRequestStatus_ACTIVE,
+	}
+	require.NoError(t, store.CreateVRFRequest(req, nil))
+	job := models.NewVRFRequestJob(req)
+	require.NoError(t, store.SaveVRFRequestJob(job))
+
+	//The job fails before reaching the contract, so the resolver needs nothing but the store and the keys
+	resolver := &svrf.VRFResolver{Store: store, Keys: keys}
+	run, err := resolver.Process(job)
+	require.Error(t, err)
+	require.NotNil(t, run)
+	assert.Equal(t, models.VRFRequestRunStatus(models.VRFRequestRunStatus_FAILED), run.Status)
+	assert.Contains(t, run.StatusMsg, "Unknown key hash")
+
+	//The key is not unlocked in this oracle, retrying would not fix it
+	jobs, runsByJob, err := store.FindVRFRequestJobsWithRuns(req.ID)
+	require.NoError(t, err)
+	require.Len(t, jobs, 1)
+	assert.Equal(t, models.VRFRequestJobStatus(models.VRFRequestJobStatus_FAILED), jobs[0].Status)
+	assert.Equal(t, uint8(1), jobs[0].Retries)
+	assert.False(t, jobs[0].RetryAt.Valid)
+	assert.Len(t, runsByJob[job.ID], 1)
+	stored, err := store.FindVRFRequest(req.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.VRFRequestStatus(models.VRFRequestStatus_COMPLETED), stored.Status)
+}
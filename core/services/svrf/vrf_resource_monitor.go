@@ -0,0 +1,220 @@
+package svrf
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	eosc "github.com/eoscanada/eos-go"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/sebastianmontero/vrf-oracle/core/logger"
+	"github.com/sebastianmontero/vrf-oracle/core/services/eos"
+)
+
+var (
+	promEOSResourceUsed = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "eos_account_resource_used",
+		Help: "The amount of the resource used by the account, cpu in microseconds, net and ram in bytes",
+	}, []string{"account", "resource"})
+	promEOSResourceLimit = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "eos_account_resource_limit",
+		Help: "The amount of the resource available to the account, -1 if unlimited",
+	}, []string{"account", "resource"})
+	promVRFFulfilmentPaused = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "vrf_fulfilment_paused",
+		Help: "1 if the fulfilment of vrf requests is paused because the fulfilment account is running out of resources",
+	})
+)
+
+var errResourceMonitorStopped = errors.New("Resource monitor stopped")
+
+//accountResource usage of a resource of the fulfilment account
+type accountResource struct {
+	Name  string
+	Used  int64
+	Limit int64
+}
+
+//Percent percentage of the resource used, 0 if the resource is unlimited
+func (m *accountResource) Percent() float64 {
+	if m.Limit <= 0 {
+		return 0
+	}
+	return float64(m.Used) * 100 / float64(m.Limit)
+}
+
+//VRFResourceMonitor periodically checks the CPU, NET and RAM of the account that fulfils the vrf requests,
+//it exports them as prometheus gauges, logs a warning when the usage of a resource goes over WarnPercent
+//and pauses fulfilment while it is over PausePercent, a PausePercent of 0 disables pausing.
+//If Rent is set, CPU and NET over PausePercent are rented instead of pausing, fulfilment is only paused if
+//renting fails, as a paused fulfilment never pushes a transaction that could fail and trigger a rental.
+//After a rental the resource is not rented again until the limit read from the account grows, or until
+//RentCooldown passes in case the rental never shows up in the readings
+type VRFResourceMonitor struct {
+	EOS          *eos.EOS
+	Account      string
+	Interval     time.Duration
+	WarnPercent  uint8
+	PausePercent uint8
+	Rent         func(resource string) error
+	RentCooldown time.Duration
+	mu           sync.RWMutex
+	resumed      chan struct{}
+	rentals      map[string]*resourceRental
+	chStop       chan struct{}
+	wg           sync.WaitGroup
+}
+
+//resourceRental a rental the readings of the account may not reflect yet
+type resourceRental struct {
+	Limit    int64
+	RentedAt time.Time
+}
+
+//defaultRentCooldown time after a rental during which the resource is not rented again while its limit has not grown
+const defaultRentCooldown = 10 * time.Minute
+
+//NewVRFResourceMonitor creates a monitor, renter is optional
+func NewVRFResourceMonitor(eos *eos.EOS, account string, interval time.Duration, warnPercent, pausePercent uint8, renter *eos.ResourceRenter) *VRFResourceMonitor {
+	resumed := make(chan struct{})
+	close(resumed)
+	monitor := &VRFResourceMonitor{
+		EOS:          eos,
+		Account:      account,
+		Interval:     interval,
+		WarnPercent:  warnPercent,
+		PausePercent: pausePercent,
+		RentCooldown: defaultRentCooldown,
+		resumed:      resumed,
+		rentals:      make(map[string]*resourceRental),
+	}
+	if renter != nil {
+		monitor.Rent = renter.Rent
+	}
+	return monitor
+}
+
+//Start checks the resources and starts polling them
+func (m *VRFResourceMonitor) Start() {
+	m.chStop = make(chan struct{})
+	m.check()
+	m.wg.Add(1)
+	go m.run()
+}
+
+//Stop stops polling, fulfilments waiting for the account to recover are released with an error
+func (m *VRFResourceMonitor) Stop() {
+	close(m.chStop)
+	m.wg.Wait()
+}
+
+//Wait blocks while fulfilment is paused
+func (m *VRFResourceMonitor) Wait() error {
+	m.mu.RLock()
+	resumed := m.resumed
+	m.mu.RUnlock()
+	select {
+	case <-resumed:
+		return nil
+	case <-m.chStop:
+		return errResourceMonitorStopped
+	}
+}
+
+//Paused whether fulfilment is paused
+func (m *VRFResourceMonitor) Paused() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	select {
+	case <-m.resumed:
+		return false
+	default:
+		return true
+	}
+}
+
+func (m *VRFResourceMonitor) run() {
+	defer m.wg.Done()
+	ticker := time.NewTicker(m.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-m.chStop:
+			return
+		case <-ticker.C:
+			m.check()
+		}
+	}
+}
+
+func (m *VRFResourceMonitor) check() {
+	account, err := m.EOS.API.GetAccount(context.Background(), eosc.AN(m.Account))
+	if err != nil {
+		logger.Errorf("Failed to get account: %v, error: %v", m.Account, err)
+		return
+	}
+	resources := []*accountResource{
+		{Name: eos.Resource_CPU, Used: int64(account.CPULimit.Used), Limit: int64(account.CPULimit.Max)},
+		{Name: eos.Resource_NET, Used: int64(account.NetLimit.Used), Limit: int64(account.NetLimit.Max)},
+		{Name: "ram", Used: int64(account.RAMUsage), Limit: int64(account.RAMQuota)},
+	}
+	pause := false
+	for _, resource := range resources {
+		promEOSResourceUsed.WithLabelValues(m.Account, resource.Name).Set(float64(resource.Used))
+		promEOSResourceLimit.WithLabelValues(m.Account, resource.Name).Set(float64(resource.Limit))
+		percent := resource.Percent()
+		if m.PausePercent > 0 && percent >= float64(m.PausePercent) {
+			if m.rent(resource, percent) {
+				continue
+			}
+			logger.Errorf("Account: %v has used %.2f%% of its %v, pausing fulfilment", m.Account, percent, resource.Name)
+			pause = true
+		} else if m.WarnPercent > 0 && percent >= float64(m.WarnPercent) {
+			logger.Warnf("Account: %v has used %.2f%% of its %v", m.Account, percent, resource.Name)
+		}
+	}
+	m.setPaused(pause)
+}
+
+//rent rents the resource if it can be rented and a renter is set, returns true if it was rented or a previous
+//rental is still expected to show up in the limit of the resource
+func (m *VRFResourceMonitor) rent(resource *accountResource, percent float64) bool {
+	if m.Rent == nil || (resource.Name != eos.Resource_CPU && resource.Name != eos.Resource_NET) {
+		return false
+	}
+	if rental, ok := m.rentals[resource.Name]; ok && resource.Limit <= rental.Limit && time.Since(rental.RentedAt) < m.RentCooldown {
+		logger.Infof("Account: %v has used %.2f%% of its %v, waiting for the rental at: %v to be reflected in its limit", m.Account, percent, resource.Name, rental.RentedAt)
+		return true
+	}
+	logger.Warnf("Account: %v has used %.2f%% of its %v, renting it", m.Account, percent, resource.Name)
+	err := m.Rent(resource.Name)
+	if err != nil {
+		logger.Errorf("Failed to rent %v for account: %v, error: %v", resource.Name, m.Account, err)
+		return false
+	}
+	m.rentals[resource.Name] = &resourceRental{
+		Limit:    resource.Limit,
+		RentedAt: time.Now(),
+	}
+	return true
+}
+
+func (m *VRFResourceMonitor) setPaused(pause bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	select {
+	case <-m.resumed:
+		if pause {
+			m.resumed = make(chan struct{})
+			promVRFFulfilmentPaused.Set(1)
+		}
+	default:
+		if !pause {
+			logger.Infof("Account: %v has enough resources, resuming fulfilment", m.Account)
+			close(m.resumed)
+			promVRFFulfilmentPaused.Set(0)
+		}
+	}
+}
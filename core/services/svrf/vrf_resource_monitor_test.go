@@ -0,0 +1,140 @@
+package svrf_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sebastianmontero/vrf-oracle/core/services/eos"
+	"github.com/sebastianmontero/vrf-oracle/core/services/svrf"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+//newAccountServer serves get_account with the cpu and ram usage specified, in percent
+func newAccountServer(t *testing.T, cpuPercent, ramPercent int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/v1/chain/get_account", r.URL.Path)
+		fmt.Fprintf(w, `{
+			"account_name": "oracle",
+			"ram_quota": 100,
+			"ram_usage": %v,
+			"net_limit": {"used": 10, "available": 90, "max": 100},
+			"cpu_limit": {"used": %v, "available": %v, "max": 100}
+		}`, ramPercent, cpuPercent, 100-cpuPercent)
+	}))
+}
+
+func newResourceMonitor(t *testing.T, server *httptest.Server) *svrf.VRFResourceMonitor {
+	eosAPI, err := eos.New(server.URL, nil)
+	require.NoError(t, err)
+	return svrf.NewVRFResourceMonitor(eosAPI, "oracle", time.Hour, 80, 98, nil)
+}
+
+func TestVRFResourceMonitor_Pause(t *testing.T) {
+	server := newAccountServer(t, 99, 50)
+	defer server.Close()
+	monitor := newResourceMonitor(t, server)
+	monitor.Start()
+	defer monitor.Stop()
+
+	assert.True(t, monitor.Paused())
+}
+
+func TestVRFResourceMonitor_RentInsteadOfPausing(t *testing.T) {
+	server := newAccountServer(t, 99, 50)
+	defer server.Close()
+	monitor := newResourceMonitor(t, server)
+	var rented []string
+	monitor.Rent = func(resource string) error {
+		rented = append(rented, resource)
+		return nil
+	}
+	monitor.Start()
+	defer monitor.Stop()
+
+	assert.False(t, monitor.Paused())
+	assert.Equal(t, []string{eos.Resource_CPU}, rented)
+	assert.NoError(t, monitor.Wait())
+}
+
+func TestVRFResourceMonitor_PauseWhenRentFails(t *testing.T) {
+	server := newAccountServer(t, 99, 50)
+	defer server.Close()
+	monitor := newResourceMonitor(t, server)
+	monitor.Rent = func(resource string) error {
+		return errors.New("Daily budget: 1.0000 EOS exhausted, spent: 1.0000 EOS")
+	}
+	monitor.Start()
+	defer monitor.Stop()
+
+	assert.True(t, monitor.Paused())
+}
+
+func TestVRFResourceMonitor_PauseWhenRAMRunsOut(t *testing.T) {
+	server := newAccountServer(t, 50, 99)
+	defer server.Close()
+	monitor := newResourceMonitor(t, server)
+	var rented []string
+	monitor.Rent = func(resource string) error {
+		rented = append(rented, resource)
+		return nil
+	}
+	monitor.Start()
+	defer monitor.Stop()
+
+	//RAM can not be rented
+	assert.True(t, monitor.Paused())
+	assert.Empty(t, rented)
+}
+
+func TestVRFResourceMonitor_RentOnceUntilTheLimitGrows(t *testing.T) {
+	server := newAccountServer(t, 99, 50)
+	defer server.Close()
+	monitor := newResourceMonitor(t, server)
+	monitor.Interval = 10 * time.Millisecond
+	var mu sync.Mutex
+	var rented []string
+	monitor.Rent = func(resource string) error {
+		mu.Lock()
+		defer mu.Unlock()
+		rented = append(rented, resource)
+		return nil
+	}
+	monitor.Start()
+	time.Sleep(100 * time.Millisecond)
+	monitor.Stop()
+
+	//The limit read from the account never grows, so the rental is not repeated during the cooldown
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{eos.Resource_CPU}, rented)
+	assert.False(t, monitor.Paused())
+}
+
+func TestVRFResourceMonitor_RentAgainAfterTheCooldown(t *testing.T) {
+	server := newAccountServer(t, 99, 50)
+	defer server.Close()
+	monitor := newResourceMonitor(t, server)
+	monitor.Interval = 10 * time.Millisecond
+	monitor.RentCooldown = 0
+	var mu sync.Mutex
+	rentals := 0
+	monitor.Rent = func(resource string) error {
+		mu.Lock()
+		defer mu.Unlock()
+		rentals++
+		return nil
+	}
+	monitor.Start()
+	time.Sleep(100 * time.Millisecond)
+	monitor.Stop()
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Greater(t, rentals, 1)
+}
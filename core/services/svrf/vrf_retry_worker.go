@@ -0,0 +1,71 @@
+package svrf
+
+import (
+	"sync"
+	"time"
+
+	"github.com/sebastianmontero/vrf-oracle/core/logger"
+	strpkg "github.com/sebastianmontero/vrf-oracle/core/store"
+)
+
+//VRFRetryWorker periodically dispatches to the worker pool the vrf request jobs that failed and still have
+//retries left, the time between retries of a job grows exponentially, once the retries are exhausted the job
+//is marked as failed by the resolver
+type VRFRetryWorker struct {
+	Store    *strpkg.Store
+	Resolver *VRFResolver
+	chStop   chan struct{}
+	wg       sync.WaitGroup
+}
+
+func NewVRFRetryWorker(store *strpkg.Store, resolver *VRFResolver) *VRFRetryWorker {
+	return &VRFRetryWorker{
+		Store:    store,
+		Resolver: resolver,
+	}
+}
+
+//Start starts polling for jobs to retry
+func (m *VRFRetryWorker) Start() {
+	m.chStop = make(chan struct{})
+	m.wg.Add(1)
+	go m.run()
+}
+
+//Stop stops polling
+func (m *VRFRetryWorker) Stop() {
+	close(m.chStop)
+	m.wg.Wait()
+}
+
+func (m *VRFRetryWorker) run() {
+	defer m.wg.Done()
+	ticker := time.NewTicker(m.Store.Config.VRFRetryInterval())
+	defer ticker.Stop()
+	for {
+		select {
+		case <-m.chStop:
+			return
+		case <-ticker.C:
+			m.retryJobs()
+		}
+	}
+}
+
+func (m *VRFRetryWorker) retryJobs() {
+	jobs, err := m.Store.FindVRFRequestJobsToRetry(time.Now())
+	if err != nil {
+		logger.Errorf("Failed to find vrf request jobs to retry: %v", err)
+		return
+	}
+	for _, job := range jobs {
+		select {
+		case <-m.chStop:
+			return
+		default:
+		}
+		if m.Resolver.WorkerPool.Dispatch(job) {
+			logger.Infof("Retrying vrf request job: %v, retry: %v", job.ID, job.Retries)
+		}
+	}
+}
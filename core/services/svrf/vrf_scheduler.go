@@ -0,0 +1,225 @@
+package svrf
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"github.com/sebastianmontero/vrf-oracle/core/logger"
+	"github.com/sebastianmontero/vrf-oracle/core/services/eos"
+	strpkg "github.com/sebastianmontero/vrf-oracle/core/store"
+	"github.com/sebastianmontero/vrf-oracle/core/store/models"
+	"github.com/sebastianmontero/vrf-oracle/core/store/orm"
+	"github.com/sebastianmontero/vrf-oracle/core/utils"
+)
+
+//completeRetryInterval how often the completion of a request that has ended is tried again while it can not be
+//completed, e.g. because its last job is still pending
+const completeRetryInterval = 10 * time.Second
+
+//VRFScheduler creates jobs for recurrent and scheduled vrf requests, each job
+//uses the last irreversible block at the time it is created so that it gets a fresh random value
+type VRFScheduler struct {
+	Store    *strpkg.Store
+	EOS      *eos.EOS
+	Resolver *VRFResolver
+	cron     *cron.Cron
+	done     chan struct{}
+}
+
+func NewVRFScheduler(store *strpkg.Store, eos *eos.EOS, resolver *VRFResolver) *VRFScheduler {
+	return &VRFScheduler{
+		Store:    store,
+		EOS:      eos,
+		Resolver: resolver,
+	}
+}
+
+//Start starts the cron scheduler and adds the active recurrent requests and the scheduled
+//requests that have not been processed yet
+func (m *VRFScheduler) Start() error {
+	m.cron = cron.New(cron.WithParser(models.CronParser))
+	m.done = make(chan struct{})
+	m.cron.Start()
+	recurrent, err := m.Store.FindActiveVRFRequestsByType(models.VRFRequestType_RECURRENT)
+	if err != nil {
+		return fmt.Errorf("Failed to load active recurrent vrf requests: %v", err)
+	}
+	scheduled, err := m.Store.FindVRFRequestsWithoutJobs(models.VRFRequestType_SCHEDULED)
+	if err != nil {
+		return fmt.Errorf("Failed to load pending scheduled vrf requests: %v", err)
+	}
+	for _, req := range append(recurrent, scheduled...) {
+		m.AddRequest(req)
+	}
+	return nil
+}
+
+//Stop stops the cron scheduler and waits for the ticks being run to finish, the jobs they dispatched are
+//left to the worker pool
+func (m *VRFScheduler) Stop() {
+	close(m.done)
+	ctx := m.cron.Stop()
+	<-ctx.Done()
+}
+
+//AddRequest schedules a recurrent or scheduled request
+func (m *VRFScheduler) AddRequest(req *models.VRFRequest) {
+	switch req.Type {
+	case models.VRFRequestType_RECURRENT:
+		m.addRecurrent(req)
+	case models.VRFRequestType_SCHEDULED:
+		go m.runAt(req)
+	default:
+		logger.Errorf("Request: %v is not recurrent or scheduled", req)
+	}
+}
+
+func (m *VRFScheduler) addRecurrent(req *models.VRFRequest) {
+	//Ticks are skipped while the job of the previous one is queued or being processed by the worker pool,
+	//so that the request is not processed concurrently
+	var last *models.VRFRequestJob
+	//ended is closed once the request is removed from the scheduler
+	ended := make(chan struct{})
+	var once sync.Once
+	end := func() {
+		once.Do(func() { close(ended) })
+	}
+	job := cron.NewChain(cron.SkipIfStillRunning(cron.DiscardLogger)).Then(cron.FuncJob(func() {
+		stored := m.stored(req)
+		if stored != nil && stored.Status == models.VRFRequestStatus_CANCELLED {
+			logger.Infof("Request: %v was cancelled, removing it from the scheduler", req.ID)
+			m.cron.Remove(cron.EntryID(req.CronID))
+			end()
+			return
+		}
+		//The request is not completed either while its last job is pending, as the worker may save it
+		if last != nil && m.Resolver.WorkerPool.Pending(last.ID) {
+			logger.Infof("Skipping tick of request: %v, job: %v is still pending", req.ID, last.ID)
+			return
+		}
+		now := time.Now()
+		if req.Ended(now) {
+			if m.complete(req) {
+				end()
+			}
+			return
+		}
+		if stored != nil && stored.Removed() {
+			logger.Infof("Request: %v was removed from the jobs table, completing it", req.ID)
+			if m.complete(req) {
+				end()
+			}
+			return
+		}
+		if !req.Started(now) {
+			return
+		}
+		last = m.resolve(req)
+	}))
+	id, err := m.cron.AddJob(req.Frequency, job)
+	if err != nil {
+		logger.Errorf("Invalid frequency: %v for request: %v, error: %v", req.Frequency, req, err)
+		m.complete(req)
+		return
+	}
+	req.CronID = int(id)
+	m.saveRequest(req)
+	if req.EndAt.Valid {
+		go m.completeAt(req, job, ended)
+	}
+}
+
+//completeAt runs the tick of the request once its end date passes, so that requests with long frequencies are
+//completed on time instead of on their next tick, and requests that ended while the oracle was down are completed
+//on start. The tick runs again every completeRetryInterval until the request is removed from the scheduler
+func (m *VRFScheduler) completeAt(req *models.VRFRequest, tick cron.Job, ended chan struct{}) {
+	select {
+	case <-m.done:
+		return
+	case <-ended:
+		return
+	case <-time.After(utils.DurationFromNow(req.EndAt.Time)):
+	}
+	ticker := time.NewTicker(completeRetryInterval)
+	defer ticker.Stop()
+	for {
+		tick.Run()
+		select {
+		case <-m.done:
+			return
+		case <-ended:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (m *VRFScheduler) runAt(req *models.VRFRequest) {
+	select {
+	case <-m.done:
+	case <-time.After(utils.DurationFromNow(req.StartAt.Time)):
+		if stored := m.stored(req); stored != nil && stored.Status == models.VRFRequestStatus_CANCELLED {
+			logger.Infof("Request: %v was cancelled, skipping it", req.ID)
+			return
+		}
+		m.resolve(req)
+	}
+}
+
+//resolve creates a job at the last irreversible block and dispatches it to the worker pool of the resolver,
+//returns nil if the block could not be retrieved
+func (m *VRFScheduler) resolve(req *models.VRFRequest) *models.VRFRequestJob {
+	blockNum, blockHash, err := m.EOS.LastIrreversibleBlock()
+	if err != nil {
+		logger.Errorf("Failed to get last irreversible block for request: %v, error: %v", req, err)
+		return nil
+	}
+	job := models.NewVRFRequestJobAtBlock(req, blockNum, blockHash)
+	m.Resolver.Dispatch(job)
+	return job
+}
+
+//stored returns the stored request, nil if it could not be read. Operators cancel requests through the api, which
+//runs in another process, and the delta handler marks the requests removed from the jobs table, so the stored
+//request is checked instead of the one held in memory
+func (m *VRFScheduler) stored(req *models.VRFRequest) *models.VRFRequest {
+	stored, err := m.Store.FindVRFRequest(req.ID)
+	if err != nil {
+		logger.Errorf("Failed to read request: %v, error: %v", req.ID, err)
+		return nil
+	}
+	return stored
+}
+
+//complete completes the request and removes it from the scheduler, returns false if it could not be saved,
+//in which case the request stays scheduled so that the next tick completes it
+func (m *VRFScheduler) complete(req *models.VRFRequest) bool {
+	status := req.Status
+	req.Status = models.VRFRequestStatus_COMPLETED
+	if !m.saveRequest(req) {
+		req.Status = status
+		return false
+	}
+	m.cron.Remove(cron.EntryID(req.CronID))
+	return true
+}
+
+//saveRequest returns false if the request could not be saved, the error is logged so that a db error does not
+//stop the oracle. On a conflict the request was saved by another process after it was read, its UpdatedAt is
+//refreshed so that the next attempt does not conflict again
+func (m *VRFScheduler) saveRequest(req *models.VRFRequest) bool {
+	err := m.Store.SaveVRFRequest(req, nil)
+	if err == nil {
+		return true
+	}
+	logger.Errorf("Error storing vrf request: %v, error: %v", req.ID, err)
+	if errors.Is(err, orm.ErrOptimisticUpdateConflict) {
+		if stored := m.stored(req); stored != nil {
+			req.UpdatedAt = stored.UpdatedAt
+		}
+	}
+	return false
+}
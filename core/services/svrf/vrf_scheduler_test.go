@@ -0,0 +1,57 @@
+package svrf_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/sebastianmontero/vrf-oracle/core/internal/cltest"
+	"github.com/sebastianmontero/vrf-oracle/core/services/svrf"
+	"github.com/sebastianmontero/vrf-oracle/core/store/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	null "gopkg.in/guregu/null.v4"
+)
+
+func TestVRFScheduler_CompletesRecurrentRequestsAtTheirEnd(t *testing.T) {
+	t.Parallel()
+	store, cleanup := cltest.NewStore(t)
+	defer cleanup()
+
+	newRequest := func(assocID uint64, endAt time.Time) *models.VRFRequest {
+		req := &models.VRFRequest{
+			AssocID:   assocID,
+			BlockNum:  395235,
+			BlockHash: "000607e21b8d9da0ade735bcd19a9f8812335b510482d4b9f8c53753e0ebe9c8",
+			Seeds:     pq.StringArray([]string{"2"}),
+			Frequency: "@every 24h",
+			Count:     1,
+			Caller:    "user1",
+			Type:      models.VRFRequestType_RECURRENT,
+			Status:    models.VRFRequestStatus_ACTIVE,
+			EndAt:     null.TimeFrom(endAt),
+		}
+		require.NoError(t, store.CreateVRFRequest(req, nil))
+		return req
+	}
+	status := func(req *models.VRFRequest) func() models.VRFRequestStatus {
+		return func() models.VRFRequestStatus {
+			stored, err := store.FindVRFRequest(req.ID)
+			require.NoError(t, err)
+			return stored.Status
+		}
+	}
+	//Ended while the oracle was down
+	ended := newRequest(1, time.Now().Add(-time.Minute))
+	//Ends long before its next tick
+	ending := newRequest(2, time.Now().Add(time.Second))
+
+	scheduler := svrf.NewVRFScheduler(store, nil, &svrf.VRFResolver{})
+	require.NoError(t, scheduler.Start())
+	defer scheduler.Stop()
+
+	completed := models.VRFRequestStatus(models.VRFRequestStatus_COMPLETED)
+	assert.Eventually(t, func() bool { return status(ended)() == completed }, 5*time.Second, 100*time.Millisecond)
+	assert.Equal(t, models.VRFRequestStatus(models.VRFRequestStatus_ACTIVE), status(ending)())
+	assert.Eventually(t, func() bool { return status(ending)() == completed }, 5*time.Second, 100*time.Millisecond)
+}
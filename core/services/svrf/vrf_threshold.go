@@ -0,0 +1,410 @@
+package svrf
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"sync"
+	"time"
+
+	eosc "github.com/eoscanada/eos-go"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/sebastianmontero/vrf-oracle/core/logger"
+	"github.com/sebastianmontero/vrf-oracle/core/services/eos/contracts"
+	"github.com/sebastianmontero/vrf-oracle/core/services/vrf"
+	"github.com/sebastianmontero/vrf-oracle/core/store/models"
+	"github.com/sebastianmontero/vrf-oracle/core/store/models/vrfkey"
+	"github.com/sebastianmontero/vrf-oracle/core/utils"
+	ocrtypes "github.com/smartcontractkit/libocr/offchainreporting/types"
+	"go.dedis.ch/kyber/v3/share"
+)
+
+var errThresholdStopped = errors.New("VRF threshold stopped")
+
+const (
+	//defaultPartialProofsExpiry how long the partial proofs of a request are kept, so that the aggregator can retry
+	//the request or ask for them again after a restart
+	defaultPartialProofsExpiry = time.Hour
+	//thresholdTokenBucketRefillRate and thresholdTokenBucketSize limit the messages received from each oracle
+	thresholdTokenBucketRefillRate = 50.0
+	thresholdTokenBucketSize       = 500
+)
+
+//ThresholdContract pushes the proofs combined from the partial proofs of the oracles, contracts.VRF in the oracle
+type ThresholdContract interface {
+	SetRandThreshold(assocId uint64, caller string, proofs []*vrf.EOSThresholdProofResponse, values []*contracts.RandValues) (*eosc.PushTransactionFullResp, error)
+	SetRandThresholdAction(assocId uint64, caller string, proofs []*vrf.EOSThresholdProofResponse, values []*contracts.RandValues) *eosc.Action
+}
+
+//partialProofsMessage the partial proofs of the share Index for the seeds of a request, in the order of the seeds
+type partialProofsMessage struct {
+	Caller  string                 `json:"caller"`
+	AssocID uint64                 `json:"assoc_id"`
+	BlockID string                 `json:"block_id"`
+	Index   int                    `json:"index"`
+	Proofs  []*vrf.EOSPartialProof `json:"proofs"`
+}
+
+func (m *partialProofsMessage) key() string {
+	return fmt.Sprintf("%v:%v:%v", m.Caller, m.AssocID, m.BlockID)
+}
+
+//thresholdRequest the partial proofs of the oracle and those received from the other oracles for a request,
+//updated is closed when a message is received
+type thresholdRequest struct {
+	own       *partialProofsMessage
+	received  map[int]*partialProofsMessage
+	updated   chan struct{}
+	expiresAt time.Time
+}
+
+//VRFThreshold fulfils requests with a share of a threshold group key. Every oracle of the group generates
+//the partial proofs of its share for the seeds of a request and broadcasts them over the OCR p2p network,
+//the oracle whose share index is the assoc id modulo the number of shares aggregates the request: it waits
+//until it has the partial proofs of a threshold of shares, combines them and pushes the result. As the
+//output of the combination does not depend on which shares took part, no single oracle can predict or
+//bias it. The partial proofs are kept for Expiry, when the aggregator broadcasts its partial proofs for
+//a request the other oracles send theirs to it again, so that it can recover them after a restart.
+//Requests aggregated by an oracle that is down wait until it is back.
+type VRFThreshold struct {
+	Share    *vrfkey.KeyShare
+	Endpoint ocrtypes.BinaryNetworkEndpoint
+	Contract ThresholdContract
+	Timeout  time.Duration
+	Expiry   time.Duration
+	pubPoly  *share.PubPoly
+	lock     sync.Mutex
+	requests map[string]*thresholdRequest
+	chStop   chan struct{}
+	wg       sync.WaitGroup
+}
+
+func NewVRFThreshold(keyShare *vrfkey.KeyShare, endpoint ocrtypes.BinaryNetworkEndpoint, contract ThresholdContract, timeout time.Duration) (*VRFThreshold, error) {
+	pubPoly, err := keyShare.Commitments.PubPoly()
+	if err != nil {
+		return nil, fmt.Errorf("Invalid commitments of group key: %v, error: %v", keyShare.GroupPublicKey(), err)
+	}
+	return &VRFThreshold{
+		Share:    keyShare,
+		Endpoint: endpoint,
+		Contract: contract,
+		Timeout:  timeout,
+		Expiry:   defaultPartialProofsExpiry,
+		pubPoly:  pubPoly,
+		requests: make(map[string]*thresholdRequest),
+		chStop:   make(chan struct{}),
+	}, nil
+}
+
+//NewVRFThresholdEndpoint creates the endpoint through which the oracles of the group of the key share exchange
+//their partial proofs, peerIDs are the p2p peer ids of the oracles in the order of the index of their shares
+func NewVRFThresholdEndpoint(factory ocrtypes.BinaryNetworkEndpointFactory, keyShare *vrfkey.KeyShare, peerIDs, bootstrappers []string) (ocrtypes.BinaryNetworkEndpoint, error) {
+	if len(peerIDs) != keyShare.Nodes {
+		return nil, fmt.Errorf("Group key: %v has %v shares, got %v peer ids", keyShare.GroupPublicKey(), keyShare.Nodes, len(peerIDs))
+	}
+	//The endpoints of the oracles of a group key must not receive the messages of other groups or of OCR jobs
+	var configDigest ocrtypes.ConfigDigest
+	groupKey := keyShare.GroupPublicKey()
+	copy(configDigest[:], utils.MustHash("vrf threshold "+groupKey.String()).Bytes())
+	//Any oracles that are not needed to reach the threshold can fail
+	failureThreshold := keyShare.Nodes - keyShare.Threshold()
+	if failureThreshold < 1 {
+		failureThreshold = 1
+	}
+	return factory.MakeEndpoint(configDigest, peerIDs, bootstrappers, failureThreshold, thresholdTokenBucketRefillRate, thresholdTokenBucketSize)
+}
+
+//Start starts the endpoint and receiving the partial proofs of the other oracles
+func (m *VRFThreshold) Start() error {
+	err := m.Endpoint.Start()
+	if err != nil {
+		return fmt.Errorf("Failed to start threshold endpoint: %v", err)
+	}
+	m.wg.Add(1)
+	go m.run()
+	return nil
+}
+
+//Stop stops receiving partial proofs and closes the endpoint
+func (m *VRFThreshold) Stop() {
+	close(m.chStop)
+	m.wg.Wait()
+	err := m.Endpoint.Close()
+	if err != nil {
+		logger.Errorf("Failed to close threshold endpoint: %v", err)
+	}
+}
+
+//Aggregator index of the share of the oracle that aggregates the request
+func (m *VRFThreshold) Aggregator(assocID uint64) int {
+	return int(assocID % uint64(m.Share.Nodes))
+}
+
+//Resolve generates and broadcasts the partial proofs of the share for the seeds of the request. If the oracle
+//aggregates the request it waits for the partial proofs of the other oracles and returns the combined proofs,
+//in the order of the seeds, otherwise it returns nil
+func (m *VRFThreshold) Resolve(caller string, assocID uint64, preSeeds []vrf.PreSeedData) ([]*vrf.EOSThresholdProofResponse, error) {
+	if len(preSeeds) == 0 {
+		return nil, fmt.Errorf("Request has no seeds")
+	}
+	select {
+	case <-m.chStop:
+		return nil, errThresholdStopped
+	default:
+	}
+	own := &partialProofsMessage{
+		Caller:  caller,
+		AssocID: assocID,
+		BlockID: preSeeds[0].BlockHash.Hex(),
+		Index:   m.Share.Index,
+		Proofs:  make([]*vrf.EOSPartialProof, 0, len(preSeeds)),
+	}
+	for _, preSeed := range preSeeds {
+		partial, err := m.Share.PartialProof(preSeed)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to generate partial proof: %v", err)
+		}
+		own.Proofs = append(own.Proofs, vrf.NewEOSPartialProof(partial))
+	}
+	payload, err := json.Marshal(own)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to encode partial proofs: %v", err)
+	}
+	m.lock.Lock()
+	m.request(own.key()).own = own
+	m.lock.Unlock()
+	m.Endpoint.Broadcast(payload)
+	if m.Aggregator(assocID) != m.Share.Index {
+		return nil, nil
+	}
+	timeout := time.NewTimer(m.Timeout)
+	defer timeout.Stop()
+	for {
+		proofs, updated, received := m.combine(own.key(), preSeeds)
+		if proofs != nil {
+			return proofs, nil
+		}
+		select {
+		case <-updated:
+		case <-timeout.C:
+			return nil, fmt.Errorf("Timed out waiting for partial proofs, need: %v, got: %v", m.Share.Threshold(), received)
+		case <-m.chStop:
+			return nil, errThresholdStopped
+		}
+	}
+}
+
+//combine combines the partial proofs of the request, it returns the channel that is closed when more are received
+//and the number of oracles whose partial proofs were available if there are not enough valid ones for every seed
+func (m *VRFThreshold) combine(key string, preSeeds []vrf.PreSeedData) ([]*vrf.EOSThresholdProofResponse, chan struct{}, int) {
+	m.lock.Lock()
+	request := m.request(key)
+	messages := make([]*partialProofsMessage, 0, len(request.received)+1)
+	if request.own != nil {
+		messages = append(messages, request.own)
+	}
+	for _, message := range request.received {
+		messages = append(messages, message)
+	}
+	updated := request.updated
+	m.lock.Unlock()
+	//Combine the same partial proofs whatever the order they were received in
+	sort.Slice(messages, func(i, j int) bool { return messages[i].Index < messages[j].Index })
+	proofs := make([]*vrf.EOSThresholdProofResponse, 0, len(preSeeds))
+	for i, preSeed := range preSeeds {
+		finalSeed := vrf.FinalSeed(preSeed)
+		partials := make([]*vrf.PartialProof, 0, len(messages))
+		for _, message := range messages {
+			if len(message.Proofs) != len(preSeeds) || int(message.Proofs[i].Index) != message.Index {
+				continue
+			}
+			partial, err := message.Proofs[i].PartialProof(m.pubPoly, finalSeed)
+			if err != nil {
+				continue
+			}
+			partials = append(partials, partial)
+		}
+		proof, err := vrf.CombinePartialProofs(m.pubPoly, finalSeed, partials)
+		if err != nil {
+			return nil, updated, len(messages)
+		}
+		proofs = append(proofs, vrf.NewEOSThresholdProofResponse(preSeed, proof))
+	}
+	return proofs, updated, len(messages)
+}
+
+//request returns the partial proofs of the request, the caller is responsible for taking m.lock
+func (m *VRFThreshold) request(key string) *thresholdRequest {
+	request, ok := m.requests[key]
+	if !ok {
+		request = &thresholdRequest{
+			received: make(map[int]*partialProofsMessage),
+			updated:  make(chan struct{}),
+		}
+		m.requests[key] = request
+	}
+	request.expiresAt = time.Now().Add(m.Expiry)
+	return request
+}
+
+func (m *VRFThreshold) run() {
+	defer m.wg.Done()
+	ticker := time.NewTicker(m.Expiry / 4)
+	defer ticker.Stop()
+	for {
+		select {
+		case msg := <-m.Endpoint.Receive():
+			m.receive(msg)
+		case <-ticker.C:
+			m.expire()
+		case <-m.chStop:
+			return
+		}
+	}
+}
+
+//receive stores the partial proofs of another oracle and notifies the aggregator waiting for them. The aggregator
+//broadcasts its partial proofs every time it processes a request, when it does the partial proofs of this oracle
+//are sent to it again in case it lost them
+func (m *VRFThreshold) receive(msg ocrtypes.BinaryMessageWithSender) {
+	message := &partialProofsMessage{}
+	err := json.Unmarshal(msg.Msg, message)
+	if err != nil {
+		logger.Warnf("Failed to decode partial proofs sent by oracle: %v, error: %v", msg.Sender, err)
+		return
+	}
+	//Broadcasts are received by the sender as well
+	if message.Index == m.Share.Index {
+		return
+	}
+	//The oracle ids of the endpoint are the indexes of the shares
+	if message.Index != int(msg.Sender) {
+		logger.Warnf("Oracle: %v sent partial proofs of share: %v, ignoring them", msg.Sender, message.Index)
+		return
+	}
+	m.lock.Lock()
+	request := m.request(message.key())
+	request.received[message.Index] = message
+	close(request.updated)
+	request.updated = make(chan struct{})
+	own := request.own
+	m.lock.Unlock()
+	if own != nil && message.Index == m.Aggregator(message.AssocID) {
+		payload, err := json.Marshal(own)
+		if err != nil {
+			logger.Errorf("Failed to encode partial proofs: %v", err)
+			return
+		}
+		m.Endpoint.SendTo(payload, ocrtypes.OracleID(message.Index))
+	}
+}
+
+func (m *VRFThreshold) expire() {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	now := time.Now()
+	for key, request := range m.requests {
+		if request.expiresAt.Before(now) {
+			delete(m.requests, key)
+		}
+	}
+}
+
+//processThreshold fulfils the request in threshold mode, the partial proofs of the key share of the oracle are
+//shared with the other oracles of the group, if the oracle aggregates the request it pushes the combined proofs
+func (m *VRFResolver) processThreshold(job *models.VRFRequestJob, run *models.VRFRequestRun) (*models.VRFRequestRun, error) {
+	req := job.VRFRequest
+	preSeeds := make([]vrf.PreSeedData, 0, len(req.Seeds))
+	for _, seed := range req.Seeds {
+		bigSeed, ok := big.NewInt(0).SetString(seed, 10)
+		if !ok {
+			err := fmt.Errorf("Failed to parse seed: %v as big int", seed)
+			m.failRun(run, err, 0)
+			return run, err
+		}
+		preSeed, err := vrf.BigToSeed(bigSeed)
+		if err != nil {
+			err := fmt.Errorf("BigToSeed Failed: %v", err)
+			m.failRun(run, err, 0)
+			return run, err
+		}
+		preSeeds = append(preSeeds, vrf.PreSeedData{
+			PreSeed:   preSeed,
+			BlockHash: common.HexToHash(job.BlockHash),
+			BlockNum:  job.BlockNum,
+		})
+	}
+	proofs, err := m.Threshold.Resolve(req.Caller, req.AssocID, preSeeds)
+	if err != nil {
+		err := fmt.Errorf("Failed to resolve threshold proofs: %v", err)
+		m.failRun(run, err, m.maxRetries())
+		return run, err
+	}
+	if proofs == nil {
+		run.UpdateStatus(
+			models.VRFRequestRunStatus_COMPLETED,
+			fmt.Sprintf("Partial proofs sent, share %v aggregates the request", m.Threshold.Aggregator(req.AssocID)),
+			m.maxRetries())
+		m.saveRun(run, isCompleted(req))
+		return run, nil
+	}
+	var values []*contracts.RandValues
+	for _, proof := range proofs {
+		err = proof.Seed.SetType(m.Store.Config.VRFSeedType())
+		if err != nil {
+			m.failRun(run, err, 0)
+			return run, err
+		}
+		if req.Expanded() {
+			derived, err := proof.Values(req.ValueCount, req.ValueMin, req.ValueMax, req.UniqueValues)
+			if err != nil {
+				err := fmt.Errorf("Failed to derive values: %v", err)
+				m.failRun(run, err, 0)
+				return run, err
+			}
+			values = append(values, &contracts.RandValues{Values: derived})
+		}
+	}
+	//The combined proofs are recorded even if the push fails
+	run.Proofs, err = proofsJSON(proofs)
+	if err != nil {
+		m.failRun(run, err, 0)
+		return run, err
+	}
+	groupKey := m.Threshold.Share.GroupPublicKey()
+	run.KeyHash = groupKey.MustHash().Hex()
+	resp, err := m.setRandThreshold(req, proofs, values)
+	if err != nil {
+		err := fmt.Errorf("Calling SetRandThreshold on contract failed: %v", err)
+		m.failRun(run, err, m.maxRetries())
+		return run, err
+	}
+	run.SetTx(resp.TransactionID, uint64(resp.BlockNum))
+	run.UpdateStatus(
+		models.VRFRequestRunStatus_COMPLETED,
+		"Completed successfully",
+		m.maxRetries())
+	m.saveRun(run, isCompleted(req))
+	return run, nil
+}
+
+//setRandThreshold pushes the threshold proofs the way push pushes an action, returns the transaction they
+//were pushed in
+func (m *VRFResolver) setRandThreshold(req *models.VRFRequest, proofs []*vrf.EOSThresholdProofResponse, values []*contracts.RandValues) (*eosc.PushTransactionFullResp, error) {
+	err := m.Monitor.Wait()
+	if err != nil {
+		return nil, err
+	}
+	if m.Batcher == nil {
+		var resp *eosc.PushTransactionFullResp
+		err = m.Renter.Retry(func() error {
+			var err error
+			resp, err = m.Threshold.Contract.SetRandThreshold(req.AssocID, req.Caller, proofs, values)
+			return err
+		})
+		return resp, err
+	}
+	return m.Batcher.Fulfil(m.Threshold.Contract.SetRandThresholdAction(req.AssocID, req.Caller, proofs, values))
+}
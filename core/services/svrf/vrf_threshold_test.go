@@ -0,0 +1,220 @@
+package svrf_test
+
+import (
+	"encoding/json"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/sebastianmontero/vrf-oracle/core/services/svrf"
+	"github.com/sebastianmontero/vrf-oracle/core/services/vrf"
+	"github.com/sebastianmontero/vrf-oracle/core/store/models/vrfkey"
+	ocrtypes "github.com/smartcontractkit/libocr/offchainreporting/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+//stubNetwork delivers the messages between the endpoints of the oracles that are up
+type stubNetwork struct {
+	mu        sync.Mutex
+	endpoints map[int]*stubEndpoint
+}
+
+func (m *stubNetwork) deliver(payload []byte, from, to int) {
+	m.mu.Lock()
+	endpoint, ok := m.endpoints[to]
+	m.mu.Unlock()
+	if ok {
+		endpoint.recv <- ocrtypes.BinaryMessageWithSender{Msg: payload, Sender: ocrtypes.OracleID(from)}
+	}
+}
+
+type stubEndpoint struct {
+	network *stubNetwork
+	id      int
+	recv    chan ocrtypes.BinaryMessageWithSender
+}
+
+func (m *stubEndpoint) SendTo(payload []byte, to ocrtypes.OracleID) {
+	m.network.deliver(payload, m.id, int(to))
+}
+
+func (m *stubEndpoint) Broadcast(payload []byte) {
+	m.network.mu.Lock()
+	ids := make([]int, 0, len(m.network.endpoints))
+	for id := range m.network.endpoints {
+		ids = append(ids, id)
+	}
+	m.network.mu.Unlock()
+	for _, id := range ids {
+		m.network.deliver(payload, m.id, id)
+	}
+}
+
+func (m *stubEndpoint) Receive() <-chan ocrtypes.BinaryMessageWithSender {
+	return m.recv
+}
+
+func (m *stubEndpoint) Start() error {
+	m.network.mu.Lock()
+	defer m.network.mu.Unlock()
+	m.network.endpoints[m.id] = m
+	return nil
+}
+
+func (m *stubEndpoint) Close() error {
+	m.network.mu.Lock()
+	defer m.network.mu.Unlock()
+	delete(m.network.endpoints, m.id)
+	return nil
+}
+
+func newThresholdOracle(t *testing.T, network *stubNetwork, keyShare *vrfkey.KeyShare) *svrf.VRFThreshold {
+	endpoint := &stubEndpoint{network: network, id: keyShare.Index, recv: make(chan ocrtypes.BinaryMessageWithSender, 100)}
+	threshold, err := svrf.NewVRFThreshold(keyShare, endpoint, nil, 500*time.Millisecond)
+	require.NoError(t, err)
+	require.NoError(t, threshold.Start())
+	return threshold
+}
+
+func thresholdPreSeeds(t *testing.T, seeds ...int64) []vrf.PreSeedData {
+	preSeeds := make([]vrf.PreSeedData, 0, len(seeds))
+	for _, seed := range seeds {
+		preSeed, err := vrf.BigToSeed(big.NewInt(seed))
+		require.NoError(t, err)
+		preSeeds = append(preSeeds, vrf.PreSeedData{PreSeed: preSeed, BlockHash: common.HexToHash("0x0a0b"), BlockNum: 10})
+	}
+	return preSeeds
+}
+
+type thresholdResult struct {
+	proofs []*vrf.EOSThresholdProofResponse
+	err    error
+}
+
+//resolveAll resolves the request on the oracles concurrently
+func resolveAll(oracles []*svrf.VRFThreshold, assocID uint64, preSeeds []vrf.PreSeedData) []thresholdResult {
+	results := make([]thresholdResult, len(oracles))
+	var wg sync.WaitGroup
+	for i, oracle := range oracles {
+		wg.Add(1)
+		go func(i int, oracle *svrf.VRFThreshold) {
+			defer wg.Done()
+			proofs, err := oracle.Resolve("caller1", assocID, preSeeds)
+			results[i] = thresholdResult{proofs, err}
+		}(i, oracle)
+	}
+	wg.Wait()
+	return results
+}
+
+func TestVRFThreshold_AggregatorCombinesThePartialProofs(t *testing.T) {
+	shares, err := vrfkey.GenerateKeyShares(3, 5)
+	require.NoError(t, err)
+	pubPoly, err := shares[0].Commitments.PubPoly()
+	require.NoError(t, err)
+	network := &stubNetwork{endpoints: make(map[int]*stubEndpoint)}
+	oracles := make([]*svrf.VRFThreshold, 0, len(shares))
+	for _, keyShare := range shares {
+		oracle := newThresholdOracle(t, network, keyShare)
+		defer oracle.Stop()
+		oracles = append(oracles, oracle)
+	}
+	preSeeds := thresholdPreSeeds(t, 1, 2)
+	assocID := uint64(7)
+	aggregator := oracles[0].Aggregator(assocID)
+	assert.Equal(t, 2, aggregator)
+
+	results := resolveAll(oracles, assocID, preSeeds)
+	var outputs []string
+	for i, result := range results {
+		require.NoError(t, result.err)
+		if i != aggregator {
+			assert.Nil(t, result.proofs)
+			continue
+		}
+		require.Len(t, result.proofs, 2)
+		for j, proof := range result.proofs {
+			assert.NoError(t, proof.Verify(pubPoly))
+			assert.Len(t, proof.Partials, 3)
+			assert.Equal(t, vrf.FinalSeed(preSeeds[j]).Text(10), proof.FinalSeed)
+			outputs = append(outputs, proof.OutputU256)
+		}
+	}
+	require.Len(t, outputs, 2)
+	assert.NotEqual(t, outputs[0], outputs[1])
+
+	//Any threshold of oracles produce the same output, the output only depends on the seed and the block
+	results = resolveAll([]*svrf.VRFThreshold{oracles[4], oracles[2], oracles[3]}, assocID+5, preSeeds)
+	require.NoError(t, results[1].err)
+	assert.Equal(t, outputs[0], results[1].proofs[0].OutputU256)
+	assert.Equal(t, outputs[1], results[1].proofs[1].OutputU256)
+}
+
+func TestVRFThreshold_TimesOutWithoutEnoughPartialProofs(t *testing.T) {
+	shares, err := vrfkey.GenerateKeyShares(3, 4)
+	require.NoError(t, err)
+	network := &stubNetwork{endpoints: make(map[int]*stubEndpoint)}
+	aggregator := newThresholdOracle(t, network, shares[1])
+	defer aggregator.Stop()
+	other := newThresholdOracle(t, network, shares[2])
+	defer other.Stop()
+
+	results := resolveAll([]*svrf.VRFThreshold{aggregator, other}, 5, thresholdPreSeeds(t, 1))
+	assert.Error(t, results[0].err)
+	assert.Nil(t, results[0].proofs)
+	assert.NoError(t, results[1].err)
+}
+
+func TestVRFThreshold_PartialProofsAreSentAgainToTheAggregator(t *testing.T) {
+	shares, err := vrfkey.GenerateKeyShares(2, 3)
+	require.NoError(t, err)
+	pubPoly, err := shares[0].Commitments.PubPoly()
+	require.NoError(t, err)
+	network := &stubNetwork{endpoints: make(map[int]*stubEndpoint)}
+	preSeeds := thresholdPreSeeds(t, 3)
+	others := []*svrf.VRFThreshold{newThresholdOracle(t, network, shares[1]), newThresholdOracle(t, network, shares[2])}
+	for _, other := range others {
+		defer other.Stop()
+	}
+	//The aggregator is down while the other oracles process the request
+	results := resolveAll(others, 3, preSeeds)
+	for _, result := range results {
+		require.NoError(t, result.err)
+		assert.Nil(t, result.proofs)
+	}
+	aggregator := newThresholdOracle(t, network, shares[0])
+	defer aggregator.Stop()
+	proofs, err := aggregator.Resolve("caller1", 3, preSeeds)
+	require.NoError(t, err)
+	require.Len(t, proofs, 1)
+	assert.NoError(t, proofs[0].Verify(pubPoly))
+}
+
+func TestVRFThreshold_IgnoresPartialProofsOfOtherShares(t *testing.T) {
+	shares, err := vrfkey.GenerateKeyShares(2, 3)
+	require.NoError(t, err)
+	network := &stubNetwork{endpoints: make(map[int]*stubEndpoint)}
+	aggregator := newThresholdOracle(t, network, shares[0])
+	defer aggregator.Stop()
+	preSeeds := thresholdPreSeeds(t, 4)
+
+	//Oracle 2 relays the partial proofs of share 1 as its own
+	partial, err := shares[1].PartialProof(preSeeds[0])
+	require.NoError(t, err)
+	payload, err := json.Marshal(map[string]interface{}{
+		"caller":   "caller1",
+		"assoc_id": 0,
+		"block_id": preSeeds[0].BlockHash.Hex(),
+		"index":    1,
+		"proofs":   []*vrf.EOSPartialProof{vrf.NewEOSPartialProof(partial)},
+	})
+	require.NoError(t, err)
+	network.deliver(payload, 2, 0)
+
+	proofs, err := aggregator.Resolve("caller1", 0, preSeeds)
+	assert.Error(t, err)
+	assert.Nil(t, proofs)
+}
@@ -0,0 +1,191 @@
+package svrf
+
+import (
+	"hash/fnv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/sebastianmontero/vrf-oracle/core/store/models"
+)
+
+var (
+	promVRFQueuedJobs = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "vrf_resolver_queued_jobs",
+		Help: "The number of vrf request jobs waiting for a worker",
+	})
+	promVRFBusyWorkers = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "vrf_resolver_busy_workers",
+		Help: "The number of workers processing a vrf request job",
+	})
+	promVRFJobProcessingTime = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "vrf_resolver_job_processing_seconds",
+		Help:    "The time it takes to generate the proofs of a vrf request job and push them",
+		Buckets: prometheus.ExponentialBuckets(0.1, 2, 10),
+	})
+)
+
+//VRFWorkerPool processes vrf request jobs concurrently, queues are unbounded so that
+//the stream is never stalled, the backlog is reported through metrics instead.
+//If OrderPerCaller is set the jobs of a caller are always processed by the same
+//worker, in the order they were dispatched. A job that is already queued or being processed is not
+//dispatched again, as the retry worker finds it again until it is picked up by a worker
+type VRFWorkerPool struct {
+	OrderPerCaller bool
+	process        func(*models.VRFRequestJob)
+	workers        []*vrfWorker
+	mu             sync.Mutex
+	pending        map[uint64]bool
+	wg             sync.WaitGroup
+}
+
+func NewVRFWorkerPool(size int, orderPerCaller bool, process func(*models.VRFRequestJob)) *VRFWorkerPool {
+	if size < 1 {
+		size = 1
+	}
+	workers := make([]*vrfWorker, 0, size)
+	for i := 0; i < size; i++ {
+		workers = append(workers, &vrfWorker{
+			signal: make(chan struct{}, 1),
+		})
+	}
+	return &VRFWorkerPool{
+		OrderPerCaller: orderPerCaller,
+		process:        process,
+		workers:        workers,
+		pending:        make(map[uint64]bool),
+	}
+}
+
+//Start starts the workers
+func (m *VRFWorkerPool) Start() {
+	for _, worker := range m.workers {
+		worker.chStop = make(chan struct{})
+		m.wg.Add(1)
+		go func(worker *vrfWorker) {
+			defer m.wg.Done()
+			worker.run(func(job *models.VRFRequestJob) {
+				m.process(job)
+				m.done(job)
+			})
+		}(worker)
+	}
+}
+
+//Stop stops the workers and waits for the jobs being processed to finish,
+//queued jobs are recovered on the next start
+func (m *VRFWorkerPool) Stop() {
+	for _, worker := range m.workers {
+		close(worker.chStop)
+	}
+	m.wg.Wait()
+}
+
+//Dispatch queues a job, returns false if the job is already queued or being processed
+func (m *VRFWorkerPool) Dispatch(job *models.VRFRequestJob) bool {
+	m.mu.Lock()
+	if m.pending[job.ID] {
+		m.mu.Unlock()
+		return false
+	}
+	m.pending[job.ID] = true
+	m.mu.Unlock()
+	m.worker(job).push(job)
+	return true
+}
+
+//Pending returns true if the job is queued or being processed
+func (m *VRFWorkerPool) Pending(jobID uint64) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.pending[jobID]
+}
+
+func (m *VRFWorkerPool) done(job *models.VRFRequestJob) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.pending, job.ID)
+}
+
+func (m *VRFWorkerPool) worker(job *models.VRFRequestJob) *vrfWorker {
+	if m.OrderPerCaller {
+		h := fnv.New32a()
+		h.Write([]byte(job.VRFRequest.Caller))
+		return m.workers[h.Sum32()%uint32(len(m.workers))]
+	}
+	least := m.workers[0]
+	for _, worker := range m.workers[1:] {
+		if worker.load() < least.load() {
+			least = worker
+		}
+	}
+	return least
+}
+
+type vrfWorker struct {
+	mu     sync.Mutex
+	queue  []*models.VRFRequestJob
+	busy   bool
+	signal chan struct{}
+	chStop chan struct{}
+}
+
+func (m *vrfWorker) push(job *models.VRFRequestJob) {
+	m.mu.Lock()
+	m.queue = append(m.queue, job)
+	m.mu.Unlock()
+	promVRFQueuedJobs.Inc()
+	select {
+	case m.signal <- struct{}{}:
+	default:
+	}
+}
+
+//pop returns the next job and marks the worker as busy, nil if the queue is empty
+func (m *vrfWorker) pop() *models.VRFRequestJob {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.queue) == 0 {
+		m.busy = false
+		return nil
+	}
+	job := m.queue[0]
+	m.queue[0] = nil
+	m.queue = m.queue[1:]
+	m.busy = true
+	promVRFQueuedJobs.Dec()
+	return job
+}
+
+//load the number of jobs queued plus the one being processed
+func (m *vrfWorker) load() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.busy {
+		return len(m.queue) + 1
+	}
+	return len(m.queue)
+}
+
+func (m *vrfWorker) run(process func(*models.VRFRequestJob)) {
+	for {
+		select {
+		case <-m.chStop:
+			return
+		case <-m.signal:
+		}
+		for job := m.pop(); job != nil; job = m.pop() {
+			promVRFBusyWorkers.Inc()
+			start := time.Now()
+			process(job)
+			promVRFJobProcessingTime.Observe(time.Since(start).Seconds())
+			promVRFBusyWorkers.Dec()
+			select {
+			case <-m.chStop:
+				return
+			default:
+			}
+		}
+	}
+}
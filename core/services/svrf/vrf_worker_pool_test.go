@@ -0,0 +1,195 @@
+package svrf_test
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sebastianmontero/vrf-oracle/core/services/svrf"
+	"github.com/sebastianmontero/vrf-oracle/core/store/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newPoolJob(id uint64, caller string) *models.VRFRequestJob {
+	return &models.VRFRequestJob{
+		ID:         id,
+		VRFRequest: &models.VRFRequest{Caller: caller},
+	}
+}
+
+//blockingProcess a process function that reports the jobs it starts and blocks until they are released
+type blockingProcess struct {
+	started  chan uint64
+	release  chan struct{}
+	mu       sync.Mutex
+	finished []uint64
+}
+
+func newBlockingProcess() *blockingProcess {
+	return &blockingProcess{
+		started: make(chan uint64, 10),
+		release: make(chan struct{}),
+	}
+}
+
+func (m *blockingProcess) process(job *models.VRFRequestJob) {
+	m.started <- job.ID
+	<-m.release
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.finished = append(m.finished, job.ID)
+}
+
+func (m *blockingProcess) waitStarted(t *testing.T) uint64 {
+	select {
+	case id := <-m.started:
+		return id
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "Timed out waiting for a job to start")
+	}
+	return 0
+}
+
+func (m *blockingProcess) assertNotStarted(t *testing.T) {
+	select {
+	case id := <-m.started:
+		assert.Fail(t, "No job should have started", "job: %v", id)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func gaugeValue(t *testing.T, name string) float64 {
+	families, err := prometheus.DefaultGatherer.Gather()
+	require.NoError(t, err)
+	for _, family := range families {
+		if family.GetName() == name {
+			return family.GetMetric()[0].GetGauge().GetValue()
+		}
+	}
+	require.FailNow(t, fmt.Sprintf("Metric: %v not found", name))
+	return 0
+}
+
+func TestVRFWorkerPool_OrderPerCaller(t *testing.T) {
+	var mu sync.Mutex
+	processed := make(map[string][]uint64)
+	var wg sync.WaitGroup
+	pool := svrf.NewVRFWorkerPool(4, true, func(job *models.VRFRequestJob) {
+		defer wg.Done()
+		//Gives the jobs dispatched later the chance to overtake this one if ordering is broken
+		time.Sleep(time.Millisecond)
+		mu.Lock()
+		defer mu.Unlock()
+		processed[job.VRFRequest.Caller] = append(processed[job.VRFRequest.Caller], job.ID)
+	})
+	pool.Start()
+	defer pool.Stop()
+
+	callers := []string{"user1", "user2", "user3"}
+	for id := uint64(1); id <= 30; id++ {
+		wg.Add(1)
+		assert.True(t, pool.Dispatch(newPoolJob(id, callers[id%3])))
+	}
+	wg.Wait()
+
+	require.Len(t, processed, 3)
+	for caller, ids := range processed {
+		require.Len(t, ids, 10, caller)
+		for i := 1; i < len(ids); i++ {
+			assert.Less(t, ids[i-1], ids[i], "Jobs of %v processed out of order: %v", caller, ids)
+		}
+	}
+}
+
+func TestVRFWorkerPool_LeastLoaded(t *testing.T) {
+	process := newBlockingProcess()
+	pool := svrf.NewVRFWorkerPool(2, false, process.process)
+	pool.Start()
+
+	require.True(t, pool.Dispatch(newPoolJob(1, "user1")))
+	assert.Equal(t, uint64(1), process.waitStarted(t))
+	//The first worker is busy, the job goes to the idle one even if it is from the same caller
+	require.True(t, pool.Dispatch(newPoolJob(2, "user1")))
+	assert.Equal(t, uint64(2), process.waitStarted(t))
+	//Both workers are busy, the jobs are queued one on each of them
+	require.True(t, pool.Dispatch(newPoolJob(3, "user1")))
+	require.True(t, pool.Dispatch(newPoolJob(4, "user1")))
+	process.assertNotStarted(t)
+	//Jobs already queued or being processed are not dispatched again
+	assert.False(t, pool.Dispatch(newPoolJob(1, "user1")))
+	assert.False(t, pool.Dispatch(newPoolJob(3, "user1")))
+	assert.True(t, pool.Pending(1))
+	assert.True(t, pool.Pending(3))
+	assert.False(t, pool.Pending(5))
+
+	process.release <- struct{}{}
+	process.release <- struct{}{}
+	started := []uint64{process.waitStarted(t), process.waitStarted(t)}
+	assert.ElementsMatch(t, []uint64{3, 4}, started)
+	close(process.release)
+	pool.Stop()
+
+	assert.ElementsMatch(t, []uint64{1, 2, 3, 4}, process.finished)
+	assert.False(t, pool.Pending(1))
+	//Once processed a job can be dispatched again
+	assert.True(t, pool.Dispatch(newPoolJob(1, "user1")))
+}
+
+func TestVRFWorkerPool_Stop(t *testing.T) {
+	process := newBlockingProcess()
+	pool := svrf.NewVRFWorkerPool(1, false, process.process)
+	pool.Start()
+
+	require.True(t, pool.Dispatch(newPoolJob(1, "user1")))
+	process.waitStarted(t)
+	require.True(t, pool.Dispatch(newPoolJob(2, "user1")))
+
+	stopped := make(chan struct{})
+	go func() {
+		pool.Stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+		require.FailNow(t, "Stop returned while a job was being processed")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	close(process.release)
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "Timed out waiting for the pool to stop")
+	}
+	//The job being processed finishes, the queued one is left to be recovered on the next start
+	assert.Equal(t, []uint64{1}, process.finished)
+	process.assertNotStarted(t)
+}
+
+func TestVRFWorkerPool_Metrics(t *testing.T) {
+	process := newBlockingProcess()
+	pool := svrf.NewVRFWorkerPool(1, false, process.process)
+	pool.Start()
+	queued := gaugeValue(t, "vrf_resolver_queued_jobs")
+	busy := gaugeValue(t, "vrf_resolver_busy_workers")
+
+	require.True(t, pool.Dispatch(newPoolJob(1, "user1")))
+	process.waitStarted(t)
+	require.True(t, pool.Dispatch(newPoolJob(2, "user1")))
+	require.True(t, pool.Dispatch(newPoolJob(3, "user1")))
+	assert.Equal(t, queued+2, gaugeValue(t, "vrf_resolver_queued_jobs"))
+	assert.Equal(t, busy+1, gaugeValue(t, "vrf_resolver_busy_workers"))
+
+	process.release <- struct{}{}
+	process.waitStarted(t)
+	assert.Equal(t, queued+1, gaugeValue(t, "vrf_resolver_queued_jobs"))
+
+	close(process.release)
+	process.waitStarted(t)
+	pool.Stop()
+	assert.Equal(t, queued, gaugeValue(t, "vrf_resolver_queued_jobs"))
+	assert.Equal(t, busy, gaugeValue(t, "vrf_resolver_busy_workers"))
+}
@@ -4,30 +4,33 @@ import (
 	"bytes"
 	"encoding/base64"
 	"fmt"
+	"math/big"
 
+	"github.com/ethereum/go-ethereum/common"
 	"go.dedis.ch/kyber/v3"
 )
 
 //EOSProofResponse Proof response sent to an eosio chain
 type EOSProofResponse struct {
-	BlockNum   uint64 `json:"block_num"`
-	BlockID    string `json:"block_id"`
-	Seed       uint64 `json:"seed"`
-	FinalSeed  string `json:"final_seed"`
-	PublicKey  string `json:"public_key"`
-	Gamma      string `json:"gamma"`
-	C          string `json:"c"`
-	S          string `json:"s"`
-	OutputU256 string `json:"output_u256"`
-	OutputU64  uint64 `json:"output_u64"`
-}
-
-//NewEOSProofResponse Creates an EOSProofResponse from a ProofResponse
+	BlockNum   uint64  `json:"block_num"`
+	BlockID    string  `json:"block_id"`
+	Seed       EOSSeed `json:"seed"`
+	FinalSeed  string  `json:"final_seed"`
+	PublicKey  string  `json:"public_key"`
+	Gamma      string  `json:"gamma"`
+	C          string  `json:"c"`
+	S          string  `json:"s"`
+	OutputU256 string  `json:"output_u256"`
+	OutputU64  uint64  `json:"output_u64"`
+}
+
+//NewEOSProofResponse Creates an EOSProofResponse from a ProofResponse, the seed is a checksum256 so
+//that it holds the full value, its type has to be set to the type of the seed in the contract
 func NewEOSProofResponse(pr *ProofResponse) *EOSProofResponse {
 	return &EOSProofResponse{
 		BlockNum:   pr.BlockNum,
 		BlockID:    pr.BlockHash.String(),
-		Seed:       pr.PreSeed.Big().Uint64(),
+		Seed:       EOSSeed{Value: pr.PreSeed.Big(), Type: EOSSeedType_CHECKSUM256},
 		FinalSeed:  pr.P.Seed.Text(10),
 		PublicKey:  encodeKyberPoint(&pr.P.PublicKey),
 		Gamma:      encodeKyberPoint(&pr.P.Gamma),
@@ -54,8 +57,132 @@ func (m *EOSProofResponse) String() string {
 	)
 }
 
+//PreSeedData returns the seed and block the proof was generated for
+func (m *EOSProofResponse) PreSeedData() (PreSeedData, error) {
+	return eosPreSeedData(m.Seed, m.BlockID, m.BlockNum)
+}
+
+func eosPreSeedData(seed EOSSeed, blockID string, blockNum uint64) (PreSeedData, error) {
+	if seed.Value == nil {
+		return PreSeedData{}, fmt.Errorf("Seed not set")
+	}
+	preSeed, err := BigToSeed(seed.Value)
+	if err != nil {
+		return PreSeedData{}, fmt.Errorf("Invalid seed: %v, error: %v", seed, err)
+	}
+	return PreSeedData{
+		PreSeed:   preSeed,
+		BlockHash: common.HexToHash(blockID),
+		BlockNum:  blockNum,
+	}, nil
+}
+
+//Proof decodes the proof, the seed is the final seed recomputed from the seed and the block id,
+//an error is returned if it does not match the final seed of the response
+func (m *EOSProofResponse) Proof() (*Proof, error) {
+	preSeedData, err := m.PreSeedData()
+	if err != nil {
+		return nil, err
+	}
+	finalSeed := FinalSeed(preSeedData)
+	if finalSeed.Text(10) != m.FinalSeed {
+		return nil, fmt.Errorf("Final seed: %v does not match the one computed from the seed and block id: %v", m.FinalSeed, finalSeed)
+	}
+	publicKey, err := decodeKyberPoint(m.PublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid public key: %v, error: %v", m.PublicKey, err)
+	}
+	gamma, err := decodeKyberPoint(m.Gamma)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid gamma: %v, error: %v", m.Gamma, err)
+	}
+	c, err := decodeBig("c", m.C)
+	if err != nil {
+		return nil, err
+	}
+	s, err := decodeBig("s", m.S)
+	if err != nil {
+		return nil, err
+	}
+	output, err := decodeBig("output_u256", m.OutputU256)
+	if err != nil {
+		return nil, err
+	}
+	return &Proof{
+		PublicKey: publicKey,
+		Gamma:     gamma,
+		C:         c,
+		S:         s,
+		Seed:      finalSeed,
+		Output:    output,
+		OutputU64: m.OutputU64,
+	}, nil
+}
+
+//Verify decodes and verifies the proof, checks that it was generated with publicKey and that the uint64 output
+//is derived from the uint256 one. The public key is required, as the proof carries the key it was generated
+//with, and anyone can generate a valid proof for any seed with a key of their own
+func (m *EOSProofResponse) Verify(publicKey kyber.Point) error {
+	if publicKey == nil {
+		return fmt.Errorf("A trusted public key is required to verify the proof")
+	}
+	proof, err := m.Proof()
+	if err != nil {
+		return err
+	}
+	if !publicKey.Equal(proof.PublicKey) {
+		return fmt.Errorf("Proof public key: %v does not match: %v", proof.PublicKey, publicKey)
+	}
+	valid, err := proof.VerifyVRFProof()
+	if err != nil {
+		return fmt.Errorf("Failed to verify proof: %v", err)
+	}
+	if !valid {
+		return fmt.Errorf("Invalid proof: %v", m)
+	}
+	if outputU64 := scaleBigToUint64(proof.Output); outputU64 != m.OutputU64 {
+		return fmt.Errorf("Output u64: %v does not match the one derived from output u256: %v", m.OutputU64, outputU64)
+	}
+	return nil
+}
+
+//Values derives values in [min, max] from the uint256 output, see ExpandOutput
+func (m *EOSProofResponse) Values(count uint16, min, max uint64, unique bool) ([]uint64, error) {
+	output, err := decodeBig("output_u256", m.OutputU256)
+	if err != nil {
+		return nil, err
+	}
+	return ExpandOutput(output, count, min, max, unique)
+}
+
+//EncodeEOSPublicKey encodes a vrf public key the way the contract expects it, the same way the public key of the proofs is encoded
+func EncodeEOSPublicKey(publicKey kyber.Point) string {
+	return encodeKyberPoint(&publicKey)
+}
+
 func encodeKyberPoint(kp *kyber.Point) string {
 	var buf bytes.Buffer
 	(*kp).MarshalTo(&buf)
 	return base64.StdEncoding.EncodeToString(buf.Bytes())
 }
+
+func decodeKyberPoint(encoded string) (kyber.Point, error) {
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, err
+	}
+	point := secp256k1Curve.Point()
+	err = point.UnmarshalBinary(data)
+	if err != nil {
+		return nil, err
+	}
+	return point, nil
+}
+
+func decodeBig(name, decimal string) (*big.Int, error) {
+	value, ok := big.NewInt(0).SetString(decimal, 10)
+	if !ok {
+		return nil, fmt.Errorf("Invalid %v: %v, expected a decimal number", name, decimal)
+	}
+	return value, nil
+}
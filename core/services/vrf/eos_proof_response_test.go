@@ -0,0 +1,58 @@
+package vrf
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func generateTestEOSProofResponse(t *testing.T) *EOSProofResponse {
+	preSeed, err := BigToSeed(big.NewInt(42))
+	require.NoError(t, err)
+	pr, err := GenerateEOSProofResponse(common.BigToHash(big.NewInt(2)), PreSeedData{
+		PreSeed:   preSeed,
+		BlockHash: common.HexToHash("0x0000abcd"),
+		BlockNum:  10,
+	})
+	require.NoError(t, err)
+	return pr
+}
+
+func TestVRF_EOSProofResponseVerify(t *testing.T) {
+	pr := generateTestEOSProofResponse(t)
+	proof, err := pr.Proof()
+	require.NoError(t, err)
+	require.NoError(t, pr.Verify(proof.PublicKey))
+	assert.Error(t, pr.Verify(secp256k1Curve.Point().Base()))
+	//Without a trusted key a proof generated with any key would be accepted
+	assert.Error(t, pr.Verify(nil))
+}
+
+func TestVRF_EOSProofResponseVerifyTampered(t *testing.T) {
+	tests := []struct {
+		name   string
+		tamper func(pr *EOSProofResponse)
+	}{
+		{"seed", func(pr *EOSProofResponse) { pr.Seed.Value = big.NewInt(43) }},
+		{"block id", func(pr *EOSProofResponse) { pr.BlockID = common.HexToHash("0x0000abce").String() }},
+		{"final seed", func(pr *EOSProofResponse) { pr.FinalSeed = "1" }},
+		{"gamma", func(pr *EOSProofResponse) { pr.Gamma = pr.PublicKey }},
+		{"public key", func(pr *EOSProofResponse) { pr.PublicKey = "not base64" }},
+		{"c", func(pr *EOSProofResponse) { pr.C = "0x1" }},
+		{"output u256", func(pr *EOSProofResponse) { pr.OutputU256 = "1" }},
+		{"output u64", func(pr *EOSProofResponse) { pr.OutputU64++ }},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			pr := generateTestEOSProofResponse(t)
+			proof, err := pr.Proof()
+			require.NoError(t, err)
+			test.tamper(pr)
+			assert.Error(t, pr.Verify(proof.PublicKey))
+		})
+	}
+}
@@ -0,0 +1,163 @@
+package vrf
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	eosc "github.com/eoscanada/eos-go"
+)
+
+//Abi types the seed of a proof can have in the contract
+const (
+	EOSSeedType_UINT64      = "uint64"
+	EOSSeedType_UINT128     = "uint128"
+	EOSSeedType_CHECKSUM256 = "checksum256"
+)
+
+var eosSeedTypeBits = map[string]int{
+	EOSSeedType_UINT64:      64,
+	EOSSeedType_UINT128:     128,
+	EOSSeedType_CHECKSUM256: 256,
+}
+
+//EOSSeed seed of an EOSProofResponse, it is packed and rendered as its abi type in the contract,
+//checksum256 seeds hold the 32 bytes of the seed in big endian order
+type EOSSeed struct {
+	Value *big.Int
+	Type  string
+}
+
+//NewEOSSeed creates a seed of the abi type, returns an error if the value does not fit in it
+func NewEOSSeed(value *big.Int, abiType string) (EOSSeed, error) {
+	seed := EOSSeed{Value: value}
+	return seed, seed.SetType(abiType)
+}
+
+//SetType sets the abi type of the seed, returns an error instead of truncating the value if it does not fit in it
+func (m *EOSSeed) SetType(abiType string) error {
+	bits, ok := eosSeedTypeBits[abiType]
+	if !ok {
+		return fmt.Errorf("Unknown seed type: %v, valid types are: %v, %v, %v", abiType, EOSSeedType_UINT64, EOSSeedType_UINT128, EOSSeedType_CHECKSUM256)
+	}
+	if m.Value == nil || m.Value.Sign() < 0 {
+		return fmt.Errorf("Invalid seed: %v", m.Value)
+	}
+	if m.Value.BitLen() > bits {
+		return fmt.Errorf("Seed: %v does not fit in the contract seed type: %v", m.Value, abiType)
+	}
+	m.Type = abiType
+	return nil
+}
+
+//MarshalBinary packs the seed as its abi type
+func (m EOSSeed) MarshalBinary(encoder *eosc.Encoder) error {
+	switch m.Type {
+	case EOSSeedType_UINT64:
+		return encoder.Encode(m.Value.Uint64())
+	case EOSSeedType_UINT128:
+		lo := big.NewInt(0).And(m.Value, maxUint64)
+		hi := big.NewInt(0).Rsh(m.Value, 64)
+		return encoder.Encode(eosc.Uint128{Lo: lo.Uint64(), Hi: hi.Uint64()})
+	case EOSSeedType_CHECKSUM256:
+		return encoder.Encode(eosc.Checksum256(m.bytes32()))
+	}
+	return fmt.Errorf("Seed: %v has no valid type: %v", m.Value, m.Type)
+}
+
+//MarshalJSON renders the seed the way the chain does, uint64 as a number, uint128 as a decimal string
+//and checksum256 as a hex string
+func (m EOSSeed) MarshalJSON() ([]byte, error) {
+	if m.Value == nil {
+		return nil, fmt.Errorf("Seed has no value")
+	}
+	switch m.Type {
+	case EOSSeedType_UINT128:
+		return json.Marshal(m.Value.Text(10))
+	case EOSSeedType_CHECKSUM256:
+		return json.Marshal(hex.EncodeToString(m.bytes32()))
+	}
+	return []byte(m.Value.Text(10)), nil
+}
+
+//UnmarshalJSON parses the seed as its type if it is set, as the chain renders checksum256 seeds as hex
+//strings without prefix. Otherwise it parses a number, a decimal string or a 0x prefixed hex string, the
+//type is the smallest one that can hold the value
+func (m *EOSSeed) UnmarshalJSON(b []byte) error {
+	s := strings.ReplaceAll(string(b), `"`, "")
+	if m.Type != "" {
+		seed, err := ParseEOSSeed(s, m.Type)
+		if err != nil {
+			return err
+		}
+		*m = seed
+		return nil
+	}
+	value, err := parseSeedValue(s, strings.HasPrefix(s, "0x"))
+	if err != nil {
+		return err
+	}
+	*m = EOSSeed{Value: value}
+	for _, abiType := range []string{EOSSeedType_UINT64, EOSSeedType_UINT128} {
+		if m.SetType(abiType) == nil {
+			return nil
+		}
+	}
+	return m.SetType(EOSSeedType_CHECKSUM256)
+}
+
+//ParseEOSSeed parses a seed rendered as the abi type, checksum256 seeds are hex strings with or without
+//0x prefix, uint64 seeds are decimal numbers and uint128 seeds are decimal numbers as rendered by the node or
+//0x prefixed strings as rendered by the abi decoder of eos-go, which holds the 16 bytes in little endian order
+func ParseEOSSeed(s, abiType string) (EOSSeed, error) {
+	var value *big.Int
+	var err error
+	if abiType == EOSSeedType_UINT128 && strings.HasPrefix(s, "0x") {
+		value, err = parseUint128(s)
+	} else {
+		value, err = parseSeedValue(s, abiType == EOSSeedType_CHECKSUM256 || strings.HasPrefix(s, "0x"))
+	}
+	if err != nil {
+		return EOSSeed{}, err
+	}
+	return NewEOSSeed(value, abiType)
+}
+
+func parseUint128(s string) (*big.Int, error) {
+	var value eosc.Uint128
+	err := value.UnmarshalJSON([]byte(strconv.Quote(s)))
+	if err != nil {
+		return nil, fmt.Errorf("Invalid uint128 seed: %v, error: %v", s, err)
+	}
+	return value.BigInt(), nil
+}
+
+func parseSeedValue(s string, isHex bool) (*big.Int, error) {
+	if isHex {
+		data, err := hex.DecodeString(strings.TrimPrefix(s, "0x"))
+		if err != nil {
+			return nil, fmt.Errorf("Invalid hex seed: %v, error: %v", s, err)
+		}
+		return big.NewInt(0).SetBytes(data), nil
+	}
+	value, ok := big.NewInt(0).SetString(s, 10)
+	if !ok {
+		return nil, fmt.Errorf("Invalid seed: %v, expected a decimal number or a 0x prefixed hex string", s)
+	}
+	return value, nil
+}
+
+func (m EOSSeed) String() string {
+	if m.Value == nil {
+		return "<nil>"
+	}
+	return m.Value.Text(10)
+}
+
+func (m EOSSeed) bytes32() []byte {
+	data := make([]byte, 32)
+	return m.Value.FillBytes(data)
+}
@@ -0,0 +1,121 @@
+package vrf
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	eosc "github.com/eoscanada/eos-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVRF_EOSSeedSetType(t *testing.T) {
+	maxUint128 := sub(i().Lsh(one, 128), one)
+	_, err := NewEOSSeed(maxUint64, EOSSeedType_UINT64)
+	assert.NoError(t, err)
+	_, err = NewEOSSeed(add(maxUint64, one), EOSSeedType_UINT64)
+	assert.Error(t, err)
+	_, err = NewEOSSeed(maxUint128, EOSSeedType_UINT128)
+	assert.NoError(t, err)
+	_, err = NewEOSSeed(add(maxUint128, one), EOSSeedType_UINT128)
+	assert.Error(t, err)
+	_, err = NewEOSSeed(maxUint256, EOSSeedType_CHECKSUM256)
+	assert.NoError(t, err)
+	_, err = NewEOSSeed(big.NewInt(1), "uint32")
+	assert.Error(t, err)
+}
+
+func TestVRF_EOSSeedMarshalBinary(t *testing.T) {
+	tests := []struct {
+		abiType  string
+		value    *big.Int
+		expected string
+	}{
+		{EOSSeedType_UINT64, big.NewInt(0x0102), "0201000000000000"},
+		{EOSSeedType_UINT128, add(i().Lsh(big.NewInt(3), 64), big.NewInt(2)), "02000000000000000300000000000000"},
+		{EOSSeedType_CHECKSUM256, big.NewInt(0x0102), "0000000000000000000000000000000000000000000000000000000000000102"},
+	}
+	for _, test := range tests {
+		seed, err := NewEOSSeed(test.value, test.abiType)
+		require.NoError(t, err)
+		data, err := eosc.MarshalBinary(seed)
+		require.NoError(t, err)
+		assert.Equal(t, test.expected, hex.EncodeToString(data), test.abiType)
+	}
+}
+
+func TestVRF_EOSSeedJSON(t *testing.T) {
+	tests := []struct {
+		json    string
+		abiType string
+		value   string
+	}{
+		{`18446744073709551615`, EOSSeedType_UINT64, "18446744073709551615"},
+		{`"18446744073709551616"`, EOSSeedType_UINT128, "18446744073709551616"},
+		{`"00000000000000000000000000000000000000000000000000000000000000ff"`, EOSSeedType_CHECKSUM256, "255"},
+	}
+	for _, test := range tests {
+		seed := EOSSeed{Type: test.abiType}
+		require.NoError(t, json.Unmarshal([]byte(test.json), &seed))
+		assert.Equal(t, test.abiType, seed.Type)
+		assert.Equal(t, test.value, seed.String())
+		data, err := json.Marshal(seed)
+		require.NoError(t, err)
+		assert.Equal(t, test.json, string(data))
+	}
+	assert.Error(t, json.Unmarshal([]byte(`"seed"`), &EOSSeed{}))
+	assert.Error(t, json.Unmarshal([]byte(`"seed"`), &EOSSeed{Type: EOSSeedType_CHECKSUM256}))
+	assert.Error(t, json.Unmarshal([]byte(`"18446744073709551616"`), &EOSSeed{Type: EOSSeedType_UINT64}))
+	_, err := json.Marshal(EOSSeed{Type: EOSSeedType_UINT64})
+	assert.Error(t, err)
+}
+
+func TestVRF_ParseEOSSeedUint128(t *testing.T) {
+	tests := []struct {
+		s     string
+		value string
+	}{
+		{"18446744073709551616", "18446744073709551616"},
+		//Rendered by the abi decoder of eos-go, as firehose deltas are, the bytes are in little endian order
+		{"0x01000000000000000000000000000000", "1"},
+		{"0x02000000000000000300000000000000", "55340232221128654850"},
+		{"0xffffffffffffffffffffffffffffffff", "340282366920938463463374607431768211455"},
+	}
+	for _, test := range tests {
+		seed, err := ParseEOSSeed(test.s, EOSSeedType_UINT128)
+		require.NoError(t, err, test.s)
+		assert.Equal(t, test.value, seed.String(), test.s)
+	}
+	rendered, err := json.Marshal(eosc.Uint128{Lo: 2, Hi: 3})
+	require.NoError(t, err)
+	var s string
+	require.NoError(t, json.Unmarshal(rendered, &s))
+	seed, err := ParseEOSSeed(s, EOSSeedType_UINT128)
+	require.NoError(t, err)
+	assert.Equal(t, "55340232221128654850", seed.String())
+	_, err = ParseEOSSeed("0x0100", EOSSeedType_UINT128)
+	assert.Error(t, err)
+}
+
+func TestVRF_EOSSeedJSONWithoutType(t *testing.T) {
+	tests := []struct {
+		json    string
+		abiType string
+		value   string
+	}{
+		{`18446744073709551615`, EOSSeedType_UINT64, "18446744073709551615"},
+		{`"18446744073709551616"`, EOSSeedType_UINT128, "18446744073709551616"},
+		{`"0x00000000000000000000000000000000000000000000000000000000000000ff"`, EOSSeedType_UINT64, "255"},
+		//A 64 digit decimal number is not taken as hex
+		{`"1000000000000000000000000000000000000000000000000000000000000000"`, EOSSeedType_CHECKSUM256, "1000000000000000000000000000000000000000000000000000000000000000"},
+	}
+	for _, test := range tests {
+		seed := EOSSeed{}
+		require.NoError(t, json.Unmarshal([]byte(test.json), &seed))
+		assert.Equal(t, test.abiType, seed.Type, test.json)
+		assert.Equal(t, test.value, seed.String(), test.json)
+	}
+	assert.Error(t, json.Unmarshal([]byte(`"00000000000000000000000000000000000000000000000000000000000000ff"`), &EOSSeed{}))
+}
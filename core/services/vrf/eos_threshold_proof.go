@@ -0,0 +1,155 @@
+package vrf
+
+import (
+	"fmt"
+	"math/big"
+
+	"go.dedis.ch/kyber/v3/share"
+)
+
+//EOSPartialProof partial proof of a key share encoded like the proofs sent to an eosio chain, the public key
+//of the share is not included, it is derived from the commitments of the group key
+type EOSPartialProof struct {
+	Index uint16 `json:"index"`
+	Gamma string `json:"gamma"`
+	C     string `json:"c"`
+	S     string `json:"s"`
+}
+
+//NewEOSPartialProof encodes a PartialProof
+func NewEOSPartialProof(p *PartialProof) *EOSPartialProof {
+	return &EOSPartialProof{
+		Index: uint16(p.Index),
+		Gamma: encodeKyberPoint(&p.Gamma),
+		C:     p.C.Text(10),
+		S:     p.S.Text(10),
+	}
+}
+
+//PartialProof decodes the partial proof of the share Index of the group key described by pubPoly for seed,
+//it does not verify it
+func (m *EOSPartialProof) PartialProof(pubPoly *share.PubPoly, seed *big.Int) (*PartialProof, error) {
+	gamma, err := decodeKyberPoint(m.Gamma)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid gamma: %v, error: %v", m.Gamma, err)
+	}
+	c, err := decodeBig("c", m.C)
+	if err != nil {
+		return nil, err
+	}
+	s, err := decodeBig("s", m.S)
+	if err != nil {
+		return nil, err
+	}
+	return &PartialProof{
+		Index:          int(m.Index),
+		SharePublicKey: pubPoly.Eval(int(m.Index)).V,
+		GroupPublicKey: pubPoly.Commit(),
+		Gamma:          gamma,
+		C:              c,
+		S:              s,
+		Seed:           seed,
+	}, nil
+}
+
+//EOSThresholdProofResponse threshold proof response sent to an eosio chain, the contract verifies the partial
+//proofs against the commitments of the group key and checks that gamma is combined from them
+type EOSThresholdProofResponse struct {
+	BlockNum       uint64             `json:"block_num"`
+	BlockID        string             `json:"block_id"`
+	Seed           EOSSeed            `json:"seed"`
+	FinalSeed      string             `json:"final_seed"`
+	GroupPublicKey string             `json:"group_public_key"`
+	Gamma          string             `json:"gamma"`
+	OutputU256     string             `json:"output_u256"`
+	OutputU64      uint64             `json:"output_u64"`
+	Partials       []*EOSPartialProof `json:"partials"`
+}
+
+//NewEOSThresholdProofResponse encodes the threshold proof for the seed and block in s, the seed is a checksum256
+//as in NewEOSProofResponse
+func NewEOSThresholdProofResponse(s PreSeedData, proof *ThresholdProof) *EOSThresholdProofResponse {
+	partials := make([]*EOSPartialProof, 0, len(proof.Partials))
+	for _, partial := range proof.Partials {
+		partials = append(partials, NewEOSPartialProof(partial))
+	}
+	return &EOSThresholdProofResponse{
+		BlockNum:       s.BlockNum,
+		BlockID:        s.BlockHash.String(),
+		Seed:           EOSSeed{Value: s.PreSeed.Big(), Type: EOSSeedType_CHECKSUM256},
+		FinalSeed:      proof.Seed.Text(10),
+		GroupPublicKey: encodeKyberPoint(&proof.GroupPublicKey),
+		Gamma:          encodeKyberPoint(&proof.Gamma),
+		OutputU256:     proof.Output.Text(10),
+		OutputU64:      proof.OutputU64,
+		Partials:       partials,
+	}
+}
+
+//PreSeedData returns the seed and block the proof was generated for
+func (m *EOSThresholdProofResponse) PreSeedData() (PreSeedData, error) {
+	return eosPreSeedData(m.Seed, m.BlockID, m.BlockNum)
+}
+
+//Proof decodes the threshold proof of the group key described by pubPoly, the seed is the final seed
+//recomputed from the seed and the block id
+func (m *EOSThresholdProofResponse) Proof(pubPoly *share.PubPoly) (*ThresholdProof, error) {
+	preSeedData, err := m.PreSeedData()
+	if err != nil {
+		return nil, err
+	}
+	finalSeed := FinalSeed(preSeedData)
+	if finalSeed.Text(10) != m.FinalSeed {
+		return nil, fmt.Errorf("Final seed: %v does not match the one computed from the seed and block id: %v", m.FinalSeed, finalSeed)
+	}
+	groupPublicKey, err := decodeKyberPoint(m.GroupPublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid group public key: %v, error: %v", m.GroupPublicKey, err)
+	}
+	if !groupPublicKey.Equal(pubPoly.Commit()) {
+		return nil, fmt.Errorf("Proof group public key: %v does not match: %v", groupPublicKey, pubPoly.Commit())
+	}
+	gamma, err := decodeKyberPoint(m.Gamma)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid gamma: %v, error: %v", m.Gamma, err)
+	}
+	output, err := decodeBig("output_u256", m.OutputU256)
+	if err != nil {
+		return nil, err
+	}
+	partials := make([]*PartialProof, 0, len(m.Partials))
+	for _, encoded := range m.Partials {
+		partial, err := encoded.PartialProof(pubPoly, finalSeed)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid partial proof of share: %v, error: %v", encoded.Index, err)
+		}
+		partials = append(partials, partial)
+	}
+	return &ThresholdProof{
+		GroupPublicKey: groupPublicKey,
+		Gamma:          gamma,
+		Seed:           finalSeed,
+		Output:         output,
+		OutputU64:      m.OutputU64,
+		Partials:       partials,
+	}, nil
+}
+
+//Verify decodes and verifies the proof against the group key described by pubPoly, which has to be trusted
+//as anyone can generate a valid proof with a group key of their own
+func (m *EOSThresholdProofResponse) Verify(pubPoly *share.PubPoly) error {
+	proof, err := m.Proof(pubPoly)
+	if err != nil {
+		return err
+	}
+	return proof.Verify(pubPoly)
+}
+
+//Values derives values in [min, max] from the uint256 output, see ExpandOutput
+func (m *EOSThresholdProofResponse) Values(count uint16, min, max uint64, unique bool) ([]uint64, error) {
+	output, err := decodeBig("output_u256", m.OutputU256)
+	if err != nil {
+		return nil, err
+	}
+	return ExpandOutput(output, count, min, max, unique)
+}
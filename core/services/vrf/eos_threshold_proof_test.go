@@ -0,0 +1,52 @@
+package vrf
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/kyber/v3/share"
+	"go.dedis.ch/kyber/v3/util/random"
+
+	"github.com/sebastianmontero/vrf-oracle/core/services/signatures/secp256k1"
+)
+
+func TestVRF_EOSThresholdProofResponse(t *testing.T) {
+	priPoly := share.NewPriPoly(secp256k1Curve, 2, secp256k1.IntToScalar(big.NewInt(987654321)), random.New())
+	pubPoly := priPoly.Commit(nil)
+	seed, err := BigToSeed(big.NewInt(7))
+	require.NoError(t, err)
+	preSeedData := PreSeedData{PreSeed: seed, BlockHash: common.HexToHash("0xabcdef"), BlockNum: 100}
+	partials := make([]*PartialProof, 0, 3)
+	for _, keyShare := range priPoly.Shares(3) {
+		partial, err := GeneratePartialProof(pubPoly.Commit(), keyShare, FinalSeed(preSeedData))
+		require.NoError(t, err)
+		partials = append(partials, partial)
+	}
+	proof, err := CombinePartialProofs(pubPoly, FinalSeed(preSeedData), partials[1:])
+	require.NoError(t, err)
+
+	data, err := json.Marshal(NewEOSThresholdProofResponse(preSeedData, proof))
+	require.NoError(t, err)
+	response := &EOSThresholdProofResponse{}
+	require.NoError(t, json.Unmarshal(data, response))
+	assert.NoError(t, response.Verify(pubPoly))
+	assert.Equal(t, proof.OutputU64, response.OutputU64)
+
+	//The proof only verifies against the group key it was generated with
+	otherPubPoly := share.NewPriPoly(secp256k1Curve, 2, nil, random.New()).Commit(nil)
+	assert.Error(t, response.Verify(otherPubPoly))
+
+	tampered := *response
+	tampered.BlockID = common.HexToHash("0xabcdee").String()
+	assert.Error(t, tampered.Verify(pubPoly))
+	tampered = *response
+	tampered.Partials = []*EOSPartialProof{response.Partials[0], response.Partials[0]}
+	assert.Error(t, tampered.Verify(pubPoly))
+	tampered = *response
+	tampered.Gamma = response.Partials[0].Gamma
+	assert.Error(t, tampered.Verify(pubPoly))
+}
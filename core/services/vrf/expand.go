@@ -0,0 +1,61 @@
+package vrf
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/sebastianmontero/vrf-oracle/core/utils"
+)
+
+// MaxExpandedValues is the maximum number of values that can be derived from a
+// single VRF output
+const MaxExpandedValues = 1000
+
+var twoTo256 = i().Lsh(one, 256)
+
+// ExpandOutput deterministically derives count values in [min, max] from a VRF
+// output, so that a single proof can serve a request that needs several random
+// numbers. Anyone holding the output can reproduce the values:
+//
+//   for counter = 0, 1, 2, ... until count values have been derived
+//     x = keccak256(uint256(output) ++ uint256(counter)) as a uint256
+//     if x >= 2**256 - (2**256 mod (max-min+1)) skip the counter
+//     v = min + x mod (max-min+1)
+//     if unique and v has already been derived skip the counter
+//     append v
+//
+// Skipping the values of x above the largest multiple of the range size avoids
+// modulo bias.
+func ExpandOutput(output *big.Int, count uint16, min, max uint64, unique bool) ([]uint64, error) {
+	if output == nil || output.Sign() < 0 || output.BitLen() > 256 {
+		return nil, fmt.Errorf("output must be a uint256, got %v", output)
+	}
+	if min > max {
+		return nil, fmt.Errorf("min: %v is greater than max: %v", min, max)
+	}
+	if count > MaxExpandedValues {
+		return nil, fmt.Errorf("can derive at most %v values, requested %v", MaxExpandedValues, count)
+	}
+	rangeSize := add(sub(i().SetUint64(max), i().SetUint64(min)), one)
+	if unique && i().SetUint64(uint64(count)).Cmp(rangeSize) > 0 {
+		return nil, fmt.Errorf("can not derive %v unique values in [%v, %v]", count, min, max)
+	}
+	limit := sub(twoTo256, mod(twoTo256, rangeSize))
+	outputBytes := uint256ToBytes32(output)
+	values := make([]uint64, 0, count)
+	seen := make(map[uint64]bool, count)
+	for counter := int64(0); len(values) < int(count); counter++ {
+		msg := append(append([]byte{}, outputBytes...), uint256ToBytes32(big.NewInt(counter))...)
+		x := utils.MustHash(string(msg)).Big()
+		if x.Cmp(limit) >= 0 {
+			continue
+		}
+		value := add(i().SetUint64(min), mod(x, rangeSize)).Uint64()
+		if unique && seen[value] {
+			continue
+		}
+		seen[value] = true
+		values = append(values, value)
+	}
+	return values, nil
+}
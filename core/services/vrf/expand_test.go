@@ -0,0 +1,51 @@
+package vrf
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVRF_ExpandOutput(t *testing.T) {
+	output := bigFromHex("8d47b7ed1a08fb1e0dd86bc3bae5f3d8b7e6e2ebe5c31eb0b6ac3a4d2f2c1b0a")
+	values, err := ExpandOutput(output, 50, 1, 10000, true)
+	require.NoError(t, err)
+	assert.Len(t, values, 50)
+	seen := map[uint64]bool{}
+	for _, value := range values {
+		assert.True(t, value >= 1 && value <= 10000, "value out of range: %v", value)
+		assert.False(t, seen[value], "repeated value: %v", value)
+		seen[value] = true
+	}
+
+	again, err := ExpandOutput(output, 50, 1, 10000, true)
+	require.NoError(t, err)
+	assert.Equal(t, values, again, "values must be reproducible")
+
+	other, err := ExpandOutput(add(output, one), 50, 1, 10000, true)
+	require.NoError(t, err)
+	assert.NotEqual(t, values, other)
+}
+
+func TestVRF_ExpandOutputWholeRange(t *testing.T) {
+	values, err := ExpandOutput(big.NewInt(7), 10, 5, 14, true)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []uint64{5, 6, 7, 8, 9, 10, 11, 12, 13, 14}, values)
+
+	values, err = ExpandOutput(big.NewInt(7), 3, 0, ^uint64(0), false)
+	require.NoError(t, err)
+	assert.Len(t, values, 3)
+}
+
+func TestVRF_ExpandOutputInvalid(t *testing.T) {
+	_, err := ExpandOutput(big.NewInt(7), 1, 10, 5, false)
+	assert.Error(t, err)
+	_, err = ExpandOutput(big.NewInt(7), 11, 5, 14, true)
+	assert.Error(t, err)
+	_, err = ExpandOutput(big.NewInt(7), MaxExpandedValues+1, 0, 1, false)
+	assert.Error(t, err)
+	_, err = ExpandOutput(add(maxUint256, one), 1, 0, 1, false)
+	assert.Error(t, err)
+}
@@ -0,0 +1,213 @@
+package vrf
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+
+	"github.com/pkg/errors"
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/share"
+
+	"github.com/sebastianmontero/vrf-oracle/core/services/signatures/secp256k1"
+	"github.com/sebastianmontero/vrf-oracle/core/utils"
+)
+
+// Threshold VRF
+//
+// The group secret key x is shared among n nodes with a degree t-1 polynomial,
+// node i holds x_i and the commitments to the polynomial, which determine the
+// group public key x*G and every share public key x_i*G, are public.
+//
+// For a seed, every node computes H = HashToCurve(x*G, seed), the same point a
+// single node holding x would use, and publishes a PartialProof: Gamma_i = x_i*H
+// together with a proof that Gamma_i and x_i*G have the same discrete log. The
+// proof is built exactly like Proof, but with H derived from the group public
+// key.
+//
+// Any t valid partial proofs are combined by Lagrange interpolation into
+// Gamma = x*H, so the output is the one a regular Proof by x would have, and it
+// does not depend on which t nodes took part. Verifying a ThresholdProof takes
+// the public polynomial and the t partial proofs it was combined from.
+
+// PartialProof is the contribution of the node holding share Index to the
+// threshold VRF output for Seed
+type PartialProof struct {
+	Index          int
+	SharePublicKey kyber.Point
+	GroupPublicKey kyber.Point
+	Gamma          kyber.Point
+	C              *big.Int
+	S              *big.Int
+	Seed           *big.Int
+}
+
+func (p *PartialProof) String() string {
+	return fmt.Sprintf(
+		"vrf.PartialProof{Index: %v, SharePublicKey: %s, GroupPublicKey: %s, Gamma: %s, C: %x, S: %x, Seed: %x}",
+		p.Index, p.SharePublicKey, p.GroupPublicKey, p.Gamma, p.C, p.S, p.Seed)
+}
+
+// GeneratePartialProof returns the partial proof of keyShare for seed
+func GeneratePartialProof(groupPublicKey kyber.Point, keyShare *share.PriShare, seed *big.Int) (*PartialProof, error) {
+	for {
+		nonce, err := rand.Int(rand.Reader, secp256k1.GroupOrder)
+		if err != nil {
+			return nil, err
+		}
+		proof, err := generatePartialProofWithNonce(groupPublicKey, keyShare, seed, nonce)
+		if err == ErrCGammaEqualsSHash {
+			continue
+		}
+		return proof, err
+	}
+}
+
+func generatePartialProofWithNonce(groupPublicKey kyber.Point, keyShare *share.PriShare, seed, nonce *big.Int) (*PartialProof, error) {
+	if !secp256k1.IsSecp256k1Scalar(keyShare.V) || seed.BitLen() > 256 {
+		return nil, fmt.Errorf("badly-formatted key share or seed")
+	}
+	secret := secp256k1.ToInt(keyShare.V)
+	sharePublicKey := secp256k1Curve.Point().Mul(keyShare.V, nil)
+	h, err := HashToCurve(groupPublicKey, seed, func(*big.Int) {})
+	if err != nil {
+		return nil, errors.Wrap(err, "vrf.generatePartialProof#HashToCurve")
+	}
+	gamma := secp256k1Curve.Point().Mul(keyShare.V, h)
+	sm := secp256k1.IntToScalar(nonce)
+	u := secp256k1Curve.Point().Mul(sm, Generator)
+	v := secp256k1Curve.Point().Mul(sm, h)
+	c := ScalarFromCurvePoints(h, sharePublicKey, gamma, secp256k1.EthereumAddress(u), v)
+	s := mod(sub(nonce, mul(c, secret)), secp256k1.GroupOrder)
+	if e := checkCGammaNotEqualToSHash(c, gamma, s, h); e != nil {
+		return nil, e
+	}
+	return &PartialProof{
+		Index:          keyShare.I,
+		SharePublicKey: sharePublicKey,
+		GroupPublicKey: groupPublicKey,
+		Gamma:          gamma,
+		C:              c,
+		S:              s,
+		Seed:           seed,
+	}, nil
+}
+
+// Verify checks that the partial proof was generated by the share Index of the
+// key described by the public polynomial
+func (p *PartialProof) Verify(pubPoly *share.PubPoly) error {
+	if !(secp256k1.ValidPublicKey(p.SharePublicKey) && secp256k1.ValidPublicKey(p.Gamma) &&
+		secp256k1.RepresentsScalar(p.C) && secp256k1.RepresentsScalar(p.S) && p.Seed.BitLen() <= 256) {
+		return fmt.Errorf("badly-formatted partial proof")
+	}
+	if !p.GroupPublicKey.Equal(pubPoly.Commit()) {
+		return fmt.Errorf("partial proof is for group public key %s, expected %s", p.GroupPublicKey, pubPoly.Commit())
+	}
+	if !p.SharePublicKey.Equal(pubPoly.Eval(p.Index).V) {
+		return fmt.Errorf("share public key %s does not match share %v of the group key", p.SharePublicKey, p.Index)
+	}
+	h, err := HashToCurve(p.GroupPublicKey, p.Seed, func(*big.Int) {})
+	if err != nil {
+		return err
+	}
+	if checkCGammaNotEqualToSHash(p.C, p.Gamma, p.S, h) != nil {
+		return fmt.Errorf("c*γ = s*hash (disallowed in solidity verifier)")
+	}
+	uPrime := linearCombination(p.C, p.SharePublicKey, p.S, Generator)
+	vPrime := linearCombination(p.C, p.Gamma, p.S, h)
+	cPrime := ScalarFromCurvePoints(h, p.SharePublicKey, p.Gamma, secp256k1.EthereumAddress(uPrime), vPrime)
+	if !equal(p.C, cPrime) {
+		return fmt.Errorf("invalid partial proof of share %v", p.Index)
+	}
+	return nil
+}
+
+// ThresholdProof is the threshold VRF output for Seed, combined from Partials
+type ThresholdProof struct {
+	GroupPublicKey kyber.Point
+	Gamma          kyber.Point
+	Seed           *big.Int
+	Output         *big.Int
+	OutputU64      uint64
+	Partials       []*PartialProof
+}
+
+// CombinePartialProofs verifies the partial proofs and combines the first
+// threshold valid ones for the same seed, invalid partial proofs are skipped so
+// that a faulty node can not prevent the output from being produced
+func CombinePartialProofs(pubPoly *share.PubPoly, seed *big.Int, partials []*PartialProof) (*ThresholdProof, error) {
+	threshold := pubPoly.Threshold()
+	valid := make([]*PartialProof, 0, threshold)
+	seen := make(map[int]bool, threshold)
+	for _, partial := range partials {
+		if len(valid) == threshold {
+			break
+		}
+		if seen[partial.Index] || partial.Seed.Cmp(seed) != 0 || partial.Verify(pubPoly) != nil {
+			continue
+		}
+		seen[partial.Index] = true
+		valid = append(valid, partial)
+	}
+	if len(valid) < threshold {
+		return nil, fmt.Errorf("need %v valid partial proofs, got %v", threshold, len(valid))
+	}
+	gamma, err := combineGammas(valid)
+	if err != nil {
+		return nil, err
+	}
+	output := thresholdOutput(gamma)
+	return &ThresholdProof{
+		GroupPublicKey: pubPoly.Commit(),
+		Gamma:          gamma,
+		Seed:           seed,
+		Output:         output,
+		OutputU64:      scaleBigToUint64(output),
+		Partials:       valid,
+	}, nil
+}
+
+// Verify checks the partial proofs and that Gamma and the output were combined from them
+func (p *ThresholdProof) Verify(pubPoly *share.PubPoly) error {
+	if len(p.Partials) != pubPoly.Threshold() {
+		return fmt.Errorf("need %v partial proofs, got %v", pubPoly.Threshold(), len(p.Partials))
+	}
+	seen := make(map[int]bool, len(p.Partials))
+	for _, partial := range p.Partials {
+		if seen[partial.Index] {
+			return fmt.Errorf("share %v contributed more than one partial proof", partial.Index)
+		}
+		seen[partial.Index] = true
+		if partial.Seed.Cmp(p.Seed) != 0 {
+			return fmt.Errorf("partial proof of share %v is for seed %v, expected %v", partial.Index, partial.Seed, p.Seed)
+		}
+		if err := partial.Verify(pubPoly); err != nil {
+			return err
+		}
+	}
+	gamma, err := combineGammas(p.Partials)
+	if err != nil {
+		return err
+	}
+	if !gamma.Equal(p.Gamma) {
+		return fmt.Errorf("gamma was not combined from the partial proofs")
+	}
+	output := thresholdOutput(gamma)
+	if !equal(output, p.Output) || scaleBigToUint64(output) != p.OutputU64 {
+		return fmt.Errorf("output does not match gamma")
+	}
+	return nil
+}
+
+func combineGammas(partials []*PartialProof) (kyber.Point, error) {
+	shares := make([]*share.PubShare, 0, len(partials))
+	for _, partial := range partials {
+		shares = append(shares, &share.PubShare{I: partial.Index, V: partial.Gamma})
+	}
+	return share.RecoverCommit(secp256k1Curve, shares, len(shares), len(shares))
+}
+
+// thresholdOutput is computed from gamma like the output of a Proof
+func thresholdOutput(gamma kyber.Point) *big.Int {
+	return utils.MustHash(string(append(vrfRandomOutputHashPrefix, secp256k1.LongMarshal(gamma)...))).Big()
+}
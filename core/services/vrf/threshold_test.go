@@ -0,0 +1,61 @@
+package vrf
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.dedis.ch/kyber/v3/share"
+	"go.dedis.ch/kyber/v3/util/random"
+
+	"github.com/sebastianmontero/vrf-oracle/core/services/signatures/secp256k1"
+)
+
+func TestVRF_ThresholdProof(t *testing.T) {
+	secret := big.NewInt(123456789)
+	priPoly := share.NewPriPoly(secp256k1Curve, 3, secp256k1.IntToScalar(secret), random.New())
+	pubPoly := priPoly.Commit(nil)
+	keyShares := priPoly.Shares(5)
+	seed := big.NewInt(42)
+
+	partials := make([]*PartialProof, 0, len(keyShares))
+	for _, keyShare := range keyShares {
+		partial, err := GeneratePartialProof(pubPoly.Commit(), keyShare, seed)
+		require.NoError(t, err)
+		require.NoError(t, partial.Verify(pubPoly))
+		partials = append(partials, partial)
+	}
+
+	//The output is the one of a regular proof by the group secret, whichever shares take part
+	expected, err := GenerateProof(common.BigToHash(secret), common.BigToHash(seed))
+	require.NoError(t, err)
+	for _, subset := range [][]*PartialProof{partials[:3], partials[2:], {partials[4], partials[0], partials[2]}} {
+		proof, err := CombinePartialProofs(pubPoly, seed, subset)
+		require.NoError(t, err)
+		assert.True(t, proof.Gamma.Equal(expected.Gamma))
+		assert.Equal(t, expected.Output, proof.Output)
+		assert.Equal(t, expected.OutputU64, proof.OutputU64)
+		assert.NoError(t, proof.Verify(pubPoly))
+	}
+
+	//Invalid and repeated partial proofs are skipped
+	forged := *partials[1]
+	forged.Gamma = secp256k1Curve.Point().Add(forged.Gamma, Generator)
+	assert.Error(t, forged.Verify(pubPoly))
+	proof, err := CombinePartialProofs(pubPoly, seed, []*PartialProof{&forged, partials[0], partials[0], partials[3], partials[4]})
+	require.NoError(t, err)
+	assert.Equal(t, expected.Output, proof.Output)
+
+	_, err = CombinePartialProofs(pubPoly, seed, []*PartialProof{&forged, partials[0], partials[3]})
+	assert.Error(t, err)
+	_, err = CombinePartialProofs(pubPoly, big.NewInt(43), partials)
+	assert.Error(t, err)
+
+	//Any valid subset verifies, a tampered output does not
+	proof.Partials[0] = partials[1]
+	assert.NoError(t, proof.Verify(pubPoly))
+	proof.Output = add(proof.Output, one)
+	assert.Error(t, proof.Verify(pubPoly))
+}
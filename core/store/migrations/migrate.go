@@ -6,6 +6,20 @@ import (
 	"github.com/sebastianmontero/vrf-oracle/core/store/migrations/migration1609963213"
 	"github.com/sebastianmontero/vrf-oracle/core/store/migrations/migration1611388693"
 	"github.com/sebastianmontero/vrf-oracle/core/store/migrations/migration1611847145"
+	"github.com/sebastianmontero/vrf-oracle/core/store/migrations/migration1617235200"
+	"github.com/sebastianmontero/vrf-oracle/core/store/migrations/migration1617321600"
+	"github.com/sebastianmontero/vrf-oracle/core/store/migrations/migration1617408000"
+	"github.com/sebastianmontero/vrf-oracle/core/store/migrations/migration1617451200"
+	"github.com/sebastianmontero/vrf-oracle/core/store/migrations/migration1617494400"
+	"github.com/sebastianmontero/vrf-oracle/core/store/migrations/migration1617580800"
+	"github.com/sebastianmontero/vrf-oracle/core/store/migrations/migration1617667200"
+	"github.com/sebastianmontero/vrf-oracle/core/store/migrations/migration1617753600"
+	"github.com/sebastianmontero/vrf-oracle/core/store/migrations/migration1617796800"
+	"github.com/sebastianmontero/vrf-oracle/core/store/migrations/migration1617840000"
+	"github.com/sebastianmontero/vrf-oracle/core/store/migrations/migration1617926400"
+	"github.com/sebastianmontero/vrf-oracle/core/store/migrations/migration1618012800"
+	"github.com/sebastianmontero/vrf-oracle/core/store/migrations/migration1618099200"
+	"github.com/sebastianmontero/vrf-oracle/core/store/migrations/migration1618185600"
 
 	"github.com/sebastianmontero/vrf-oracle/core/store/migrations/migration1608217193"
 
@@ -503,6 +517,62 @@ func init() {
 			ID:      "1611847145",
 			Migrate: migration1611847145.Migrate,
 		},
+		{
+			ID:      "1617235200",
+			Migrate: migration1617235200.Migrate,
+		},
+		{
+			ID:      "1617321600",
+			Migrate: migration1617321600.Migrate,
+		},
+		{
+			ID:      "1617408000",
+			Migrate: migration1617408000.Migrate,
+		},
+		{
+			ID:      "1617451200",
+			Migrate: migration1617451200.Migrate,
+		},
+		{
+			ID:      "1617494400",
+			Migrate: migration1617494400.Migrate,
+		},
+		{
+			ID:      "1617580800",
+			Migrate: migration1617580800.Migrate,
+		},
+		{
+			ID:      "1617667200",
+			Migrate: migration1617667200.Migrate,
+		},
+		{
+			ID:      "1617753600",
+			Migrate: migration1617753600.Migrate,
+		},
+		{
+			ID:      "1617796800",
+			Migrate: migration1617796800.Migrate,
+		},
+		{
+			ID:      "1617840000",
+			Migrate: migration1617840000.Migrate,
+		},
+		{
+			ID:      "1617926400",
+			Migrate: migration1617926400.Migrate,
+		},
+		{
+			ID:      "1618012800",
+			Migrate: migration1618012800.Migrate,
+		},
+		{
+			ID:      "1618099200",
+			Migrate: migration1618099200.Migrate,
+		},
+		{
+			ID:      "1618185600",
+			Migrate: migration1618185600.Migrate,
+		},
 	}
 }
 
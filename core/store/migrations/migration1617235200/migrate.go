@@ -0,0 +1,14 @@
+package migration1617235200
+
+import "github.com/jinzhu/gorm"
+
+// Migrate adds the block used to generate the proofs of a vrf request job,
+// required by recurrent and scheduled requests that use a new block on every job
+func Migrate(tx *gorm.DB) error {
+	return tx.Exec(`
+		ALTER TABLE vrf_request_jobs ADD COLUMN IF NOT EXISTS block_num numeric(20);
+		ALTER TABLE vrf_request_jobs ADD COLUMN IF NOT EXISTS block_hash varchar(70);
+		UPDATE vrf_request_jobs SET block_num = vrf_requests.block_num, block_hash = vrf_requests.block_hash
+		FROM vrf_requests WHERE vrf_request_jobs.vrf_request_id = vrf_requests.id AND vrf_request_jobs.block_hash IS NULL;
+	`).Error
+}
@@ -0,0 +1,11 @@
+package migration1617321600
+
+import "github.com/jinzhu/gorm"
+
+// Migrate adds the time at which a failed vrf request job should be retried
+func Migrate(tx *gorm.DB) error {
+	return tx.Exec(`
+		ALTER TABLE vrf_request_jobs ADD COLUMN IF NOT EXISTS retry_at timestamp with time zone;
+		CREATE INDEX IF NOT EXISTS idx_vrf_request_jobs_retry_at ON vrf_request_jobs (retry_at);
+	`).Error
+}
@@ -0,0 +1,29 @@
+package migration1617408000
+
+import "github.com/jinzhu/gorm"
+
+// Migrate creates the vrf_dead_letters table, which stores the jobs table deltas
+// that could not be processed so that they can be replayed later
+func Migrate(tx *gorm.DB) error {
+	return tx.Exec(`
+		CREATE TABLE IF NOT EXISTS vrf_dead_letters (
+			id BIGSERIAL PRIMARY KEY,
+			code varchar(13) NOT NULL,
+			job_table varchar(13) NOT NULL,
+			scope varchar(13) NOT NULL,
+			operation varchar(30) NOT NULL,
+			fork_step varchar(30) NOT NULL,
+			old_data text,
+			new_data text,
+			block_num numeric(20) NOT NULL,
+			block_hash varchar(70) NOT NULL,
+			cursor varchar(1000) NOT NULL,
+			error text NOT NULL,
+			status varchar(20) NOT NULL,
+			replays integer NOT NULL DEFAULT 0,
+			created_at timestamp with time zone NOT NULL,
+			updated_at timestamp with time zone NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS idx_vrf_dead_letters_status ON vrf_dead_letters (status);
+	`).Error
+}
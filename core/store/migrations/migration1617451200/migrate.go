@@ -0,0 +1,22 @@
+package migration1617451200
+
+import "github.com/jinzhu/gorm"
+
+// Migrate creates the eos_rentals table, which records the resources rented for the
+// fulfilment account so that the daily budget of the payer is kept across restarts
+func Migrate(tx *gorm.DB) error {
+	return tx.Exec(`
+		CREATE TABLE IF NOT EXISTS eos_rentals (
+			id BIGSERIAL PRIMARY KEY,
+			payer varchar(13) NOT NULL,
+			receiver varchar(13) NOT NULL,
+			resource varchar(10) NOT NULL,
+			mode varchar(10) NOT NULL,
+			tx_id varchar(64) NOT NULL,
+			amount bigint NOT NULL,
+			symbol varchar(7) NOT NULL,
+			created_at timestamp with time zone NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS idx_eos_rentals_payer_created_at ON eos_rentals (payer, created_at);
+	`).Error
+}
@@ -0,0 +1,11 @@
+package migration1617494400
+
+import "github.com/jinzhu/gorm"
+
+// Migrate widens the vrf_requests seeds column so that it can hold the decimal
+// representation of 256 bit seeds
+func Migrate(tx *gorm.DB) error {
+	return tx.Exec(`
+		ALTER TABLE vrf_requests ALTER COLUMN seeds TYPE varchar(80)[];
+	`).Error
+}
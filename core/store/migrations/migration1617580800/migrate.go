@@ -0,0 +1,14 @@
+package migration1617580800
+
+import "github.com/jinzhu/gorm"
+
+// Migrate adds the columns that describe the values to derive from the output of
+// each seed of a vrf request
+func Migrate(tx *gorm.DB) error {
+	return tx.Exec(`
+		ALTER TABLE vrf_requests ADD COLUMN IF NOT EXISTS value_count integer NOT NULL DEFAULT 0;
+		ALTER TABLE vrf_requests ADD COLUMN IF NOT EXISTS value_min numeric(20) NOT NULL DEFAULT 0;
+		ALTER TABLE vrf_requests ADD COLUMN IF NOT EXISTS value_max numeric(20) NOT NULL DEFAULT 0;
+		ALTER TABLE vrf_requests ADD COLUMN IF NOT EXISTS unique_values boolean NOT NULL DEFAULT false;
+	`).Error
+}
@@ -0,0 +1,11 @@
+package migration1617667200
+
+import "github.com/jinzhu/gorm"
+
+// Migrate adds the hash of the vrf key that has to generate the proofs of a vrf
+// request
+func Migrate(tx *gorm.DB) error {
+	return tx.Exec(`
+		ALTER TABLE vrf_requests ADD COLUMN IF NOT EXISTS key_hash varchar(70) NOT NULL DEFAULT '';
+	`).Error
+}
@@ -0,0 +1,24 @@
+package migration1617753600
+
+import "github.com/jinzhu/gorm"
+
+// Migrate creates the vrf_key_rotations table, which records when each vrf key
+// was registered in the contract and when it was archived
+func Migrate(tx *gorm.DB) error {
+	return tx.Exec(`
+		CREATE TABLE IF NOT EXISTS vrf_key_rotations (
+			id BIGSERIAL PRIMARY KEY,
+			old_key varchar(70) NOT NULL,
+			old_key_hash varchar(70) NOT NULL,
+			new_key varchar(70) NOT NULL,
+			new_key_hash varchar(70) NOT NULL,
+			register_tx_id varchar(70) NOT NULL,
+			status varchar(20) NOT NULL,
+			grace_ends_at timestamp with time zone NOT NULL,
+			completed_at timestamp with time zone,
+			created_at timestamp with time zone NOT NULL,
+			updated_at timestamp with time zone NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS idx_vrf_key_rotations_status ON vrf_key_rotations (status);
+	`).Error
+}
@@ -0,0 +1,20 @@
+package migration1617796800
+
+import "github.com/jinzhu/gorm"
+
+// Migrate creates the encrypted_vrf_key_shares table, which holds the share of each
+// threshold vrf group key the node takes part in
+func Migrate(tx *gorm.DB) error {
+	return tx.Exec(`
+		CREATE TABLE IF NOT EXISTS encrypted_vrf_key_shares (
+			group_public_key varchar(70) PRIMARY KEY,
+			index integer NOT NULL,
+			nodes integer NOT NULL,
+			public_key varchar(70) NOT NULL,
+			commitments text NOT NULL,
+			vrf_key text NOT NULL,
+			created_at timestamp with time zone NOT NULL,
+			updated_at timestamp with time zone NOT NULL
+		);
+	`).Error
+}
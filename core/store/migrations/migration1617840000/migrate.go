@@ -0,0 +1,12 @@
+package migration1617840000
+
+import "github.com/jinzhu/gorm"
+
+// Migrate adds the columns used by the commit-reveal strategy to the vrf request
+// jobs
+func Migrate(tx *gorm.DB) error {
+	return tx.Exec(`
+		ALTER TABLE vrf_request_jobs ADD COLUMN IF NOT EXISTS secrets varchar(70)[];
+		ALTER TABLE vrf_request_jobs ADD COLUMN IF NOT EXISTS committed_at timestamp with time zone;
+	`).Error
+}
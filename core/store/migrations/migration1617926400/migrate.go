@@ -0,0 +1,15 @@
+package migration1617926400
+
+import "github.com/jinzhu/gorm"
+
+// Migrate adds the columns that record the fulfilment transaction of the vrf
+// request runs, the proofs pushed and the key that generated them
+func Migrate(tx *gorm.DB) error {
+	return tx.Exec(`
+		ALTER TABLE vrf_request_runs ADD COLUMN IF NOT EXISTS tx_id varchar(64) NOT NULL DEFAULT '';
+		ALTER TABLE vrf_request_runs ADD COLUMN IF NOT EXISTS tx_block_num numeric(20) NOT NULL DEFAULT 0;
+		ALTER TABLE vrf_request_runs ADD COLUMN IF NOT EXISTS proofs jsonb;
+		ALTER TABLE vrf_request_runs ADD COLUMN IF NOT EXISTS key_hash varchar(70) NOT NULL DEFAULT '';
+		CREATE INDEX IF NOT EXISTS idx_vrf_request_runs_tx_id ON vrf_request_runs (tx_id);
+	`).Error
+}
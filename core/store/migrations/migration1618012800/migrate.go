@@ -0,0 +1,29 @@
+package migration1618012800
+
+import "github.com/jinzhu/gorm"
+
+// Migrate creates the eos_txs table, which tracks the transactions pushed to the
+// chain until they are included in an irreversible block, and adds the time the
+// fulfilment transaction of the vrf request runs was confirmed
+func Migrate(tx *gorm.DB) error {
+	return tx.Exec(`
+		CREATE TABLE IF NOT EXISTS eos_txs (
+			id BIGSERIAL PRIMARY KEY,
+			tx_id varchar(64) NOT NULL,
+			current_tx_id varchar(64) NOT NULL,
+			actions jsonb NOT NULL,
+			block_num numeric(20) NOT NULL,
+			head_block_num numeric(20) NOT NULL DEFAULT 0,
+			expires_at timestamp with time zone NOT NULL,
+			attempts bigint NOT NULL,
+			status varchar(20) NOT NULL,
+			error text NOT NULL DEFAULT '',
+			confirmed_at timestamp with time zone,
+			created_at timestamp with time zone NOT NULL,
+			updated_at timestamp with time zone NOT NULL
+		);
+		CREATE UNIQUE INDEX IF NOT EXISTS idx_eos_txs_tx_id ON eos_txs (tx_id);
+		CREATE INDEX IF NOT EXISTS idx_eos_txs_status ON eos_txs (status);
+		ALTER TABLE vrf_request_runs ADD COLUMN IF NOT EXISTS confirmed_at timestamp with time zone;
+	`).Error
+}
@@ -0,0 +1,11 @@
+package migration1618099200
+
+import "github.com/jinzhu/gorm"
+
+// Migrate adds the block at which the row of each vrf request was removed from
+// the jobs table, which the contract does once it resolves the request
+func Migrate(tx *gorm.DB) error {
+	return tx.Exec(`
+		ALTER TABLE vrf_requests ADD COLUMN IF NOT EXISTS removed_block_num numeric(20) NOT NULL DEFAULT 0;
+	`).Error
+}
@@ -0,0 +1,16 @@
+package migration1618185600
+
+import "github.com/jinzhu/gorm"
+
+// Migrate adds the block of the setcommit transaction to the vrf request jobs,
+// commit-reveal jobs are revealed once it is irreversible. Jobs already
+// committed take it from the run that committed them
+func Migrate(tx *gorm.DB) error {
+	return tx.Exec(`
+		ALTER TABLE vrf_request_jobs ADD COLUMN IF NOT EXISTS commit_tx_block_num numeric(20) NOT NULL DEFAULT 0;
+		UPDATE vrf_request_jobs SET commit_tx_block_num = COALESCE((
+			SELECT MAX(tx_block_num) FROM vrf_request_runs
+			WHERE vrf_request_runs.vrf_request_job_id = vrf_request_jobs.id AND tx_id <> '' AND status IN ('Completed', 'Confirmed')
+		), 0) WHERE status = 'Committed';
+	`).Error
+}
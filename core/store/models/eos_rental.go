@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+)
+
+//EOSRental resources rented for an account, the payments made by the payer during a day are counted against
+//its daily budget. Amount is the payment in the smallest unit of Symbol
+type EOSRental struct {
+	ID        uint64    `gorm:"primary_key;auto_increment"`
+	Payer     string    `gorm:"type:varchar(13);not null"`
+	Receiver  string    `gorm:"type:varchar(13);not null"`
+	Resource  string    `gorm:"type:varchar(10);not null"`
+	Mode      string    `gorm:"type:varchar(10);not null"`
+	TxID      string    `gorm:"type:varchar(64);not null"`
+	Amount    int64     `gorm:"not null"`
+	Symbol    string    `gorm:"type:varchar(7);not null"`
+	CreatedAt time.Time `gorm:"not null"`
+}
@@ -0,0 +1,96 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	null "gopkg.in/guregu/null.v4"
+)
+
+type EOSTxStatus string
+
+const (
+	EOSTxStatus_UNCONFIRMED = "Unconfirmed"
+	EOSTxStatus_CONFIRMED   = "Confirmed"
+	EOSTxStatus_FAILED      = "Failed"
+)
+
+//EOSTx a transaction pushed to the chain, tracked until it is included in an irreversible block.
+//TxID is the id of the transaction when it was first pushed, which is the one known by the caller,
+//CurrentTxID is the id of the last attempt, each time the transaction expires without being included
+//its actions are signed again, which changes its id. BlockNum is the block the last attempt was included in
+//as reported by the push, a microfork can move it to any block after HeadBlockNum, the head block when it
+//was signed
+type EOSTx struct {
+	ID           uint64      `gorm:"primary_key;auto_increment"`
+	TxID         string      `gorm:"type:varchar(64);unique_index;not null"`
+	CurrentTxID  string      `gorm:"type:varchar(64);not null"`
+	Actions      JSON        `gorm:"type:jsonb;not null"`
+	BlockNum     uint64      `gorm:"type:numeric(20);not null"`
+	HeadBlockNum uint64      `gorm:"type:numeric(20);not null;default:0"`
+	ExpiresAt    time.Time   `gorm:"not null"`
+	Attempts     uint32      `gorm:"not null"`
+	Status       EOSTxStatus `gorm:"index;type:varchar(20);not null"`
+	Error        string      `gorm:"type:text;not null;default:''"`
+	ConfirmedAt  null.Time
+	CreatedAt    time.Time `gorm:"not null"`
+	UpdatedAt    time.Time `gorm:"not null"`
+}
+
+//NewEOSTx creates a transaction that has been signed but not pushed yet, headBlockNum is the head block when
+//it was signed, the transaction can only be included in later blocks
+func NewEOSTx(txID string, actions JSON, headBlockNum uint64, expiresAt time.Time) *EOSTx {
+	return &EOSTx{
+		TxID:         txID,
+		CurrentTxID:  txID,
+		Actions:      actions,
+		BlockNum:     headBlockNum,
+		HeadBlockNum: headBlockNum,
+		ExpiresAt:    expiresAt,
+		Attempts:     1,
+		Status:       EOSTxStatus_UNCONFIRMED,
+	}
+}
+
+//Pushed records the block the transaction was included in when it was pushed, as reported by the node
+func (m *EOSTx) Pushed(blockNum uint64) {
+	if blockNum > 0 {
+		m.BlockNum = blockNum
+	}
+}
+
+//Resign records a new attempt, the actions were signed again as txID when the head block was headBlockNum
+func (m *EOSTx) Resign(txID string, headBlockNum uint64, expiresAt time.Time) {
+	m.CurrentTxID = txID
+	m.BlockNum = headBlockNum
+	m.HeadBlockNum = headBlockNum
+	m.ExpiresAt = expiresAt
+	m.Attempts++
+}
+
+//Confirm marks the transaction as confirmed, it was included in blockNum which is irreversible
+func (m *EOSTx) Confirm(blockNum uint64) {
+	m.BlockNum = blockNum
+	m.Status = EOSTxStatus_CONFIRMED
+	m.ConfirmedAt = null.TimeFrom(time.Now())
+}
+
+//Fail marks the transaction as failed, it will not be pushed again
+func (m *EOSTx) Fail(msg string) {
+	m.Status = EOSTxStatus_FAILED
+	m.Error = msg
+}
+
+func (m *EOSTx) String() string {
+	return fmt.Sprintf("\nEOSTx{ \n\tID: %v, \n\tTxID: %v, \n\tCurrentTxID: %v, \n\tBlockNum: %v, \n\tHeadBlockNum: %v, \n\tExpiresAt: %v, \n\tAttempts: %v, \n\tStatus: %v, \n\tError: %v\n}",
+		m.ID,
+		m.TxID,
+		m.CurrentTxID,
+		m.BlockNum,
+		m.HeadBlockNum,
+		m.ExpiresAt,
+		m.Attempts,
+		m.Status,
+		m.Error,
+	)
+}
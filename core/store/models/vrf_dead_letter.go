@@ -0,0 +1,53 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+type VRFDeadLetterStatus string
+
+const (
+	VRFDeadLetterStatus_FAILED   = "Failed"
+	VRFDeadLetterStatus_REPLAY   = "Replay"
+	VRFDeadLetterStatus_REPLAYED = "Replayed"
+)
+
+//VRFDeadLetter represents a jobs table delta that could not be processed,
+//it can be replayed once the cause of the error has been fixed
+type VRFDeadLetter struct {
+	ID        uint64              `gorm:"primary_key;auto_increment"`
+	Code      string              `gorm:"type:varchar(13);not null"`
+	JobTable  string              `gorm:"type:varchar(13);not null"`
+	Scope     string              `gorm:"type:varchar(13);not null"`
+	Operation string              `gorm:"type:varchar(30);not null"`
+	ForkStep  string              `gorm:"type:varchar(30);not null"`
+	OldData   string              `gorm:"type:text"`
+	NewData   string              `gorm:"type:text"`
+	BlockNum  uint64              `gorm:"type:numeric(20);not null"`
+	BlockHash string              `gorm:"type:varchar(70);not null"`
+	Cursor    string              `gorm:"type:varchar(1000);not null"`
+	Error     string              `gorm:"type:text;not null"`
+	Status    VRFDeadLetterStatus `gorm:"index;type:varchar(20);not null"`
+	Replays   int                 `gorm:"not null"`
+	CreatedAt time.Time           `gorm:"not null"`
+	UpdatedAt time.Time           `gorm:"not null"`
+}
+
+func (m *VRFDeadLetter) String() string {
+	return fmt.Sprintf("\nVRFDeadLetter{ \n\tID: %v, \n\tCode: %v, \n\tJobTable: %v, \n\tScope: %v, \n\tOperation: %v, \n\tForkStep: %v, \n\tOldData: %v, \n\tNewData: %v, \n\tBlockNum: %v, \n\tBlockHash: %v, \n\tError: %v, \n\tStatus: %v, \n\tReplays: %v\n}",
+		m.ID,
+		m.Code,
+		m.JobTable,
+		m.Scope,
+		m.Operation,
+		m.ForkStep,
+		m.OldData,
+		m.NewData,
+		m.BlockNum,
+		m.BlockHash,
+		m.Error,
+		m.Status,
+		m.Replays,
+	)
+}
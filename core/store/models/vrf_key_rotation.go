@@ -0,0 +1,49 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	null "gopkg.in/guregu/null.v4"
+)
+
+type VRFKeyRotationStatus string
+
+const (
+	VRFKeyRotationStatus_GRACE     = "Grace"
+	VRFKeyRotationStatus_COMPLETED = "Completed"
+)
+
+//VRFKeyRotation records the rotation of the oracle's vrf key, the new key is authoritative from the moment
+//its public key is registered in the contract, CreatedAt, both keys are unlocked until the grace period ends
+//and the old key stops being authoritative when it is archived, CompletedAt
+type VRFKeyRotation struct {
+	ID           uint64               `gorm:"primary_key;auto_increment"`
+	OldKey       string               `gorm:"type:varchar(70);not null"`
+	OldKeyHash   string               `gorm:"type:varchar(70);not null"`
+	NewKey       string               `gorm:"type:varchar(70);not null"`
+	NewKeyHash   string               `gorm:"type:varchar(70);not null"`
+	RegisterTxID string               `gorm:"type:varchar(70);not null"`
+	Status       VRFKeyRotationStatus `gorm:"index;type:varchar(20);not null"`
+	GraceEndsAt  time.Time            `gorm:"not null"`
+	CompletedAt  null.Time
+	CreatedAt    time.Time `gorm:"not null"`
+	UpdatedAt    time.Time `gorm:"not null"`
+}
+
+//GraceEnded returns true if the grace period during which both keys are unlocked has ended
+func (m *VRFKeyRotation) GraceEnded(t time.Time) bool {
+	return !t.Before(m.GraceEndsAt)
+}
+
+func (m *VRFKeyRotation) String() string {
+	return fmt.Sprintf("\nVRFKeyRotation{ \n\tID: %v, \n\tOldKey: %v, \n\tNewKey: %v, \n\tRegisterTxID: %v, \n\tStatus: %v, \n\tGraceEndsAt: %v, \n\tCompletedAt: %v\n}",
+		m.ID,
+		m.OldKey,
+		m.NewKey,
+		m.RegisterTxID,
+		m.Status,
+		m.GraceEndsAt,
+		m.CompletedAt.Time,
+	)
+}
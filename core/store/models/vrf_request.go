@@ -19,17 +19,43 @@ const (
 type VRFRequestStatus string
 
 const (
+	VRFRequestStatus_PENDING   = "Pending"
 	VRFRequestStatus_ACTIVE    = "Active"
 	VRFRequestStatus_COMPLETED = "Completed"
+	VRFRequestStatus_CANCELLED = "Cancelled"
 )
 
+//VRFStrategy how the requests of a caller contract are fulfilled
+type VRFStrategy string
+
+const (
+	//With a proof that the vrf contract verifies
+	VRFStrategy_VRF VRFStrategy = "vrf"
+	//By committing secrets when the request is processed and revealing them once the commit is irreversible,
+	//for callers that can not afford verifying the proofs
+	VRFStrategy_COMMIT_REVEAL VRFStrategy = "commitreveal"
+)
+
+//VRFStrategies the valid strategies
+var VRFStrategies = []VRFStrategy{VRFStrategy_VRF, VRFStrategy_COMMIT_REVEAL}
+
+//ParseVRFStrategy returns the strategy named s or an error if it is not one of VRFStrategies
+func ParseVRFStrategy(s string) (VRFStrategy, error) {
+	for _, strategy := range VRFStrategies {
+		if string(strategy) == s {
+			return strategy, nil
+		}
+	}
+	return "", fmt.Errorf("invalid strategy: %v, valid strategies are: %v", s, VRFStrategies)
+}
+
 //VRFRequest represents a vrf request
 type VRFRequest struct {
 	ID        uint64           `gorm:"primary_key;auto_increment"`
 	AssocID   uint64           `gorm:"type:numeric(20);index;not null"`
 	BlockNum  uint64           `gorm:"type:numeric(20);not null"`
 	BlockHash string           `gorm:"type:varchar(70);not null"`
-	Seeds     pq.StringArray   `gorm:"type:varchar(70)[];not null"`
+	Seeds     pq.StringArray   `gorm:"type:varchar(80)[];not null"`
 	Frequency string           `gorm:"type:varchar(25);not null"`
 	Count     uint8            `gorm:"not null"`
 	Caller    string           `gorm:"type:varchar(15);index;not null"`
@@ -38,12 +64,54 @@ type VRFRequest struct {
 	StartAt   null.Time        `gorm:"index"`
 	EndAt     null.Time        `gorm:"index"`
 	CronID    int
-	CreatedAt time.Time `gorm:"not null"`
-	UpdatedAt time.Time `gorm:"index"`
-	DeletedAt null.Time `gorm:"index"`
+	//Number of values in [ValueMin, ValueMax] to derive from the output of each seed, 0 if none
+	ValueCount   uint16 `gorm:"not null;default:0"`
+	ValueMin     uint64 `gorm:"type:numeric(20);not null;default:0"`
+	ValueMax     uint64 `gorm:"type:numeric(20);not null;default:0"`
+	UniqueValues bool   `gorm:"not null;default:false"`
+	//Hash of the vrf key that has to generate the proofs, empty to use the oracle's default key
+	KeyHash string `gorm:"type:varchar(70);not null;default:''"`
+	//Block at which the contract removed the row of the request from the jobs table, 0 while it is there,
+	//only set by the delta handler so saving the request does not write it
+	RemovedBlockNum uint64    `gorm:"type:numeric(20);not null;default:0"`
+	CreatedAt       time.Time `gorm:"not null"`
+	UpdatedAt       time.Time `gorm:"index"`
+	DeletedAt       null.Time `gorm:"index"`
+}
+
+// Started returns true if the request has started, requests without a start
+// date are considered started
+func (m *VRFRequest) Started(t time.Time) bool {
+	if !m.StartAt.Valid {
+		return true
+	}
+	return t.After(m.StartAt.Time) || t.Equal(m.StartAt.Time)
+}
+
+// Ended returns true if the request has an end date and it has passed
+func (m *VRFRequest) Ended(t time.Time) bool {
+	if !m.EndAt.Valid {
+		return false
+	}
+	return t.After(m.EndAt.Time)
 }
 
+// Removed returns true if the row of the request was removed from the jobs table,
+// i.e. the contract already resolved it
+func (m *VRFRequest) Removed() bool {
+	return m.RemovedBlockNum > 0
+}
+
+// Expanded returns true if values have to be derived from the output of each seed
+func (m *VRFRequest) Expanded() bool {
+	return m.ValueCount > 0
+}
+
+//UpdateStatus completes immediate and scheduled requests once their job ends, cancelled requests stay cancelled
 func (m *VRFRequest) UpdateStatus(jobStatus VRFRequestJobStatus) {
+	if m.Status == VRFRequestStatus_CANCELLED {
+		return
+	}
 	switch jobStatus {
 	case VRFRequestJobStatus_COMPLETED, VRFRequestJobStatus_FAILED:
 		if m.Type == VRFRequestType_IMMEDIATE || m.Type == VRFRequestType_SCHEDULED {
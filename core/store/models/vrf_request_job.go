@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/lib/pq"
 	null "gopkg.in/guregu/null.v4"
 )
 
@@ -14,6 +15,8 @@ const (
 	VRFRequestJobStatus_ACTIVE    = "Active"
 	VRFRequestJobStatus_RUNNING   = "Running"
 	VRFRequestJobStatus_COMPLETED = "Completed"
+	//The secrets of a commit-reveal job were committed, it is revealed once the block of the commit is irreversible
+	VRFRequestJobStatus_COMMITTED = "Committed"
 )
 
 //VRFRequestJob represents a vrf request job
@@ -21,22 +24,62 @@ type VRFRequestJob struct {
 	ID           uint64 `gorm:"primary_key;auto_increment"`
 	VRFRequestID uint64
 	VRFRequest   *VRFRequest
+	BlockNum     uint64              `gorm:"type:numeric(20)"`
+	BlockHash    string              `gorm:"type:varchar(70)"`
 	StartAt      time.Time           `gorm:"index"`
 	EndAt        null.Time           `gorm:"index"`
 	Status       VRFRequestJobStatus `gorm:"index; type:varchar(20)"`
 	Retries      uint8
+	RetryAt      null.Time `gorm:"index"`
+	//Secrets one per seed, only used by the commit-reveal strategy
+	Secrets     pq.StringArray `gorm:"type:varchar(70)[]"`
+	CommittedAt null.Time
+	//CommitTxBlockNum the block the setcommit transaction was included in, the job is revealed once it is irreversible
+	CommitTxBlockNum uint64 `gorm:"type:numeric(20)"`
 }
 
 func NewVRFRequestJob(req *VRFRequest) *VRFRequestJob {
+	return NewVRFRequestJobAtBlock(req, req.BlockNum, req.BlockHash)
+}
+
+//NewVRFRequestJobAtBlock creates a job whose proofs are generated using the specified block,
+//used by recurrent and scheduled requests so that each job gets a fresh random value
+func NewVRFRequestJobAtBlock(req *VRFRequest, blockNum uint64, blockHash string) *VRFRequestJob {
 	return &VRFRequestJob{
 		VRFRequestID: req.ID,
 		VRFRequest:   req,
+		BlockNum:     blockNum,
+		BlockHash:    blockHash,
 		StartAt:      time.Now(),
 		Status:       VRFRequestJobStatus_ACTIVE,
 		Retries:      0,
 	}
 }
 
+//InProgress returns true if the job is waiting to be processed, being processed or waiting to be revealed
+func (m *VRFRequestJob) InProgress() bool {
+	switch m.Status {
+	case VRFRequestJobStatus_ACTIVE, VRFRequestJobStatus_RUNNING, VRFRequestJobStatus_COMMITTED:
+		return true
+	}
+	return false
+}
+
+//Retry returns a new job that processes this one again, at the same block so that the same proofs are generated,
+//the secrets of a committed job are kept so that they are revealed instead of committing new ones
+func (m *VRFRequestJob) Retry(req *VRFRequest) *VRFRequestJob {
+	job := NewVRFRequestJobAtBlock(req, m.BlockNum, m.BlockHash)
+	if m.CommittedAt.Valid {
+		job.Secrets = m.Secrets
+		job.CommittedAt = m.CommittedAt
+		job.CommitTxBlockNum = m.CommitTxBlockNum
+		job.Status = VRFRequestJobStatus_COMMITTED
+	} else {
+		job.RetryAt = null.TimeFrom(job.StartAt)
+	}
+	return job
+}
+
 func (m *VRFRequestJob) UpdateStatus(runStatus VRFRequestRunStatus, maxRetries uint8) {
 	switch runStatus {
 	case VRFRequestRunStatus_RUNNING:
@@ -57,13 +100,16 @@ func (m *VRFRequestJob) UpdateStatus(runStatus VRFRequestRunStatus, maxRetries u
 }
 
 func (m *VRFRequestJob) String() string {
-	return fmt.Sprintf("VRFRequestJob{ ID: %v, VRFRequestID: %v, VRFRequest: %v, StartAt: %v, EndAt: %v, Status: %v, Retries: %v",
+	return fmt.Sprintf("VRFRequestJob{ ID: %v, VRFRequestID: %v, VRFRequest: %v, BlockNum: %v, BlockHash: %v, StartAt: %v, EndAt: %v, Status: %v, Retries: %v, RetryAt: %v",
 		m.ID,
 		m.VRFRequestID,
 		m.VRFRequest,
+		m.BlockNum,
+		m.BlockHash,
 		m.StartAt,
 		m.EndAt,
 		m.Status,
 		m.Retries,
+		m.RetryAt,
 	)
 }
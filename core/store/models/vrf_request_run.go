@@ -13,6 +13,7 @@ const (
 	VRFRequestRunStatus_FAILED    = "Failed"
 	VRFRequestRunStatus_RUNNING   = "Running"
 	VRFRequestRunStatus_COMPLETED = "Completed"
+	VRFRequestRunStatus_CONFIRMED = "Confirmed"
 )
 
 //VRFRequestRun represents a vrf request run
@@ -24,6 +25,14 @@ type VRFRequestRun struct {
 	EndAt           null.Time           `gorm:"index"`
 	Status          VRFRequestRunStatus `gorm:"index; type:varchar(20)"`
 	StatusMsg       string              `gorm:"index; type:text"`
+	//Transaction that fulfilled the job and the block it was included in when it was pushed, empty if the
+	//run did not push a transaction
+	TxID       string `gorm:"type:varchar(64);index;not null;default:''"`
+	TxBlockNum uint64 `gorm:"type:numeric(20);not null;default:0"`
+	//Proofs pushed in the transaction and hash of the key that generated them
+	Proofs      *JSON  `gorm:"type:jsonb"`
+	KeyHash     string `gorm:"type:varchar(70);not null;default:''"`
+	ConfirmedAt null.Time
 }
 
 func NewVRFRequestRun(vrfRequestJob *VRFRequestJob) *VRFRequestRun {
@@ -43,6 +52,52 @@ func (m *VRFRequestRun) UpdateStatus(status VRFRequestRunStatus, msg string, max
 	m.VRFRequestJob.UpdateStatus(status, maxRetries)
 }
 
+//Commit completes the run that committed the secrets of a commit-reveal job, the job stays committed
+//until it is revealed
+func (m *VRFRequestRun) Commit(msg string) {
+	m.EndAt = null.NewTime(time.Now(), true)
+	m.Status = VRFRequestRunStatus_COMPLETED
+	m.StatusMsg = msg
+	m.VRFRequestJob.Status = VRFRequestJobStatus_COMMITTED
+}
+
+//SetTx records the transaction that fulfilled the job
+func (m *VRFRequestRun) SetTx(txID string, blockNum uint64) {
+	m.TxID = txID
+	m.TxBlockNum = blockNum
+}
+
+//Confirm marks the run as confirmed once its transaction is irreversible, it was included in blockNum. A
+//commit that was pushed again after a microfork may have been included in a later block than the first push
+func (m *VRFRequestRun) Confirm(blockNum uint64) {
+	m.TxBlockNum = blockNum
+	if job := m.VRFRequestJob; job != nil && job.Status == VRFRequestJobStatus_COMMITTED {
+		job.CommitTxBlockNum = blockNum
+	}
+	m.Status = VRFRequestRunStatus_CONFIRMED
+	m.ConfirmedAt = null.TimeFrom(time.Now())
+}
+
+//Drop marks the run as failed because its transaction was dropped and reactivates the job so that it is
+//processed again, a dropped commit has to be committed again before revealing. Requests that are not
+//recurrent are reactivated as well
+func (m *VRFRequestRun) Drop(msg string) {
+	m.Status = VRFRequestRunStatus_FAILED
+	m.StatusMsg = msg
+	job := m.VRFRequestJob
+	if job.Status == VRFRequestJobStatus_COMMITTED {
+		job.CommittedAt = null.Time{}
+		job.CommitTxBlockNum = 0
+	}
+	job.Status = VRFRequestJobStatus_ACTIVE
+	job.EndAt = null.Time{}
+	job.RetryAt = null.TimeFrom(time.Now())
+	req := job.VRFRequest
+	if req.Type != VRFRequestType_RECURRENT && req.Status == VRFRequestStatus_COMPLETED {
+		req.Status = VRFRequestStatus_ACTIVE
+	}
+}
+
 func (m *VRFRequestRun) String() string {
 	return fmt.Sprintf("VRFRequestRun{ ID: %v, VRFRequestJobID: %v, VRFRequestJob: %v, StartAt: %v, EndAt: %v, Status: %v, StatusMsg: %v",
 		m.ID,
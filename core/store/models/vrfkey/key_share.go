@@ -0,0 +1,230 @@
+package vrfkey
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/share"
+
+	"github.com/sebastianmontero/vrf-oracle/core/services/vrf"
+	"github.com/sebastianmontero/vrf-oracle/core/utils"
+)
+
+// KeyShare is the share Index of a threshold VRF group key, see vrf/threshold.go.
+//
+// Like PrivateKey, don't serialize it directly, use Encrypt. The commitments to
+// the polynomial the group key was shared with are public, they determine the
+// group public key and the public key of every share.
+type KeyShare struct {
+	k           kyber.Scalar
+	Index       int
+	Nodes       int
+	PublicKey   PublicKey
+	Commitments Commitments
+}
+
+// GenerateKeyShares creates a group key and splits it among nodes shares, any
+// threshold of which produce the VRF output of the group key. The group secret
+// is dropped once the shares are generated, so the caller has to make sure the
+// shares are distributed to different nodes.
+func GenerateKeyShares(threshold, nodes int) ([]*KeyShare, error) {
+	if threshold < 1 || threshold > nodes {
+		return nil, fmt.Errorf("threshold must be between 1 and the number of nodes: %v, got %v", nodes, threshold)
+	}
+	secret := suite.Scalar().Pick(suite.RandomStream())
+	priPoly := share.NewPriPoly(suite, threshold, secret, suite.RandomStream())
+	_, commits := priPoly.Commit(nil).Info()
+	commitments := make(Commitments, 0, len(commits))
+	for _, commit := range commits {
+		commitments = append(commitments, publicKeyFromPoint(commit))
+	}
+	shares := make([]*KeyShare, 0, nodes)
+	for _, priShare := range priPoly.Shares(nodes) {
+		shares = append(shares, &KeyShare{
+			k:           priShare.V,
+			Index:       priShare.I,
+			Nodes:       nodes,
+			PublicKey:   publicKeyFromPoint(suite.Point().Mul(priShare.V, nil)),
+			Commitments: commitments,
+		})
+	}
+	return shares, nil
+}
+
+// GroupPublicKey is the public key of the group key the share belongs to
+func (k *KeyShare) GroupPublicKey() PublicKey {
+	return k.Commitments.GroupPublicKey()
+}
+
+// Threshold is the number of shares needed to produce the VRF output
+func (k *KeyShare) Threshold() int {
+	return len(k.Commitments)
+}
+
+// PartialProof is the partial proof of the share for the seed computed from i
+func (k *KeyShare) PartialProof(i vrf.PreSeedData) (*vrf.PartialProof, error) {
+	pubPoly, err := k.Commitments.PubPoly()
+	if err != nil {
+		return nil, err
+	}
+	return vrf.GeneratePartialProof(pubPoly.Commit(), &share.PriShare{I: k.Index, V: k.k}, vrf.FinalSeed(i))
+}
+
+// Encrypt returns the share encrypted with passphrase auth, the same way a VRF
+// key is encrypted
+func (k *KeyShare) Encrypt(auth string, scryptParams utils.ScryptParams) (*EncryptedKeyShare, error) {
+	encrypted, err := (&PrivateKey{k: k.k, PublicKey: k.PublicKey}).Encrypt(auth, scryptParams)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not encrypt key share")
+	}
+	return &EncryptedKeyShare{
+		GroupPublicKey: k.GroupPublicKey(),
+		Index:          k.Index,
+		Nodes:          k.Nodes,
+		PublicKey:      k.PublicKey,
+		Commitments:    k.Commitments,
+		VRFKey:         encrypted.VRFKey,
+	}, nil
+}
+
+// String reduces the risk of accidentally logging the key share
+func (k *KeyShare) String() string {
+	return fmt.Sprintf("KeyShare{k: <redacted>, Index: %v, Nodes: %v, PublicKey: %s, GroupPublicKey: %s}",
+		k.Index, k.Nodes, k.PublicKey, k.GroupPublicKey())
+}
+
+// GoStringer reduces the risk of accidentally logging the key share
+func (k *KeyShare) GoStringer() string {
+	return k.String()
+}
+
+// EncryptedKeyShare contains an encrypted key share to be serialized to DB,
+// a node holds one share of each group key
+type EncryptedKeyShare struct {
+	GroupPublicKey PublicKey     `json:"group_public_key" gorm:"primary_key"`
+	Index          int           `json:"index"`
+	Nodes          int           `json:"nodes"`
+	PublicKey      PublicKey     `json:"public_key"`
+	Commitments    Commitments   `json:"commitments"`
+	VRFKey         gethKeyStruct `json:"vrf_key"`
+	CreatedAt      time.Time     `json:"-"`
+	UpdatedAt      time.Time     `json:"-"`
+}
+
+// TableName of the encrypted key shares
+func (EncryptedKeyShare) TableName() string {
+	return "encrypted_vrf_key_shares"
+}
+
+// Decrypt returns the KeyShare in e, decrypted via auth, or an error. The public
+// key of the share has to match the one the commitments determine for its index
+func (e *EncryptedKeyShare) Decrypt(auth string) (*KeyShare, error) {
+	key, err := (&EncryptedVRFKey{PublicKey: e.PublicKey, VRFKey: e.VRFKey}).Decrypt(auth)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not decrypt share %v of group key %s", e.Index, e.GroupPublicKey)
+	}
+	pubPoly, err := e.Commitments.PubPoly()
+	if err != nil {
+		return nil, err
+	}
+	if publicKeyFromPoint(pubPoly.Eval(e.Index).V) != key.PublicKey {
+		return nil, fmt.Errorf("public key %s is not share %v of group key %s", key.PublicKey, e.Index, e.GroupPublicKey)
+	}
+	if e.Commitments.GroupPublicKey() != e.GroupPublicKey {
+		return nil, fmt.Errorf("commitments are not those of group key %s", e.GroupPublicKey)
+	}
+	return &KeyShare{
+		k:           key.k,
+		Index:       e.Index,
+		Nodes:       e.Nodes,
+		PublicKey:   key.PublicKey,
+		Commitments: e.Commitments,
+	}, nil
+}
+
+// JSON returns the JSON representation of e, or errors
+func (e *EncryptedKeyShare) JSON() ([]byte, error) {
+	keyJSON, err := json.Marshal(e)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not marshal encrypted key share to JSON")
+	}
+	return keyJSON, nil
+}
+
+// WriteToDisk writes the JSON representation of e to given file path, and
+// ensures the file has appropriate access permissions
+func (e *EncryptedKeyShare) WriteToDisk(path string) error {
+	keyJSON, err := e.JSON()
+	if err != nil {
+		return errors.Wrapf(err, "while marshaling key share to save to %s", path)
+	}
+	userReadWriteOtherNoAccess := os.FileMode(0600)
+	return utils.WriteFileWithMaxPerms(path, keyJSON, userReadWriteOtherNoAccess)
+}
+
+// Commitments to the coefficients of the polynomial a group key was shared
+// with, the first one is the group public key
+type Commitments []PublicKey
+
+// GroupPublicKey is the commitment to the group secret
+func (c Commitments) GroupPublicKey() PublicKey {
+	if len(c) == 0 {
+		return PublicKey{}
+	}
+	return c[0]
+}
+
+// PubPoly returns the public polynomial, which verifies the partial proofs of
+// the shares
+func (c Commitments) PubPoly() (*share.PubPoly, error) {
+	if len(c) == 0 {
+		return nil, fmt.Errorf("no commitments")
+	}
+	commits := make([]kyber.Point, 0, len(c))
+	for _, commitment := range c {
+		point, err := commitment.Point()
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid commitment %s", commitment)
+		}
+		commits = append(commits, point)
+	}
+	return share.NewPubPoly(suite, nil, commits), nil
+}
+
+// Value marshals Commitments to be saved in the DB
+func (c Commitments) Value() (driver.Value, error) {
+	return json.Marshal([]PublicKey(c))
+}
+
+// Scan reconstructs Commitments from a DB record of them
+func (c *Commitments) Scan(value interface{}) error {
+	var toUnmarshal []byte
+	switch s := value.(type) {
+	case []byte:
+		toUnmarshal = s
+	case string:
+		toUnmarshal = []byte(s)
+	default:
+		return errors.Wrap(
+			fmt.Errorf("unable to convert %+v of type %T to Commitments",
+				value, value), "scan failure")
+	}
+	return json.Unmarshal(toUnmarshal, (*[]PublicKey)(c))
+}
+
+func publicKeyFromPoint(p kyber.Point) PublicKey {
+	raw, err := p.MarshalBinary()
+	if err != nil {
+		panic(errors.Wrapf(err, "could not marshal public key"))
+	}
+	var k PublicKey
+	if l := copy(k[:], raw); l != CompressedPublicKeyLength {
+		panic(fmt.Errorf("public key %x has wrong length", raw))
+	}
+	return k
+}
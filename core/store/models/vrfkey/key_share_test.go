@@ -0,0 +1,71 @@
+package vrfkey
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sebastianmontero/vrf-oracle/core/services/vrf"
+	"github.com/sebastianmontero/vrf-oracle/core/utils"
+)
+
+func TestKeyShare_PartialProofsCombineToTheGroupOutput(t *testing.T) {
+	shares, err := GenerateKeyShares(3, 5)
+	require.NoError(t, err)
+	require.Len(t, shares, 5)
+	pubPoly, err := shares[0].Commitments.PubPoly()
+	require.NoError(t, err)
+
+	seed, err := vrf.BigToSeed(big.NewInt(42))
+	require.NoError(t, err)
+	preSeedData := vrf.PreSeedData{PreSeed: seed, BlockHash: common.HexToHash("0x0102"), BlockNum: 10}
+	partials := make([]*vrf.PartialProof, 0, len(shares))
+	for _, keyShare := range shares {
+		assert.Equal(t, shares[0].GroupPublicKey(), keyShare.GroupPublicKey())
+		assert.Equal(t, 3, keyShare.Threshold())
+		partial, err := keyShare.PartialProof(preSeedData)
+		require.NoError(t, err)
+		require.NoError(t, partial.Verify(pubPoly))
+		partials = append(partials, partial)
+	}
+	first, err := vrf.CombinePartialProofs(pubPoly, vrf.FinalSeed(preSeedData), partials[:3])
+	require.NoError(t, err)
+	second, err := vrf.CombinePartialProofs(pubPoly, vrf.FinalSeed(preSeedData), partials[2:])
+	require.NoError(t, err)
+	assert.Equal(t, first.Output, second.Output)
+
+	_, err = GenerateKeyShares(4, 3)
+	assert.Error(t, err)
+}
+
+func TestKeyShare_EncryptDecryptRoundTrip(t *testing.T) {
+	shares, err := GenerateKeyShares(2, 3)
+	require.NoError(t, err)
+	encrypted, err := shares[1].Encrypt(phrase, utils.FastScryptParams)
+	require.NoError(t, err)
+	assert.Equal(t, shares[1].GroupPublicKey(), encrypted.GroupPublicKey)
+
+	decrypted, err := encrypted.Decrypt(phrase)
+	require.NoError(t, err)
+	assert.Equal(t, shares[1].PublicKey, decrypted.PublicKey)
+	assert.Equal(t, shares[1].Index, decrypted.Index)
+	assert.Equal(t, shares[1].Nodes, decrypted.Nodes)
+	assert.True(t, shares[1].k.Equal(decrypted.k))
+
+	_, err = encrypted.Decrypt("wrong password")
+	assert.Error(t, err)
+
+	//A share does not decrypt as another share of the group key
+	encrypted.Index = 0
+	_, err = encrypted.Decrypt(phrase)
+	assert.Error(t, err)
+
+	serialized, err := shares[1].Commitments.Value()
+	require.NoError(t, err)
+	var commitments Commitments
+	require.NoError(t, commitments.Scan(serialized))
+	assert.Equal(t, shares[1].Commitments, commitments)
+}
@@ -13,6 +13,7 @@ import (
 	"reflect"
 	"regexp"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/multiformats/go-multiaddr"
@@ -155,6 +156,16 @@ func (c *Config) Validate() error {
 			return errors.Wrapf(err, "invalid monitoring url: %s", me)
 		}
 	}
+	strategies, err := c.VRFCallerStrategies()
+	if err != nil {
+		return errors.Wrap(err, "invalid VRF_CALLER_STRATEGIES")
+	}
+	for caller, strategy := range strategies {
+		//The secrets have to be committed before the block of the request is irreversible
+		if strategy == models.VRFStrategy_COMMIT_REVEAL && c.VRFWaitIrreversible() {
+			return errors.Errorf("caller %v uses the %v strategy, which can not be used with VRF_WAIT_IRREVERSIBLE", caller, strategy)
+		}
+	}
 	return nil
 }
 
@@ -959,6 +970,98 @@ func (c Config) VRFContractKey() string {
 	return c.viper.GetString(EnvVarName("VRFContractKey"))
 }
 
+// VRFFulfilmentActor account that pushes the setrand actions, defaults to the vrf contract
+func (c Config) VRFFulfilmentActor() string {
+	actor := c.viper.GetString(EnvVarName("VRFFulfilmentActor"))
+	if actor == "" {
+		return c.VRFContract()
+	}
+	return actor
+}
+
+// VRFFulfilmentPermission permission of the fulfilment actor used to push the setrand actions
+func (c Config) VRFFulfilmentPermission() string {
+	return c.viper.GetString(EnvVarName("VRFFulfilmentPermission"))
+}
+
+// VRFFulfilmentKeys comma separated keys of the fulfilment permission, they are used in turns to sign the setrand transactions
+func (c Config) VRFFulfilmentKeys() []string {
+	keys := []string{}
+	for _, key := range strings.Split(c.viper.GetString(EnvVarName("VRFFulfilmentKeys")), ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// VRFSeedType abi type of the seed of the proofs in the setrand action: uint64, uint128 or checksum256
+func (c Config) VRFSeedType() string {
+	return c.viper.GetString(EnvVarName("VRFSeedType"))
+}
+
+// VRFDefaultKey public key of the vrf key used for requests that do not select a key by
+// its hash, if not set the new key of the last key rotation or the first unlocked key is used.
+// The pinned key can not be rotated
+func (c Config) VRFDefaultKey() string {
+	return c.viper.GetString(EnvVarName("VRFDefaultKey"))
+}
+
+// VRFQuorum number of oracles whose proofs the contract combines for each request, 0 if a single oracle
+// fulfils the requests. In quorum mode every oracle contributes with its default key
+func (c Config) VRFQuorum() uint8 {
+	return c.getWithFallback("VRFQuorum", parseUint8).(uint8)
+}
+
+// VRFCallerStrategies strategy of each caller contract, from the comma separated caller:strategy pairs, the
+// requests of the callers not listed are fulfilled with the vrf strategy
+func (c Config) VRFCallerStrategies() (map[string]models.VRFStrategy, error) {
+	strategies := map[string]models.VRFStrategy{}
+	for _, pair := range strings.Split(c.viper.GetString(EnvVarName("VRFCallerStrategies")), ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+		parts := strings.Split(pair, ":")
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+			return nil, errors.Errorf("invalid caller strategy: %v, expected caller:strategy", pair)
+		}
+		strategy, err := models.ParseVRFStrategy(strings.TrimSpace(parts[1]))
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid strategy for caller %v", parts[0])
+		}
+		strategies[strings.TrimSpace(parts[0])] = strategy
+	}
+	return strategies, nil
+}
+
+// VRFStrategy how the requests of caller are fulfilled, see VRFCallerStrategies
+func (c Config) VRFStrategy(caller string) models.VRFStrategy {
+	strategies, err := c.VRFCallerStrategies()
+	if err != nil {
+		logger.Errorf("Invalid VRF_CALLER_STRATEGIES, using the vrf strategy: %v", err)
+		return models.VRFStrategy_VRF
+	}
+	if strategy, ok := strategies[caller]; ok {
+		return strategy
+	}
+	return models.VRFStrategy_VRF
+}
+
+// VRFRevealInterval how often the commit-reveal strategy checks for committed jobs whose commit became irreversible
+func (c Config) VRFRevealInterval() time.Duration {
+	return c.getWithFallback("VRFRevealInterval", parseDuration).(time.Duration)
+}
+
+// VRFConfirmInterval how often the vrf oracle checks whether the fulfilment transactions have become irreversible
+func (c Config) VRFConfirmInterval() time.Duration {
+	return c.getWithFallback("VRFConfirmInterval", parseDuration).(time.Duration)
+}
+
+// VRFKeyReloadInterval how often the vrf oracle checks the db for keys created or archived by a key rotation
+func (c Config) VRFKeyReloadInterval() time.Duration {
+	return c.getWithFallback("VRFKeyReloadInterval", parseDuration).(time.Duration)
+}
+
 // VRFKeyStorePassword key of the vrf contract
 func (c Config) VRFKeyStorePassword() string {
 	return c.viper.GetString(EnvVarName("VRFKeyStorePassword"))
@@ -969,6 +1072,160 @@ func (c Config) VRFHeartBeatFrequency() uint32 {
 	return c.getWithFallback("VRFHeartBeatFrequency", parseUint32).(uint32)
 }
 
+// VRFWaitIrreversible if true vrf requests are only fulfilled once the block in which they were
+// requested becomes irreversible, requests in blocks that get forked out are cancelled
+func (c Config) VRFWaitIrreversible() bool {
+	return c.viper.GetBool(EnvVarName("VRFWaitIrreversible"))
+}
+
+// VRFRetryInterval frequency with which failed vrf request jobs are checked for retries
+func (c Config) VRFRetryInterval() time.Duration {
+	return c.getWithFallback("VRFRetryInterval", parseDuration).(time.Duration)
+}
+
+// VRFRetryMinBackoff delay before the first retry of a failed vrf request job, doubles on every retry
+func (c Config) VRFRetryMinBackoff() time.Duration {
+	return c.getWithFallback("VRFRetryMinBackoff", parseDuration).(time.Duration)
+}
+
+// VRFRetryMaxBackoff maximum delay between retries of a failed vrf request job
+func (c Config) VRFRetryMaxBackoff() time.Duration {
+	return c.getWithFallback("VRFRetryMaxBackoff", parseDuration).(time.Duration)
+}
+
+// VRFDeadLetterReplayInterval frequency with which the oracle checks for dead letters marked for replay
+func (c Config) VRFDeadLetterReplayInterval() time.Duration {
+	return c.getWithFallback("VRFDeadLetterReplayInterval", parseDuration).(time.Duration)
+}
+
+// VRFWorkers number of workers that process vrf requests concurrently
+func (c Config) VRFWorkers() uint16 {
+	return c.getWithFallback("VRFWorkers", parseUint16).(uint16)
+}
+
+// VRFOrderPerCaller if true the vrf requests of a caller are processed in order, one at a time
+func (c Config) VRFOrderPerCaller() bool {
+	return c.viper.GetBool(EnvVarName("VRFOrderPerCaller"))
+}
+
+// VRFMetricsPort port on which the vrf oracle exposes its prometheus metrics, 0 disables them
+func (c Config) VRFMetricsPort() uint16 {
+	return c.getWithFallback("VRFMetricsPort", parseUint16).(uint16)
+}
+
+// VRFAPIPort port on which the vrf oracle serves the vrf requests API, 0 disables it
+func (c Config) VRFAPIPort() uint16 {
+	return c.getWithFallback("VRFAPIPort", parseUint16).(uint16)
+}
+
+// VRFBatchSize maximum number of setrand actions pushed in one transaction, 1 disables batching
+func (c Config) VRFBatchSize() uint16 {
+	return c.getWithFallback("VRFBatchSize", parseUint16).(uint16)
+}
+
+// VRFBatchWindow time to wait for more vrf requests to fulfil before pushing a batch
+func (c Config) VRFBatchWindow() time.Duration {
+	return c.getWithFallback("VRFBatchWindow", parseDuration).(time.Duration)
+}
+
+// VRFBatchMaxBytes maximum packed size of the setrand actions pushed in one transaction
+func (c Config) VRFBatchMaxBytes() uint32 {
+	return c.getWithFallback("VRFBatchMaxBytes", parseUint32).(uint32)
+}
+
+// VRFResourceMonitorInterval how often the CPU, NET and RAM of the vrf fulfilment account are checked
+func (c Config) VRFResourceMonitorInterval() time.Duration {
+	return c.getWithFallback("VRFResourceMonitorInterval", parseDuration).(time.Duration)
+}
+
+// VRFResourceWarnPercent usage percentage of a resource of the vrf fulfilment account over which a warning is logged
+func (c Config) VRFResourceWarnPercent() uint8 {
+	return c.getWithFallback("VRFResourceWarnPercent", parseUint8).(uint8)
+}
+
+// VRFResourcePausePercent usage percentage of a resource of the vrf fulfilment account over which fulfilment is paused, 0 disables pausing
+func (c Config) VRFResourcePausePercent() uint8 {
+	return c.getWithFallback("VRFResourcePausePercent", parseUint8).(uint8)
+}
+
+// VRFThresholdGroupKey compressed public key of the threshold vrf group key whose share the oracle holds, if set
+// requests are fulfilled in threshold mode, with partial proofs of the share combined with those of the other oracles
+func (c Config) VRFThresholdGroupKey() string {
+	return c.viper.GetString(EnvVarName("VRFThresholdGroupKey"))
+}
+
+// VRFThresholdPeerIDs comma separated p2p peer ids of the oracles of the threshold vrf group, in the order of the
+// index of their shares
+func (c Config) VRFThresholdPeerIDs() []string {
+	peerIDs := []string{}
+	for _, peerID := range strings.Split(c.viper.GetString(EnvVarName("VRFThresholdPeerIDs")), ",") {
+		if peerID = strings.TrimSpace(peerID); peerID != "" {
+			peerIDs = append(peerIDs, peerID)
+		}
+	}
+	return peerIDs
+}
+
+// VRFThresholdTimeout time the oracle that aggregates a request waits for the partial proofs of the other oracles
+func (c Config) VRFThresholdTimeout() time.Duration {
+	return c.getWithFallback("VRFThresholdTimeout", parseDuration).(time.Duration)
+}
+
+// EOSRentMode how resources are rented when the vrf fulfilment account runs out of them, powerup or rex, empty disables renting
+func (c Config) EOSRentMode() string {
+	return c.viper.GetString(EnvVarName("EOSRentMode"))
+}
+
+// EOSRentPayer account that pays for the resources rented
+func (c Config) EOSRentPayer() string {
+	return c.viper.GetString(EnvVarName("EOSRentPayer"))
+}
+
+// EOSRentPayerKey private key of the account that pays for the resources rented
+func (c Config) EOSRentPayerKey() string {
+	return c.viper.GetString(EnvVarName("EOSRentPayerKey"))
+}
+
+// EOSRentPayment amount paid for each rental, for powerup it is the max payment
+func (c Config) EOSRentPayment() string {
+	return c.viper.GetString(EnvVarName("EOSRentPayment"))
+}
+
+// EOSRentDailyBudget maximum amount that can be spent renting resources each day
+func (c Config) EOSRentDailyBudget() string {
+	return c.viper.GetString(EnvVarName("EOSRentDailyBudget"))
+}
+
+// EOSPowerupDays number of days of each powerup, must match the days configured in the chain
+func (c Config) EOSPowerupDays() uint32 {
+	return c.getWithFallback("EOSPowerupDays", parseUint32).(uint32)
+}
+
+// EOSPowerupCPUFrac fraction of the CPU to powerup, 10^15 is 100%
+func (c Config) EOSPowerupCPUFrac() uint64 {
+	return c.getWithFallback("EOSPowerupCPUFrac", parseUint64).(uint64)
+}
+
+// EOSPowerupNetFrac fraction of the NET to powerup, 10^15 is 100%
+func (c Config) EOSPowerupNetFrac() uint64 {
+	return c.getWithFallback("EOSPowerupNetFrac", parseUint64).(uint64)
+}
+
+// EOSTxCheckInterval how often the pushed transactions are checked for inclusion in an irreversible block
+func (c Config) EOSTxCheckInterval() time.Duration {
+	return c.getWithFallback("EOSTxCheckInterval", parseDuration).(time.Duration)
+}
+
+// EOSTxExpiration how long after being signed a transaction expires if it has not been included in a block
+func (c Config) EOSTxExpiration() time.Duration {
+	return c.getWithFallback("EOSTxExpiration", parseDuration).(time.Duration)
+}
+
+// EOSTxMaxAttempts maximum number of times a transaction is signed and pushed before giving up on it
+func (c Config) EOSTxMaxAttempts() uint32 {
+	return c.getWithFallback("EOSTxMaxAttempts", parseUint32).(uint32)
+}
+
 func (c Config) getWithFallback(name string, parser func(string) (interface{}, error)) interface{} {
 	str := c.viper.GetString(EnvVarName(name))
 	defaultValue, hasDefault := defaultValue(name)
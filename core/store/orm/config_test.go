@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/sebastianmontero/vrf-oracle/core/assets"
+	"github.com/sebastianmontero/vrf-oracle/core/store/models"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/spf13/viper"
@@ -30,6 +31,29 @@ func TestStore_ConfigDefaults(t *testing.T) {
 	assert.Equal(t, 15*time.Minute, config.SessionTimeout().Duration())
 }
 
+func TestConfig_VRFCallerStrategies(t *testing.T) {
+	config := NewConfig()
+	require.NoError(t, config.Validate())
+	assert.Equal(t, models.VRFStrategy_VRF, config.VRFStrategy("caller1"))
+
+	config.Set("VRF_CALLER_STRATEGIES", "caller1:commitreveal, caller2:vrf")
+	require.NoError(t, config.Validate())
+	assert.Equal(t, models.VRFStrategy_COMMIT_REVEAL, config.VRFStrategy("caller1"))
+	assert.Equal(t, models.VRFStrategy_VRF, config.VRFStrategy("caller2"))
+	assert.Equal(t, models.VRFStrategy_VRF, config.VRFStrategy("caller3"))
+
+	config.Set("VRF_WAIT_IRREVERSIBLE", true)
+	assert.EqualError(t, config.Validate(), "caller caller1 uses the commitreveal strategy, which can not be used with VRF_WAIT_IRREVERSIBLE")
+	config.Set("VRF_WAIT_IRREVERSIBLE", false)
+
+	config.Set("VRF_CALLER_STRATEGIES", "caller1:commit-reveal")
+	assert.EqualError(t, config.Validate(), "invalid VRF_CALLER_STRATEGIES: invalid strategy for caller caller1: invalid strategy: commit-reveal, valid strategies are: [vrf commitreveal]")
+	assert.Equal(t, models.VRFStrategy_VRF, config.VRFStrategy("caller1"))
+
+	config.Set("VRF_CALLER_STRATEGIES", "commitreveal")
+	assert.Error(t, config.Validate())
+}
+
 func TestConfig_sessionSecret(t *testing.T) {
 	t.Parallel()
 	config := NewConfig()
@@ -0,0 +1,67 @@
+package orm
+
+import (
+	"time"
+
+	"github.com/sebastianmontero/vrf-oracle/core/store/models"
+)
+
+// CreateEOSRental records a rental of resources
+func (orm *ORM) CreateEOSRental(rental *models.EOSRental) error {
+	if err := orm.MustEnsureAdvisoryLock(); err != nil {
+		return err
+	}
+	return orm.DB.Create(rental).Error
+}
+
+// SumEOSRentalPayments returns the amount the payer paid in symbol for the rentals made since the specified time
+func (orm *ORM) SumEOSRentalPayments(payer, symbol string, since time.Time) (int64, error) {
+	var result struct {
+		Total int64
+	}
+	if err := orm.MustEnsureAdvisoryLock(); err != nil {
+		return 0, err
+	}
+	err := orm.DB.
+		Raw("SELECT COALESCE(SUM(amount), 0) AS total FROM eos_rentals WHERE payer = ? AND symbol = ? AND created_at >= ?", payer, symbol, since).
+		Scan(&result).Error
+	return result.Total, err
+}
+
+// SaveEOSTx saves an EOSTx
+func (orm *ORM) SaveEOSTx(tx *models.EOSTx) error {
+	if err := orm.MustEnsureAdvisoryLock(); err != nil {
+		return err
+	}
+	return orm.DB.Save(tx).Error
+}
+
+// FindUnconfirmedEOSTxs returns the unconfirmed EOSTxs whose last attempt was pushed at or below maxBlockNum,
+// oldest first
+func (orm *ORM) FindUnconfirmedEOSTxs(maxBlockNum uint64) ([]*models.EOSTx, error) {
+	var txs []*models.EOSTx
+	if err := orm.MustEnsureAdvisoryLock(); err != nil {
+		return txs, err
+	}
+	err := orm.DB.
+		Where("status = ? AND block_num <= ?", models.EOSTxStatus_UNCONFIRMED, maxBlockNum).
+		Order("id asc").
+		Find(&txs).Error
+	return txs, err
+}
+
+// FindEOSTxs returns the EOSTxs with the specified ids, the ones returned to the caller when they were first pushed
+func (orm *ORM) FindEOSTxs(txIDs ...string) ([]*models.EOSTx, error) {
+	var txs []*models.EOSTx
+	if err := orm.MustEnsureAdvisoryLock(); err != nil {
+		return txs, err
+	}
+	if len(txIDs) == 0 {
+		return txs, nil
+	}
+	err := orm.DB.
+		Where("tx_id IN (?)", txIDs).
+		Order("id asc").
+		Find(&txs).Error
+	return txs, err
+}
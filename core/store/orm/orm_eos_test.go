@@ -0,0 +1,108 @@
+package orm_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sebastianmontero/vrf-oracle/core/internal/cltest"
+	"github.com/sebastianmontero/vrf-oracle/core/store/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestORM_EOSRentals(t *testing.T) {
+	t.Parallel()
+	store, cleanup := cltest.NewStore(t)
+	defer cleanup()
+
+	newRental := func(payer, symbol string, amount int64) *models.EOSRental {
+		rental := &models.EOSRental{
+			Payer:    payer,
+			Receiver: "vrforacle",
+			Resource: "cpu",
+			Mode:     "powerup",
+			TxID:     "6c5d8c4fc1a9e4de0f23a7a2e0fd3fe6b0aa9e5e0c4a1d1a6bd8c59d0d4e2d7a",
+			Amount:   amount,
+			Symbol:   symbol,
+		}
+		require.NoError(t, store.CreateEOSRental(rental))
+		return rental
+	}
+	since := time.Now().Add(-time.Minute)
+	total, err := store.SumEOSRentalPayments("payer1", "TLOS", since)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), total)
+
+	newRental("payer1", "TLOS", 10000)
+	newRental("payer1", "TLOS", 5000)
+	newRental("payer1", "EOS", 7000)
+	newRental("payer2", "TLOS", 3000)
+	total, err = store.SumEOSRentalPayments("payer1", "TLOS", since)
+	require.NoError(t, err)
+	assert.Equal(t, int64(15000), total)
+	total, err = store.SumEOSRentalPayments("payer1", "TLOS", time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), total)
+}
+
+func TestORM_EOSTxs(t *testing.T) {
+	t.Parallel()
+	store, cleanup := cltest.NewStore(t)
+	defer cleanup()
+
+	actions := cltest.JSONFromString(t, `[{"account":"vrforacle","name":"setrand","authorization":[{"actor":"vrforacle","permission":"active"}],"data":"0100000000000000"}]`)
+	expiresAt := time.Now().Add(30 * time.Second)
+	tx1 := models.NewEOSTx("6c5d8c4fc1a9e4de0f23a7a2e0fd3fe6b0aa9e5e0c4a1d1a6bd8c59d0d4e2d7a", actions, 100, expiresAt)
+	require.NoError(t, store.SaveEOSTx(tx1))
+	tx2 := models.NewEOSTx("7c5d8c4fc1a9e4de0f23a7a2e0fd3fe6b0aa9e5e0c4a1d1a6bd8c59d0d4e2d7a", actions, 200, expiresAt)
+	require.NoError(t, store.SaveEOSTx(tx2))
+	tx3 := models.NewEOSTx("8c5d8c4fc1a9e4de0f23a7a2e0fd3fe6b0aa9e5e0c4a1d1a6bd8c59d0d4e2d7a", actions, 100, expiresAt)
+	tx3.Fail("assertion failure with message: request not found")
+	require.NoError(t, store.SaveEOSTx(tx3))
+
+	txs, err := store.FindUnconfirmedEOSTxs(150)
+	require.NoError(t, err)
+	require.Len(t, txs, 1)
+	assert.Equal(t, tx1.ID, txs[0].ID)
+	assert.Equal(t, tx1.TxID, txs[0].CurrentTxID)
+	assert.Equal(t, uint32(1), txs[0].Attempts)
+	assert.Equal(t, "vrforacle", txs[0].Actions.Get("0.account").String())
+
+	tx1.Resign("9c5d8c4fc1a9e4de0f23a7a2e0fd3fe6b0aa9e5e0c4a1d1a6bd8c59d0d4e2d7a", 180, expiresAt.Add(time.Minute))
+	require.NoError(t, store.SaveEOSTx(tx1))
+	txs, err = store.FindUnconfirmedEOSTxs(150)
+	require.NoError(t, err)
+	assert.Empty(t, txs)
+
+	tx1.Confirm(181)
+	require.NoError(t, store.SaveEOSTx(tx1))
+	//The reported block is the one checked first, the head block it was signed at is kept
+	tx2.Pushed(230)
+	require.NoError(t, store.SaveEOSTx(tx2))
+	txs, err = store.FindUnconfirmedEOSTxs(220)
+	require.NoError(t, err)
+	assert.Empty(t, txs)
+	txs, err = store.FindUnconfirmedEOSTxs(250)
+	require.NoError(t, err)
+	require.Len(t, txs, 1)
+	assert.Equal(t, tx2.ID, txs[0].ID)
+	assert.Equal(t, uint64(230), txs[0].BlockNum)
+	assert.Equal(t, uint64(200), txs[0].HeadBlockNum)
+
+	txs, err = store.FindEOSTxs(tx1.TxID, tx3.TxID)
+	require.NoError(t, err)
+	require.Len(t, txs, 2)
+	assert.Equal(t, tx1.TxID, txs[0].TxID)
+	assert.Equal(t, "9c5d8c4fc1a9e4de0f23a7a2e0fd3fe6b0aa9e5e0c4a1d1a6bd8c59d0d4e2d7a", txs[0].CurrentTxID)
+	assert.Equal(t, models.EOSTxStatus(models.EOSTxStatus_CONFIRMED), txs[0].Status)
+	assert.Equal(t, uint64(181), txs[0].BlockNum)
+	assert.Equal(t, uint64(180), txs[0].HeadBlockNum)
+	assert.Equal(t, uint32(2), txs[0].Attempts)
+	assert.True(t, txs[0].ConfirmedAt.Valid)
+	assert.Equal(t, models.EOSTxStatus(models.EOSTxStatus_FAILED), txs[1].Status)
+	assert.Equal(t, tx3.Error, txs[1].Error)
+
+	txs, err = store.FindEOSTxs()
+	require.NoError(t, err)
+	assert.Empty(t, txs)
+}
@@ -2,9 +2,12 @@ package orm
 
 import (
 	"errors"
+	"fmt"
+	"time"
 
 	"github.com/jinzhu/gorm"
 	"github.com/sebastianmontero/vrf-oracle/core/store/models"
+	"github.com/sebastianmontero/vrf-oracle/core/store/models/vrfkey"
 )
 
 // CreateVRFRequest inserts a new VRFRequest
@@ -38,12 +41,13 @@ func (orm *ORM) SaveVRFRequest(req *models.VRFRequest, cursor *models.Cursor) er
 	})
 }
 
-// saveVRFRequest core of saveVRFRequest so that it can be reutilized updates UpdatedAt for a VRFRequest and saves it
+// saveVRFRequest core of saveVRFRequest so that it can be reutilized updates UpdatedAt for a VRFRequest and saves it,
+// the removed block is omitted as the request held in memory may have been read before it was marked
 func (orm *ORM) saveVRFRequest(req *models.VRFRequest, cursor *models.Cursor, dbtx *gorm.DB) error {
 	result := dbtx.Unscoped().
 		Model(req).
 		Where("updated_at = ?", req.UpdatedAt).
-		Omit("deleted_at").
+		Omit("deleted_at", "removed_block_num").
 		Save(req)
 	if result.Error != nil {
 		return result.Error
@@ -97,6 +101,28 @@ func (orm *ORM) FindVRFRequestJob(id uint64) (*models.VRFRequestJob, error) {
 	return job, orm.DB.First(job, id).Error
 }
 
+// StartVRFRequestJob marks the VRFRequestJob as running if it is still waiting to be processed or revealed and its
+// VRFRequest has not been cancelled, returns false otherwise. The status is changed with a conditional update so
+// that a job whose request an operator cancelled while it was queued is never processed
+func (orm *ORM) StartVRFRequestJob(job *models.VRFRequestJob) (bool, error) {
+	if err := orm.MustEnsureAdvisoryLock(); err != nil {
+		return false, err
+	}
+	result := orm.DB.Exec(
+		`UPDATE vrf_request_jobs SET status = ? WHERE id = ? AND status IN (?)
+		AND NOT EXISTS (SELECT 1 FROM vrf_requests WHERE vrf_requests.id = vrf_request_jobs.vrf_request_id AND vrf_requests.status = ?)`,
+		models.VRFRequestJobStatus_RUNNING,
+		job.ID,
+		[]models.VRFRequestJobStatus{models.VRFRequestJobStatus_ACTIVE, models.VRFRequestJobStatus_COMMITTED},
+		models.VRFRequestStatus_CANCELLED,
+	)
+	if result.Error != nil || result.RowsAffected == 0 {
+		return false, result.Error
+	}
+	job.Status = models.VRFRequestJobStatus_RUNNING
+	return true, nil
+}
+
 // SaveVRFRequestRun looks up a VRFRequestRun by its ID.
 func (orm *ORM) SaveVRFRequestRun(run *models.VRFRequestRun, saveVRFRequest bool) error {
 	return orm.convenientTransaction(func(dbtx *gorm.DB) error {
@@ -119,6 +145,35 @@ func (orm *ORM) SaveVRFRequestRun(run *models.VRFRequestRun, saveVRFRequest bool
 	})
 }
 
+// VRFRequestFulfilledAtBlock returns true if a job of the VRFRequest at blockNum was completed by a run that pushed
+// a transaction, that is the round of the request at that block was already fulfilled
+func (orm *ORM) VRFRequestFulfilledAtBlock(vrfRequestID, blockNum uint64) (bool, error) {
+	if err := orm.MustEnsureAdvisoryLock(); err != nil {
+		return false, err
+	}
+	var count int
+	err := orm.DB.
+		Model(&models.VRFRequestJob{}).
+		Where("vrf_request_id = ? AND block_num = ? AND status = ?", vrfRequestID, blockNum, models.VRFRequestJobStatus_COMPLETED).
+		Where("EXISTS (SELECT 1 FROM vrf_request_runs WHERE vrf_request_runs.vrf_request_job_id = vrf_request_jobs.id AND tx_id <> '' AND status IN (?))",
+			[]models.VRFRequestRunStatus{models.VRFRequestRunStatus_COMPLETED, models.VRFRequestRunStatus_CONFIRMED}).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// CountVRFRequestRuns returns the number of VRFRequestRuns of a VRFRequestJob, the attempts to process it
+func (orm *ORM) CountVRFRequestRuns(vrfRequestJobID uint64) (int, error) {
+	if err := orm.MustEnsureAdvisoryLock(); err != nil {
+		return 0, err
+	}
+	var count int
+	err := orm.DB.
+		Model(&models.VRFRequestRun{}).
+		Where("vrf_request_job_id = ?", vrfRequestJobID).
+		Count(&count).Error
+	return count, err
+}
+
 // FindVRFRequestRun looks up a VRFRequestRun by its ID.
 func (orm *ORM) FindVRFRequestRun(id uint64) (*models.VRFRequestRun, error) {
 	run := &models.VRFRequestRun{}
@@ -127,3 +182,511 @@ func (orm *ORM) FindVRFRequestRun(id uint64) (*models.VRFRequestRun, error) {
 	}
 	return run, orm.DB.First(run, id).Error
 }
+
+// FindActiveVRFRequestsByType returns the active VRFRequests of the specified types
+func (orm *ORM) FindActiveVRFRequestsByType(types ...models.VRFRequestType) ([]*models.VRFRequest, error) {
+	var reqs []*models.VRFRequest
+	if err := orm.MustEnsureAdvisoryLock(); err != nil {
+		return reqs, err
+	}
+	err := orm.DB.
+		Where("status = ? AND type IN (?)", models.VRFRequestStatus_ACTIVE, types).
+		Order("id asc").
+		Find(&reqs).Error
+	return reqs, err
+}
+
+// FindVRFRequestJobsToRetry returns the active VRFRequestJobs whose retry time has been reached, those that
+// have failed at least once, those whose transaction was dropped and those retried by an operator, along with
+// their VRFRequest
+func (orm *ORM) FindVRFRequestJobsToRetry(now time.Time) ([]*models.VRFRequestJob, error) {
+	var jobs []*models.VRFRequestJob
+	if err := orm.MustEnsureAdvisoryLock(); err != nil {
+		return jobs, err
+	}
+	err := orm.DB.
+		Preload("VRFRequest").
+		Where("status = ? AND retry_at <= ?", models.VRFRequestJobStatus_ACTIVE, now).
+		Order("retry_at asc").
+		Find(&jobs).Error
+	return jobs, err
+}
+
+// FindCommittedVRFRequestJobs returns the commit-reveal jobs that are waiting to be revealed and whose block
+// and setcommit transaction block are at or below maxBlockNum. Jobs whose commit run has not been confirmed yet
+// are left out, as the VRFConfirmer saves the job when it confirms the run
+func (orm *ORM) FindCommittedVRFRequestJobs(maxBlockNum uint64) ([]*models.VRFRequestJob, error) {
+	var jobs []*models.VRFRequestJob
+	if err := orm.MustEnsureAdvisoryLock(); err != nil {
+		return jobs, err
+	}
+	err := orm.DB.
+		Preload("VRFRequest").
+		Where("status = ? AND block_num <= ? AND commit_tx_block_num > 0 AND commit_tx_block_num <= ?",
+			models.VRFRequestJobStatus_COMMITTED, maxBlockNum, maxBlockNum).
+		Where("NOT EXISTS (SELECT 1 FROM vrf_request_runs WHERE vrf_request_runs.vrf_request_job_id = vrf_request_jobs.id AND tx_id <> '' AND status = ?)",
+			models.VRFRequestRunStatus_COMPLETED).
+		Order("id asc").
+		Find(&jobs).Error
+	return jobs, err
+}
+
+// FindVRFRequestRunsToConfirm returns the completed VRFRequestRuns whose transaction has been confirmed or has
+// failed and that have not been confirmed yet, along with their VRFRequestJob and VRFRequest
+func (orm *ORM) FindVRFRequestRunsToConfirm() ([]*models.VRFRequestRun, error) {
+	var runs []*models.VRFRequestRun
+	if err := orm.MustEnsureAdvisoryLock(); err != nil {
+		return runs, err
+	}
+	err := orm.DB.
+		Preload("VRFRequestJob").
+		Preload("VRFRequestJob.VRFRequest").
+		Where("status = ? AND tx_id IN (SELECT tx_id FROM eos_txs WHERE status IN (?))",
+			models.VRFRequestRunStatus_COMPLETED,
+			[]string{models.EOSTxStatus_CONFIRMED, models.EOSTxStatus_FAILED}).
+		Order("id asc").
+		Find(&runs).Error
+	return runs, err
+}
+
+// FindVRFRequestsWithoutJobs returns the active VRFRequests of the specified types that don't have any jobs,
+// used to recover requests that were stored but never processed
+func (orm *ORM) FindVRFRequestsWithoutJobs(types ...models.VRFRequestType) ([]*models.VRFRequest, error) {
+	var reqs []*models.VRFRequest
+	if err := orm.MustEnsureAdvisoryLock(); err != nil {
+		return reqs, err
+	}
+	err := orm.DB.
+		Where("status = ? AND type IN (?)", models.VRFRequestStatus_ACTIVE, types).
+		Where("NOT EXISTS (SELECT 1 FROM vrf_request_jobs WHERE vrf_request_jobs.vrf_request_id = vrf_requests.id)").
+		Order("id asc").
+		Find(&reqs).Error
+	return reqs, err
+}
+
+// FindQueuedVRFRequestJobs returns the VRFRequestJobs that were waiting to be processed for the first time,
+// along with their VRFRequest. The jobs with a retry time are left to the retry worker
+func (orm *ORM) FindQueuedVRFRequestJobs() ([]*models.VRFRequestJob, error) {
+	var jobs []*models.VRFRequestJob
+	if err := orm.MustEnsureAdvisoryLock(); err != nil {
+		return jobs, err
+	}
+	err := orm.DB.
+		Preload("VRFRequest").
+		Where("status = ? AND retries = 0 AND retry_at IS NULL", models.VRFRequestJobStatus_ACTIVE).
+		Order("id asc").
+		Find(&jobs).Error
+	return jobs, err
+}
+
+// FailInterruptedVRFRequestRuns marks as failed the VRFRequestRuns that were left running. The interruption counts
+// as a retry of their VRFRequestJobs, so that a job that stops the oracle does not do it again on every restart:
+// the jobs that run out of retries fail, completing their immediate and scheduled VRFRequests, the others go back
+// to waiting to be retried at retryAt, or revealed if their secrets were committed
+func (orm *ORM) FailInterruptedVRFRequestRuns(msg string, maxRetries uint8, retryAt time.Time) error {
+	return orm.convenientTransaction(func(dbtx *gorm.DB) error {
+		var ids []uint64
+		err := dbtx.
+			Model(&models.VRFRequestJob{}).
+			Where("status = ?", models.VRFRequestJobStatus_RUNNING).
+			Pluck("id", &ids).Error
+		if err != nil || len(ids) == 0 {
+			return err
+		}
+		now := time.Now()
+		err = dbtx.Exec(
+			"UPDATE vrf_request_runs SET status = ?, status_msg = ?, end_at = ? WHERE status = ?",
+			models.VRFRequestRunStatus_FAILED,
+			msg,
+			now,
+			models.VRFRequestRunStatus_RUNNING,
+		).Error
+		if err != nil {
+			return err
+		}
+		err = dbtx.Exec(`
+			UPDATE vrf_request_jobs SET
+				retries = retries + 1,
+				status = CASE WHEN retries + 1 > ? THEN ? WHEN committed_at IS NULL THEN ? ELSE ? END,
+				end_at = CASE WHEN retries + 1 > ? THEN ? ELSE end_at END,
+				retry_at = CASE WHEN retries + 1 <= ? AND committed_at IS NULL THEN ? ELSE retry_at END
+			WHERE id IN (?)`,
+			maxRetries,
+			models.VRFRequestJobStatus_FAILED,
+			models.VRFRequestJobStatus_ACTIVE,
+			models.VRFRequestJobStatus_COMMITTED,
+			maxRetries,
+			now,
+			maxRetries,
+			retryAt,
+			ids,
+		).Error
+		if err != nil {
+			return err
+		}
+		return dbtx.Exec(`
+			UPDATE vrf_requests SET status = ?, updated_at = ?
+			WHERE status = ? AND type IN (?) AND id IN (
+				SELECT vrf_request_id FROM vrf_request_jobs WHERE id IN (?) AND status = ?
+			)`,
+			models.VRFRequestStatus_COMPLETED,
+			now,
+			models.VRFRequestStatus_ACTIVE,
+			[]models.VRFRequestType{models.VRFRequestType_IMMEDIATE, models.VRFRequestType_SCHEDULED},
+			ids,
+			models.VRFRequestJobStatus_FAILED,
+		).Error
+	})
+}
+
+// FindVRFRequestAtBlock looks up the latest VRFRequest of a caller and assoc id made in the specified block,
+// returns nil if there is none
+func (orm *ORM) FindVRFRequestAtBlock(caller string, assocID uint64, blockHash string) (*models.VRFRequest, error) {
+	req := &models.VRFRequest{}
+	if err := orm.MustEnsureAdvisoryLock(); err != nil {
+		return nil, err
+	}
+	err := orm.DB.
+		Where("caller = ? AND assoc_id = ? AND block_hash = ?", caller, assocID, blockHash).
+		Order("id desc").
+		First(req).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return req, err
+}
+
+// MarkVRFRequestsRemoved records that the row of the caller and assoc id was removed from the jobs table at blockNum
+// in the VRFRequests stored from it up to that block, and advances the cursor. Returns the number of requests marked
+func (orm *ORM) MarkVRFRequestsRemoved(caller string, assocID, blockNum uint64, cursor *models.Cursor) (int64, error) {
+	var marked int64
+	err := orm.convenientTransaction(func(dbtx *gorm.DB) error {
+		result := dbtx.Exec(
+			"UPDATE vrf_requests SET removed_block_num = ? WHERE caller = ? AND assoc_id = ? AND block_num <= ? AND removed_block_num = 0",
+			blockNum,
+			caller,
+			assocID,
+			blockNum,
+		)
+		if result.Error != nil {
+			return result.Error
+		}
+		marked = result.RowsAffected
+		return orm.SaveCursorTrx(cursor, dbtx)
+	})
+	return marked, err
+}
+
+// UnmarkVRFRequestsRemoved reverts MarkVRFRequestsRemoved when the block that removed the row is undone, and
+// advances the cursor. Returns the number of requests unmarked
+func (orm *ORM) UnmarkVRFRequestsRemoved(caller string, assocID, blockNum uint64, cursor *models.Cursor) (int64, error) {
+	var unmarked int64
+	err := orm.convenientTransaction(func(dbtx *gorm.DB) error {
+		result := dbtx.Exec(
+			"UPDATE vrf_requests SET removed_block_num = 0 WHERE caller = ? AND assoc_id = ? AND removed_block_num = ?",
+			caller,
+			assocID,
+			blockNum,
+		)
+		if result.Error != nil {
+			return result.Error
+		}
+		unmarked = result.RowsAffected
+		return orm.SaveCursorTrx(cursor, dbtx)
+	})
+	return unmarked, err
+}
+
+// CreateVRFDeadLetter inserts a new VRFDeadLetter and advances the cursor past the delta
+func (orm *ORM) CreateVRFDeadLetter(deadLetter *models.VRFDeadLetter, cursor *models.Cursor) error {
+	return orm.convenientTransaction(func(dbtx *gorm.DB) error {
+		err := dbtx.Create(deadLetter).Error
+		if err != nil {
+			return err
+		}
+		return orm.SaveCursorTrx(cursor, dbtx)
+	})
+}
+
+// SaveVRFDeadLetter saves a VRFDeadLetter
+func (orm *ORM) SaveVRFDeadLetter(deadLetter *models.VRFDeadLetter) error {
+	if err := orm.MustEnsureAdvisoryLock(); err != nil {
+		return err
+	}
+	return orm.DB.Save(deadLetter).Error
+}
+
+// FindVRFDeadLetters returns the VRFDeadLetters with the specified status, all of them if status is empty
+func (orm *ORM) FindVRFDeadLetters(status models.VRFDeadLetterStatus) ([]*models.VRFDeadLetter, error) {
+	var deadLetters []*models.VRFDeadLetter
+	if err := orm.MustEnsureAdvisoryLock(); err != nil {
+		return deadLetters, err
+	}
+	query := orm.DB.Order("id asc")
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	return deadLetters, query.Find(&deadLetters).Error
+}
+
+// MarkVRFDeadLettersForReplay marks failed VRFDeadLetters to be replayed, all of them if no ids are specified,
+// returns the number of dead letters marked
+func (orm *ORM) MarkVRFDeadLettersForReplay(ids ...uint64) (int64, error) {
+	if err := orm.MustEnsureAdvisoryLock(); err != nil {
+		return 0, err
+	}
+	query := orm.DB.Model(&models.VRFDeadLetter{}).Where("status = ?", models.VRFDeadLetterStatus_FAILED)
+	if len(ids) > 0 {
+		query = query.Where("id IN (?)", ids)
+	}
+	result := query.Updates(map[string]interface{}{
+		"status":     models.VRFDeadLetterStatus_REPLAY,
+		"updated_at": time.Now(),
+	})
+	return result.RowsAffected, result.Error
+}
+
+// SaveVRFKeyRotation saves a VRFKeyRotation
+func (orm *ORM) SaveVRFKeyRotation(rotation *models.VRFKeyRotation) error {
+	if err := orm.MustEnsureAdvisoryLock(); err != nil {
+		return err
+	}
+	return orm.DB.Save(rotation).Error
+}
+
+// FindVRFKeyRotations returns the VRFKeyRotations, oldest first
+func (orm *ORM) FindVRFKeyRotations() ([]*models.VRFKeyRotation, error) {
+	var rotations []*models.VRFKeyRotation
+	if err := orm.MustEnsureAdvisoryLock(); err != nil {
+		return rotations, err
+	}
+	return rotations, orm.DB.Order("id asc").Find(&rotations).Error
+}
+
+// FindLastVRFKeyRotation returns the most recent VRFKeyRotation, nil if the key has never been rotated
+func (orm *ORM) FindLastVRFKeyRotation() (*models.VRFKeyRotation, error) {
+	if err := orm.MustEnsureAdvisoryLock(); err != nil {
+		return nil, err
+	}
+	rotation := &models.VRFKeyRotation{}
+	err := orm.DB.Order("id desc").First(rotation).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return rotation, err
+}
+
+// CreateEncryptedVRFKeyShare stores the share of a threshold vrf group key, errors if there is already a share
+// of the group key, as a node holds one share of each group key
+func (orm *ORM) CreateEncryptedVRFKeyShare(share *vrfkey.EncryptedKeyShare) error {
+	if err := orm.MustEnsureAdvisoryLock(); err != nil {
+		return err
+	}
+	return orm.DB.Create(share).Error
+}
+
+// FindEncryptedVRFKeyShare returns the share of the group key, nil if the node does not hold one
+func (orm *ORM) FindEncryptedVRFKeyShare(groupPublicKey vrfkey.PublicKey) (*vrfkey.EncryptedKeyShare, error) {
+	if err := orm.MustEnsureAdvisoryLock(); err != nil {
+		return nil, err
+	}
+	share := &vrfkey.EncryptedKeyShare{}
+	err := orm.DB.Where("group_public_key = ?", groupPublicKey).First(share).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return share, err
+}
+
+// FindEncryptedVRFKeyShares returns the shares of the threshold vrf group keys the node holds
+func (orm *ORM) FindEncryptedVRFKeyShares() ([]*vrfkey.EncryptedKeyShare, error) {
+	var shares []*vrfkey.EncryptedKeyShare
+	if err := orm.MustEnsureAdvisoryLock(); err != nil {
+		return shares, err
+	}
+	return shares, orm.DB.Order("created_at asc").Find(&shares).Error
+}
+
+// VRFRequestFilter the filters of VRFRequests, empty fields are not applied
+type VRFRequestFilter struct {
+	Caller    string
+	AssocID   *uint64
+	Status    models.VRFRequestStatus
+	FromBlock uint64
+	ToBlock   uint64
+}
+
+// VRFRequests returns the VRFRequests that match the filter, newest first, and the total count of them
+func (orm *ORM) VRFRequests(filter VRFRequestFilter, offset, limit int) ([]models.VRFRequest, int, error) {
+	if err := orm.MustEnsureAdvisoryLock(); err != nil {
+		return nil, 0, err
+	}
+	query := orm.DB.Model(&models.VRFRequest{})
+	if filter.Caller != "" {
+		query = query.Where("caller = ?", filter.Caller)
+	}
+	if filter.AssocID != nil {
+		query = query.Where("assoc_id = ?", *filter.AssocID)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.FromBlock > 0 {
+		query = query.Where("block_num >= ?", filter.FromBlock)
+	}
+	if filter.ToBlock > 0 {
+		query = query.Where("block_num <= ?", filter.ToBlock)
+	}
+	var count int
+	err := query.Count(&count).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	var reqs []models.VRFRequest
+	err = query.Order("id desc").Limit(limit).Offset(offset).Find(&reqs).Error
+	return reqs, count, err
+}
+
+// FindVRFRequestJobsWithRuns returns the jobs of the VRFRequest, oldest first, and the runs of each job
+// indexed by job id
+func (orm *ORM) FindVRFRequestJobsWithRuns(vrfRequestID uint64) ([]*models.VRFRequestJob, map[uint64][]*models.VRFRequestRun, error) {
+	if err := orm.MustEnsureAdvisoryLock(); err != nil {
+		return nil, nil, err
+	}
+	var jobs []*models.VRFRequestJob
+	err := orm.DB.Where("vrf_request_id = ?", vrfRequestID).Order("id asc").Find(&jobs).Error
+	if err != nil {
+		return nil, nil, err
+	}
+	runsByJob := make(map[uint64][]*models.VRFRequestRun, len(jobs))
+	if len(jobs) == 0 {
+		return jobs, runsByJob, nil
+	}
+	jobIDs := make([]uint64, 0, len(jobs))
+	for _, job := range jobs {
+		jobIDs = append(jobIDs, job.ID)
+	}
+	var runs []*models.VRFRequestRun
+	err = orm.DB.Where("vrf_request_job_id IN (?)", jobIDs).Order("id asc").Find(&runs).Error
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, run := range runs {
+		runsByJob[run.VRFRequestJobID] = append(runsByJob[run.VRFRequestJobID], run)
+	}
+	return jobs, runsByJob, nil
+}
+
+// ErrVRFRequestStateConflict is returned when an operator action is not allowed in the current state of a VRFRequest
+var ErrVRFRequestStateConflict = errors.New("vrf request state conflict")
+
+// RetryVRFRequest creates a new job that processes the last job of the VRFRequest again, the last job must
+// have finished. Immediate and scheduled requests that were completed are reactivated so that the new job can
+// complete them again, the runs of the previous jobs are kept as they are
+func (orm *ORM) RetryVRFRequest(id uint64) (*models.VRFRequestJob, error) {
+	var job *models.VRFRequestJob
+	err := orm.convenientTransaction(func(dbtx *gorm.DB) error {
+		req, lastJob, err := findVRFRequestForUpdate(dbtx, id)
+		if err != nil {
+			return err
+		}
+		if req.Status == models.VRFRequestStatus_CANCELLED {
+			return fmt.Errorf("%w: request %v is cancelled", ErrVRFRequestStateConflict, req.ID)
+		}
+		if lastJob == nil {
+			return fmt.Errorf("%w: request %v has no jobs yet", ErrVRFRequestStateConflict, req.ID)
+		}
+		if lastJob.InProgress() {
+			return fmt.Errorf("%w: job %v of request %v is %v", ErrVRFRequestStateConflict, lastJob.ID, req.ID, lastJob.Status)
+		}
+		//Recurrent requests are held in memory by the scheduler, they are only saved when they end
+		if req.Type != models.VRFRequestType_RECURRENT && req.Status == models.VRFRequestStatus_COMPLETED {
+			req.Status = models.VRFRequestStatus_ACTIVE
+			err = orm.saveVRFRequest(req, nil, dbtx)
+			if err != nil {
+				return err
+			}
+		}
+		job = lastJob.Retry(req)
+		return dbtx.Omit("VRFRequest").Save(job).Error
+	})
+	return job, err
+}
+
+// CancelVRFRequest cancels the VRFRequest and fails its jobs that are waiting to be processed or revealed,
+// a failed run with msg is recorded for each of them. Requests with a running job can not be cancelled
+func (orm *ORM) CancelVRFRequest(id uint64, msg string) (*models.VRFRequest, error) {
+	var req *models.VRFRequest
+	err := orm.convenientTransaction(func(dbtx *gorm.DB) error {
+		var err error
+		req, _, err = findVRFRequestForUpdate(dbtx, id)
+		if err != nil {
+			return err
+		}
+		if req.Status == models.VRFRequestStatus_CANCELLED || req.Status == models.VRFRequestStatus_COMPLETED {
+			return fmt.Errorf("%w: request %v is already %v", ErrVRFRequestStateConflict, req.ID, req.Status)
+		}
+		var jobs []*models.VRFRequestJob
+		err = dbtx.
+			Where("vrf_request_id = ? AND status IN (?)", req.ID, []models.VRFRequestJobStatus{
+				models.VRFRequestJobStatus_ACTIVE,
+				models.VRFRequestJobStatus_RUNNING,
+				models.VRFRequestJobStatus_COMMITTED,
+			}).
+			Order("id asc").
+			Find(&jobs).Error
+		if err != nil {
+			return err
+		}
+		for _, job := range jobs {
+			if job.Status == models.VRFRequestJobStatus_RUNNING {
+				return fmt.Errorf("%w: job %v of request %v is running", ErrVRFRequestStateConflict, job.ID, req.ID)
+			}
+			status := job.Status
+			job.VRFRequest = req
+			run := models.NewVRFRequestRun(job)
+			run.UpdateStatus(models.VRFRequestRunStatus_FAILED, msg, 0)
+			err = dbtx.Omit("VRFRequestJob").Save(run).Error
+			if err != nil {
+				return err
+			}
+			//A worker may have started the job since it was read
+			result := dbtx.Exec(
+				"UPDATE vrf_request_jobs SET status = ?, retries = ?, end_at = ? WHERE id = ? AND status = ?",
+				job.Status,
+				job.Retries,
+				job.EndAt,
+				job.ID,
+				status,
+			)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				return fmt.Errorf("%w: job %v of request %v is running", ErrVRFRequestStateConflict, job.ID, req.ID)
+			}
+		}
+		req.Status = models.VRFRequestStatus_CANCELLED
+		return orm.saveVRFRequest(req, nil, dbtx)
+	})
+	return req, err
+}
+
+// findVRFRequestForUpdate locks the VRFRequest so that concurrent operator actions are serialized and returns it
+// along with its last job, nil if it has none
+func findVRFRequestForUpdate(dbtx *gorm.DB, id uint64) (*models.VRFRequest, *models.VRFRequestJob, error) {
+	req := &models.VRFRequest{}
+	err := dbtx.Set("gorm:query_option", "FOR UPDATE").First(req, id).Error
+	if err != nil {
+		return nil, nil, err
+	}
+	lastJob := &models.VRFRequestJob{}
+	err = dbtx.Where("vrf_request_id = ?", id).Order("id desc").First(lastJob).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return req, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return req, lastJob, nil
+}
@@ -1,13 +1,17 @@
 package orm_test
 
 import (
+	"errors"
 	"testing"
 	"time"
 
 	"github.com/lib/pq"
 	"github.com/sebastianmontero/vrf-oracle/core/internal/cltest"
+	strpkg "github.com/sebastianmontero/vrf-oracle/core/store"
 	"github.com/sebastianmontero/vrf-oracle/core/store/models"
+	"github.com/sebastianmontero/vrf-oracle/core/store/models/vrfkey"
 	"github.com/sebastianmontero/vrf-oracle/core/store/orm"
+	"github.com/sebastianmontero/vrf-oracle/core/utils"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"gopkg.in/guregu/null.v4"
@@ -367,6 +371,689 @@ func TestORM_SaveVRFRequestRun(t *testing.T) {
 	validateVRFRequest(req2, req1, t)
 }
 
+func TestORM_FindActiveVRFRequestsByType(t *testing.T) {
+	t.Parallel()
+	store, cleanup := cltest.NewStore(t)
+	defer cleanup()
+
+	recurrent := createVRFRequest(t, store, 1, "user1", 395235, models.VRFRequestType_RECURRENT, models.VRFRequestStatus_ACTIVE)
+	createVRFRequest(t, store, 1, "user1", 395235, models.VRFRequestType_RECURRENT, models.VRFRequestStatus_COMPLETED)
+	scheduled := createVRFRequest(t, store, 1, "user1", 395235, models.VRFRequestType_SCHEDULED, models.VRFRequestStatus_ACTIVE)
+	createVRFRequest(t, store, 1, "user1", 395235, models.VRFRequestType_IMMEDIATE, models.VRFRequestStatus_ACTIVE)
+
+	reqs, err := store.FindActiveVRFRequestsByType(models.VRFRequestType_RECURRENT, models.VRFRequestType_SCHEDULED)
+	require.NoError(t, err)
+	require.Len(t, reqs, 2)
+	validateVRFRequest(reqs[0], recurrent, t)
+	validateVRFRequest(reqs[1], scheduled, t)
+}
+
+func TestORM_FindVRFRequestJobsToRetry(t *testing.T) {
+	t.Parallel()
+	store, cleanup := cltest.NewStore(t)
+	defer cleanup()
+
+	req1 := createVRFRequest(t, store, 1, "user1", 395235, models.VRFRequestType_IMMEDIATE, models.VRFRequestStatus_ACTIVE)
+
+	now := time.Now()
+	job1 := createVRFRequestJob(t, store, req1, models.VRFRequestJobStatus_ACTIVE, 1, null.TimeFrom(now.Add(-time.Minute)))
+	createVRFRequestJob(t, store, req1, models.VRFRequestJobStatus_ACTIVE, 1, null.TimeFrom(now.Add(time.Minute)))
+	createVRFRequestJob(t, store, req1, models.VRFRequestJobStatus_ACTIVE, 0, null.Time{})
+	createVRFRequestJob(t, store, req1, models.VRFRequestJobStatus_FAILED, 4, null.TimeFrom(now.Add(-time.Minute)))
+	createVRFRequestJob(t, store, req1, models.VRFRequestJobStatus_RUNNING, 1, null.TimeFrom(now.Add(-time.Minute)))
+	//Its transaction was dropped or it was retried by an operator
+	job2 := createVRFRequestJob(t, store, req1, models.VRFRequestJobStatus_ACTIVE, 0, null.TimeFrom(now.Add(-time.Second)))
+
+	jobs, err := store.FindVRFRequestJobsToRetry(now)
+	require.NoError(t, err)
+	require.Len(t, jobs, 2)
+	validateVRFRequestJob(jobs[0], job1, t)
+	validateVRFRequest(jobs[0].VRFRequest, req1, t)
+	validateVRFRequestJob(jobs[1], job2, t)
+}
+
+func TestORM_FindCommittedVRFRequestJobs(t *testing.T) {
+	t.Parallel()
+	store, cleanup := cltest.NewStore(t)
+	defer cleanup()
+
+	req1 := createVRFRequest(t, store, 1, "user1", 395235, models.VRFRequestType_IMMEDIATE, models.VRFRequestStatus_ACTIVE)
+
+	job1 := createCommittedVRFRequestJob(t, store, req1, models.VRFRequestJobStatus_COMMITTED, 100, 102)
+	createCommittedVRFRequestJob(t, store, req1, models.VRFRequestJobStatus_COMMITTED, 101, 105)
+	createCommittedVRFRequestJob(t, store, req1, models.VRFRequestJobStatus_COMPLETED, 99, 101)
+	//The block of its setcommit transaction is not known
+	createCommittedVRFRequestJob(t, store, req1, models.VRFRequestJobStatus_COMMITTED, 100, 0)
+
+	//The block of the job is irreversible but not the one of the commit
+	jobs, err := store.FindCommittedVRFRequestJobs(101)
+	require.NoError(t, err)
+	assert.Empty(t, jobs)
+
+	//The commit run is waiting to be confirmed
+	run1 := models.NewVRFRequestRun(job1)
+	run1.SetTx("6c5d8c4fc1a9e4de0f23a7a2e0fd3fe6b0aa9e5e0c4a1d1a6bd8c59d0d4e2d7a", 102)
+	run1.Commit("Committed")
+	err = store.SaveVRFRequestRun(run1, false)
+	require.NoError(t, err)
+	jobs, err = store.FindCommittedVRFRequestJobs(102)
+	require.NoError(t, err)
+	assert.Empty(t, jobs)
+
+	//The commit was pushed again after a microfork and included in a later block
+	run1.Confirm(104)
+	err = store.SaveVRFRequestRun(run1, false)
+	require.NoError(t, err)
+	jobs, err = store.FindCommittedVRFRequestJobs(103)
+	require.NoError(t, err)
+	assert.Empty(t, jobs)
+
+	jobs, err = store.FindCommittedVRFRequestJobs(104)
+	require.NoError(t, err)
+	require.Len(t, jobs, 1)
+	validateVRFRequestJob(jobs[0], job1, t)
+	assert.Equal(t, job1.Secrets, jobs[0].Secrets)
+	assert.True(t, jobs[0].CommittedAt.Valid)
+	assert.Equal(t, uint64(104), jobs[0].CommitTxBlockNum)
+	validateVRFRequest(jobs[0].VRFRequest, req1, t)
+}
+
+func TestORM_VRFRequests(t *testing.T) {
+	t.Parallel()
+	store, cleanup := cltest.NewStore(t)
+	defer cleanup()
+
+	req1 := createVRFRequest(t, store, 1, "user1", 100, models.VRFRequestType_IMMEDIATE, models.VRFRequestStatus_COMPLETED)
+	req2 := createVRFRequest(t, store, 2, "user1", 200, models.VRFRequestType_IMMEDIATE, models.VRFRequestStatus_ACTIVE)
+	req3 := createVRFRequest(t, store, 1, "user2", 300, models.VRFRequestType_IMMEDIATE, models.VRFRequestStatus_ACTIVE)
+
+	reqs, count, err := store.VRFRequests(orm.VRFRequestFilter{}, 0, 2)
+	require.NoError(t, err)
+	assert.Equal(t, 3, count)
+	require.Len(t, reqs, 2)
+	validateVRFRequest(&reqs[0], req3, t)
+	validateVRFRequest(&reqs[1], req2, t)
+
+	reqs, count, err = store.VRFRequests(orm.VRFRequestFilter{Caller: "user1"}, 0, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+	require.Len(t, reqs, 2)
+	validateVRFRequest(&reqs[0], req2, t)
+	validateVRFRequest(&reqs[1], req1, t)
+
+	assocID := uint64(1)
+	reqs, count, err = store.VRFRequests(orm.VRFRequestFilter{AssocID: &assocID, Status: models.VRFRequestStatus_ACTIVE}, 0, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+	require.Len(t, reqs, 1)
+	validateVRFRequest(&reqs[0], req3, t)
+
+	reqs, count, err = store.VRFRequests(orm.VRFRequestFilter{FromBlock: 150, ToBlock: 250}, 0, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+	require.Len(t, reqs, 1)
+	validateVRFRequest(&reqs[0], req2, t)
+}
+
+func TestORM_FindVRFRequestJobsWithRuns(t *testing.T) {
+	t.Parallel()
+	store, cleanup := cltest.NewStore(t)
+	defer cleanup()
+
+	req1 := createVRFRequest(t, store, 1, "user1", 395235, models.VRFRequestType_IMMEDIATE, models.VRFRequestStatus_ACTIVE)
+
+	job1 := createVRFRequestJob(t, store, req1, models.VRFRequestJobStatus_ACTIVE, 0, null.Time{})
+	run1 := models.NewVRFRequestRun(job1)
+	run1.UpdateStatus(models.VRFRequestRunStatus_FAILED, "Calling SetRand on contract failed", 3)
+	err := store.SaveVRFRequestRun(run1, false)
+	require.NoError(t, err)
+	run2 := models.NewVRFRequestRun(job1)
+	run2.UpdateStatus(models.VRFRequestRunStatus_COMPLETED, "Completed successfully", 3)
+	err = store.SaveVRFRequestRun(run2, false)
+	require.NoError(t, err)
+
+	jobs, runsByJob, err := store.FindVRFRequestJobsWithRuns(req1.ID)
+	require.NoError(t, err)
+	require.Len(t, jobs, 1)
+	validateVRFRequestJob(jobs[0], job1, t)
+	runs := runsByJob[job1.ID]
+	require.Len(t, runs, 2)
+	validateVRFRequestRun(runs[0], run1, t)
+	validateVRFRequestRun(runs[1], run2, t)
+	assert.Equal(t, "Calling SetRand on contract failed", runs[0].StatusMsg)
+
+	jobs, runsByJob, err = store.FindVRFRequestJobsWithRuns(req1.ID + 1)
+	require.NoError(t, err)
+	assert.Empty(t, jobs)
+	assert.Empty(t, runsByJob)
+}
+
+func TestORM_FindVRFRequestRunsToConfirm(t *testing.T) {
+	t.Parallel()
+	store, cleanup := cltest.NewStore(t)
+	defer cleanup()
+
+	req1 := createVRFRequest(t, store, 1, "user1", 395235, models.VRFRequestType_IMMEDIATE, models.VRFRequestStatus_ACTIVE)
+
+	job1 := createVRFRequestJob(t, store, req1, models.VRFRequestJobStatus_ACTIVE, 0, null.Time{})
+
+	proofs, err := models.ParseJSON([]byte(`[{"block_num":395235,"output_u64":42}]`))
+	require.NoError(t, err)
+	newRun := func(status models.VRFRequestRunStatus, txID string, txBlockNum uint64) *models.VRFRequestRun {
+		run := models.NewVRFRequestRun(job1)
+		run.UpdateStatus(status, "", 3)
+		run.SetTx(txID, txBlockNum)
+		run.Proofs = &proofs
+		run.KeyHash = "0x9fddc9ad5fa1c4a2ba1b32b8ad18ba1b9e4ab5cd2f0e1a8d3e8f1a8d3e8f1a8d"
+		err := store.SaveVRFRequestRun(run, false)
+		require.NoError(t, err)
+		return run
+	}
+	tx1ID := "6c5d8c4fc1a9e4de0f23a7a2e0fd3fe6b0aa9e5e0c4a1d1a6bd8c59d0d4e2d7a"
+	tx2ID := "7c5d8c4fc1a9e4de0f23a7a2e0fd3fe6b0aa9e5e0c4a1d1a6bd8c59d0d4e2d7a"
+	newRun(models.VRFRequestRunStatus_FAILED, tx1ID, 395300)
+	run1 := newRun(models.VRFRequestRunStatus_COMPLETED, tx1ID, 395300)
+	run2 := newRun(models.VRFRequestRunStatus_COMPLETED, tx2ID, 395400)
+	newRun(models.VRFRequestRunStatus_COMPLETED, "", 0)
+
+	tx1 := models.NewEOSTx(tx1ID, cltest.JSONFromString(t, `[]`), 395299, time.Now())
+	err = store.SaveEOSTx(tx1)
+	require.NoError(t, err)
+	tx2 := models.NewEOSTx(tx2ID, cltest.JSONFromString(t, `[]`), 395399, time.Now())
+	err = store.SaveEOSTx(tx2)
+	require.NoError(t, err)
+
+	runs, err := store.FindVRFRequestRunsToConfirm()
+	require.NoError(t, err)
+	assert.Empty(t, runs)
+
+	tx1.Confirm(395301)
+	err = store.SaveEOSTx(tx1)
+	require.NoError(t, err)
+	runs, err = store.FindVRFRequestRunsToConfirm()
+	require.NoError(t, err)
+	require.Len(t, runs, 1)
+	validateVRFRequestRun(runs[0], run1, t)
+	assert.Equal(t, run1.TxID, runs[0].TxID)
+	assert.Equal(t, run1.TxBlockNum, runs[0].TxBlockNum)
+	assert.Equal(t, run1.KeyHash, runs[0].KeyHash)
+	require.NotNil(t, runs[0].Proofs)
+	assert.Equal(t, uint64(42), runs[0].Proofs.Get("0.output_u64").Uint())
+	validateVRFRequestJob(runs[0].VRFRequestJob, job1, t)
+	validateVRFRequest(runs[0].VRFRequestJob.VRFRequest, req1, t)
+
+	run := runs[0]
+	run.Confirm(395301)
+	err = store.SaveVRFRequestRun(run, false)
+	require.NoError(t, err)
+	runs, err = store.FindVRFRequestRunsToConfirm()
+	require.NoError(t, err)
+	assert.Empty(t, runs)
+
+	confirmed, err := store.FindVRFRequestRun(run1.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.VRFRequestRunStatus(models.VRFRequestRunStatus_CONFIRMED), confirmed.Status)
+	assert.Equal(t, uint64(395301), confirmed.TxBlockNum)
+	assert.True(t, confirmed.ConfirmedAt.Valid)
+
+	//The dropped run is failed and its job processed again
+	tx2.Fail("Expired without being included after 5 attempts")
+	err = store.SaveEOSTx(tx2)
+	require.NoError(t, err)
+	runs, err = store.FindVRFRequestRunsToConfirm()
+	require.NoError(t, err)
+	require.Len(t, runs, 1)
+	assert.Equal(t, run2.ID, runs[0].ID)
+	run = runs[0]
+	run.Drop("Dropped")
+	err = store.SaveVRFRequestRun(run, true)
+	require.NoError(t, err)
+	runs, err = store.FindVRFRequestRunsToConfirm()
+	require.NoError(t, err)
+	assert.Empty(t, runs)
+	jobs, err := store.FindVRFRequestJobsToRetry(time.Now())
+	require.NoError(t, err)
+	require.Len(t, jobs, 1)
+	assert.Equal(t, job1.ID, jobs[0].ID)
+	assert.Equal(t, models.VRFRequestStatus(models.VRFRequestStatus_ACTIVE), jobs[0].VRFRequest.Status)
+}
+
+func TestORM_RetryVRFRequest(t *testing.T) {
+	t.Parallel()
+	store, cleanup := cltest.NewStore(t)
+	defer cleanup()
+
+	req1 := createVRFRequest(t, store, 1, "user1", 395235, models.VRFRequestType_IMMEDIATE, models.VRFRequestStatus_ACTIVE)
+
+	_, err := store.RetryVRFRequest(req1.ID)
+	require.True(t, errors.Is(err, orm.ErrVRFRequestStateConflict), "request without jobs can not be retried")
+
+	job1 := models.NewVRFRequestJobAtBlock(req1, 395300, "000607e21b8d9da0ade735bcd19a9f8812335b510482d4b9f8c53753e0ebe9c9")
+	err = store.SaveVRFRequestJob(job1)
+	require.NoError(t, err)
+
+	_, err = store.RetryVRFRequest(req1.ID)
+	require.True(t, errors.Is(err, orm.ErrVRFRequestStateConflict), "job in progress can not be retried")
+
+	run1 := models.NewVRFRequestRun(job1)
+	run1.UpdateStatus(models.VRFRequestRunStatus_FAILED, "Calling SetRand on contract failed", 0)
+	err = store.SaveVRFRequestRun(run1, true)
+	require.NoError(t, err)
+
+	job2, err := store.RetryVRFRequest(req1.ID)
+	require.NoError(t, err)
+	assert.NotEqual(t, job1.ID, job2.ID)
+	assert.Equal(t, job1.BlockNum, job2.BlockNum)
+	assert.Equal(t, job1.BlockHash, job2.BlockHash)
+	assert.Equal(t, models.VRFRequestJobStatus(models.VRFRequestJobStatus_ACTIVE), job2.Status)
+	assert.Equal(t, uint8(0), job2.Retries)
+
+	jobs, err := store.FindVRFRequestJobsToRetry(time.Now())
+	require.NoError(t, err)
+	require.Len(t, jobs, 1)
+	validateVRFRequestJob(jobs[0], job2, t)
+	assert.Equal(t, models.VRFRequestStatus(models.VRFRequestStatus_ACTIVE), jobs[0].VRFRequest.Status)
+
+	//The runs of the previous job are kept
+	run, err := store.FindVRFRequestRun(run1.ID)
+	require.NoError(t, err)
+	validateVRFRequestRun(run, run1, t)
+
+	_, err = store.RetryVRFRequest(req1.ID + 1)
+	require.True(t, errors.Is(err, orm.ErrorNotFound))
+}
+
+func TestORM_RetryVRFRequestCommitted(t *testing.T) {
+	t.Parallel()
+	store, cleanup := cltest.NewStore(t)
+	defer cleanup()
+
+	req1 := createVRFRequest(t, store, 1, "user1", 395235, models.VRFRequestType_RECURRENT, models.VRFRequestStatus_ACTIVE)
+
+	job1 := createCommittedVRFRequestJob(t, store, req1, models.VRFRequestJobStatus_FAILED, req1.BlockNum, req1.BlockNum+1)
+
+	job2, err := store.RetryVRFRequest(req1.ID)
+	require.NoError(t, err)
+
+	jobs, err := store.FindCommittedVRFRequestJobs(req1.BlockNum + 1)
+	require.NoError(t, err)
+	require.Len(t, jobs, 1)
+	validateVRFRequestJob(jobs[0], job2, t)
+	assert.Equal(t, job1.Secrets, jobs[0].Secrets)
+	assert.Equal(t, job1.CommitTxBlockNum, jobs[0].CommitTxBlockNum)
+}
+
+func TestORM_CancelVRFRequest(t *testing.T) {
+	t.Parallel()
+	store, cleanup := cltest.NewStore(t)
+	defer cleanup()
+
+	req1 := createVRFRequest(t, store, 1, "user1", 395235, models.VRFRequestType_IMMEDIATE, models.VRFRequestStatus_ACTIVE)
+
+	job1 := createVRFRequestJob(t, store, req1, models.VRFRequestJobStatus_ACTIVE, 0, null.Time{})
+	run1 := models.NewVRFRequestRun(job1)
+	err := store.SaveVRFRequestRun(run1, false)
+	require.NoError(t, err)
+
+	_, err = store.CancelVRFRequest(req1.ID, "Cancelled by an operator")
+	require.True(t, errors.Is(err, orm.ErrVRFRequestStateConflict), "request with a running job can not be cancelled")
+
+	run1.UpdateStatus(models.VRFRequestRunStatus_FAILED, "Calling SetRand on contract failed", 3)
+	err = store.SaveVRFRequestRun(run1, false)
+	require.NoError(t, err)
+
+	req, err := store.CancelVRFRequest(req1.ID, "Cancelled by an operator")
+	require.NoError(t, err)
+	assert.Equal(t, models.VRFRequestStatus(models.VRFRequestStatus_CANCELLED), req.Status)
+
+	jobs, runsByJob, err := store.FindVRFRequestJobsWithRuns(req1.ID)
+	require.NoError(t, err)
+	require.Len(t, jobs, 1)
+	assert.Equal(t, models.VRFRequestJobStatus(models.VRFRequestJobStatus_FAILED), jobs[0].Status)
+	assert.True(t, jobs[0].EndAt.Valid)
+	runs := runsByJob[job1.ID]
+	require.Len(t, runs, 2)
+	validateVRFRequestRun(runs[0], run1, t)
+	assert.Equal(t, models.VRFRequestRunStatus(models.VRFRequestRunStatus_FAILED), runs[1].Status)
+	assert.Equal(t, "Cancelled by an operator", runs[1].StatusMsg)
+
+	_, err = store.CancelVRFRequest(req1.ID, "Cancelled by an operator")
+	require.True(t, errors.Is(err, orm.ErrVRFRequestStateConflict), "request is already cancelled")
+	_, err = store.RetryVRFRequest(req1.ID)
+	require.True(t, errors.Is(err, orm.ErrVRFRequestStateConflict), "cancelled request can not be retried")
+}
+
+func TestORM_FailInterruptedVRFRequestRuns(t *testing.T) {
+	t.Parallel()
+	store, cleanup := cltest.NewStore(t)
+	defer cleanup()
+
+	withoutJob := createVRFRequest(t, store, 1, "user1", 395235, models.VRFRequestType_IMMEDIATE, models.VRFRequestStatus_ACTIVE)
+	createVRFRequest(t, store, 1, "user1", 395235, models.VRFRequestType_SCHEDULED, models.VRFRequestStatus_ACTIVE)
+	req := createVRFRequest(t, store, 1, "user1", 395235, models.VRFRequestType_IMMEDIATE, models.VRFRequestStatus_ACTIVE)
+	running := createVRFRequestJob(t, store, req, models.VRFRequestJobStatus_RUNNING, 0, null.Time{})
+	notStarted := createVRFRequestJob(t, store, req, models.VRFRequestJobStatus_ACTIVE, 0, null.Time{})
+	createVRFRequestJob(t, store, req, models.VRFRequestJobStatus_ACTIVE, 1, null.Time{})
+	createVRFRequestJob(t, store, req, models.VRFRequestJobStatus_COMPLETED, 0, null.Time{})
+	lastReq := createVRFRequest(t, store, 1, "user1", 395235, models.VRFRequestType_IMMEDIATE, models.VRFRequestStatus_ACTIVE)
+	lastRetry := createVRFRequestJob(t, store, lastReq, models.VRFRequestJobStatus_RUNNING, 3, null.Time{})
+
+	reqs, err := store.FindVRFRequestsWithoutJobs(models.VRFRequestType_IMMEDIATE)
+	require.NoError(t, err)
+	require.Len(t, reqs, 1)
+	validateVRFRequest(reqs[0], withoutJob, t)
+
+	run1 := models.NewVRFRequestRun(running)
+	err = store.SaveVRFRequestRun(run1, false)
+	require.NoError(t, err)
+
+	retryAt := time.Now().Add(time.Minute).Truncate(time.Second)
+	err = store.FailInterruptedVRFRequestRuns("Interrupted", 3, retryAt)
+	require.NoError(t, err)
+
+	run2, err := store.FindVRFRequestRun(run1.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.VRFRequestRunStatus(models.VRFRequestRunStatus_FAILED), run2.Status)
+	assert.Equal(t, "Interrupted", run2.StatusMsg)
+	assert.True(t, run2.EndAt.Valid)
+	//The interruption counts as a retry, the job is left to the retry worker
+	job, err := store.FindVRFRequestJob(running.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.VRFRequestJobStatus(models.VRFRequestJobStatus_ACTIVE), job.Status)
+	assert.Equal(t, uint8(1), job.Retries)
+	assert.True(t, retryAt.Equal(job.RetryAt.Time))
+	//Out of retries, the job fails and its request is completed
+	job, err = store.FindVRFRequestJob(lastRetry.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.VRFRequestJobStatus(models.VRFRequestJobStatus_FAILED), job.Status)
+	assert.True(t, job.EndAt.Valid)
+	stored, err := store.FindVRFRequest(lastReq.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.VRFRequestStatus(models.VRFRequestStatus_COMPLETED), stored.Status)
+	stored, err = store.FindVRFRequest(req.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.VRFRequestStatus(models.VRFRequestStatus_ACTIVE), stored.Status)
+
+	jobs, err := store.FindQueuedVRFRequestJobs()
+	require.NoError(t, err)
+	require.Len(t, jobs, 1)
+	validateVRFRequestJob(jobs[0], notStarted, t)
+	validateVRFRequest(jobs[0].VRFRequest, req, t)
+}
+
+func TestORM_StartVRFRequestJob(t *testing.T) {
+	t.Parallel()
+	store, cleanup := cltest.NewStore(t)
+	defer cleanup()
+
+	req1 := createVRFRequest(t, store, 1, "user1", 395235, models.VRFRequestType_IMMEDIATE, models.VRFRequestStatus_ACTIVE)
+	job1 := createVRFRequestJob(t, store, req1, models.VRFRequestJobStatus_ACTIVE, 0, null.Time{})
+
+	started, err := store.StartVRFRequestJob(job1)
+	require.NoError(t, err)
+	assert.True(t, started)
+	assert.Equal(t, models.VRFRequestJobStatus(models.VRFRequestJobStatus_RUNNING), job1.Status)
+	//A job that is already running is not started twice
+	started, err = store.StartVRFRequestJob(job1)
+	require.NoError(t, err)
+	assert.False(t, started)
+
+	job1.Status = models.VRFRequestJobStatus_ACTIVE
+	err = store.SaveVRFRequestJob(job1)
+	require.NoError(t, err)
+	_, err = store.CancelVRFRequest(req1.ID, "Cancelled by an operator")
+	require.NoError(t, err)
+	//The job of a cancelled request is not started, even from a stale copy
+	job1.Status = models.VRFRequestJobStatus_ACTIVE
+	started, err = store.StartVRFRequestJob(job1)
+	require.NoError(t, err)
+	assert.False(t, started)
+	job, err := store.FindVRFRequestJob(job1.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.VRFRequestJobStatus(models.VRFRequestJobStatus_FAILED), job.Status)
+}
+
+func TestORM_FindVRFRequestAtBlock(t *testing.T) {
+	t.Parallel()
+	store, cleanup := cltest.NewStore(t)
+	defer cleanup()
+
+	req1 := createVRFRequest(t, store, 4, "user4", 395235, models.VRFRequestType_IMMEDIATE, models.VRFRequestStatus_PENDING)
+
+	req2, err := store.FindVRFRequestAtBlock("user4", 4, req1.BlockHash)
+	require.NoError(t, err)
+	require.NotNil(t, req2)
+	validateVRFRequest(req2, req1, t)
+
+	req2, err = store.FindVRFRequestAtBlock("user4", 4, "000607e31b8d9da0ade735bcd19a9f8812335b510482d4b9f8c53753e0ebe9c8")
+	require.NoError(t, err)
+	assert.Nil(t, req2)
+
+	req2, err = store.FindVRFRequestAtBlock("user5", 4, req1.BlockHash)
+	require.NoError(t, err)
+	assert.Nil(t, req2)
+}
+
+func TestORM_MarkVRFRequestsRemoved(t *testing.T) {
+	t.Parallel()
+	store, cleanup := cltest.NewStore(t)
+	defer cleanup()
+
+	removedAt := func(req *models.VRFRequest) uint64 {
+		stored, err := store.FindVRFRequest(req.ID)
+		require.NoError(t, err)
+		return stored.RemovedBlockNum
+	}
+	req1 := createVRFRequest(t, store, 4, "user4", 100, models.VRFRequestType_IMMEDIATE, models.VRFRequestStatus_ACTIVE)
+	req2 := createVRFRequest(t, store, 4, "user4", 110, models.VRFRequestType_IMMEDIATE, models.VRFRequestStatus_ACTIVE)
+	later := createVRFRequest(t, store, 4, "user4", 130, models.VRFRequestType_IMMEDIATE, models.VRFRequestStatus_ACTIVE)
+	other := createVRFRequest(t, store, 5, "user4", 100, models.VRFRequestType_IMMEDIATE, models.VRFRequestStatus_ACTIVE)
+
+	marked, err := store.MarkVRFRequestsRemoved("user4", 4, 120, &models.Cursor{ID: models.CursorID_VRF_REQUESTS, Cursor: "cursor1"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), marked)
+	assert.Equal(t, uint64(120), removedAt(req1))
+	assert.Equal(t, uint64(120), removedAt(req2))
+	assert.Equal(t, uint64(0), removedAt(later))
+	assert.Equal(t, uint64(0), removedAt(other))
+	cursor, err := store.FindCursor(models.CursorID_VRF_REQUESTS)
+	require.NoError(t, err)
+	assert.Equal(t, "cursor1", cursor.Cursor)
+
+	//Requests already marked keep the block of the first removal
+	marked, err = store.MarkVRFRequestsRemoved("user4", 4, 140, nil)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), marked)
+	assert.Equal(t, uint64(120), removedAt(req1))
+	assert.Equal(t, uint64(140), removedAt(later))
+
+	unmarked, err := store.UnmarkVRFRequestsRemoved("user4", 4, 140, nil)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), unmarked)
+	assert.Equal(t, uint64(120), removedAt(req1))
+	assert.Equal(t, uint64(0), removedAt(later))
+}
+
+func TestORM_VRFDeadLetters(t *testing.T) {
+	t.Parallel()
+	store, cleanup := cltest.NewStore(t)
+	defer cleanup()
+
+	newDeadLetter := func(cursor string) *models.VRFDeadLetter {
+		deadLetter := &models.VRFDeadLetter{
+			Code:      "vrf",
+			JobTable:  "jobs",
+			Scope:     "user1",
+			Operation: "OPERATION_INSERT",
+			ForkStep:  "STEP_NEW",
+			NewData:   `{"assoc_id":"a"}`,
+			BlockNum:  395235,
+			BlockHash: "000607e21b8d9da0ade735bcd19a9f8812335b510482d4b9f8c53753e0ebe9c8",
+			Cursor:    cursor,
+			Error:     "Error unmarshalling",
+			Status:    models.VRFDeadLetterStatus_FAILED,
+		}
+		err := store.CreateVRFDeadLetter(deadLetter, &models.Cursor{
+			ID:     models.CursorID_VRF_REQUESTS,
+			Cursor: cursor,
+		})
+		require.NoError(t, err)
+		return deadLetter
+	}
+	dl1 := newDeadLetter("c1")
+	dl2 := newDeadLetter("c2")
+
+	cursor, err := store.FindCursor(models.CursorID_VRF_REQUESTS)
+	require.NoError(t, err)
+	assert.Equal(t, "c2", cursor.Cursor)
+
+	marked, err := store.MarkVRFDeadLettersForReplay(dl2.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), marked)
+
+	deadLetters, err := store.FindVRFDeadLetters(models.VRFDeadLetterStatus_REPLAY)
+	require.NoError(t, err)
+	require.Len(t, deadLetters, 1)
+	assert.Equal(t, dl2.ID, deadLetters[0].ID)
+	assert.Equal(t, dl2.NewData, deadLetters[0].NewData)
+
+	deadLetters[0].Status = models.VRFDeadLetterStatus_REPLAYED
+	err = store.SaveVRFDeadLetter(deadLetters[0])
+	require.NoError(t, err)
+
+	marked, err = store.MarkVRFDeadLettersForReplay()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), marked)
+
+	deadLetters, err = store.FindVRFDeadLetters("")
+	require.NoError(t, err)
+	require.Len(t, deadLetters, 2)
+	assert.Equal(t, dl1.ID, deadLetters[0].ID)
+	assert.Equal(t, models.VRFDeadLetterStatus(models.VRFDeadLetterStatus_REPLAY), deadLetters[0].Status)
+	assert.Equal(t, models.VRFDeadLetterStatus(models.VRFDeadLetterStatus_REPLAYED), deadLetters[1].Status)
+}
+
+func TestORM_VRFKeyRotations(t *testing.T) {
+	t.Parallel()
+	store, cleanup := cltest.NewStore(t)
+	defer cleanup()
+
+	last, err := store.FindLastVRFKeyRotation()
+	require.NoError(t, err)
+	assert.Nil(t, last)
+
+	newRotation := func(oldKey, newKey string) *models.VRFKeyRotation {
+		rotation := &models.VRFKeyRotation{
+			OldKey:       oldKey,
+			OldKeyHash:   "0x" + oldKey,
+			NewKey:       newKey,
+			NewKeyHash:   "0x" + newKey,
+			RegisterTxID: "tx" + newKey,
+			Status:       models.VRFKeyRotationStatus_GRACE,
+			GraceEndsAt:  time.Now().Add(time.Hour),
+		}
+		require.NoError(t, store.SaveVRFKeyRotation(rotation))
+		return rotation
+	}
+	r1 := newRotation("k1", "k2")
+	r1.Status = models.VRFKeyRotationStatus_COMPLETED
+	r1.CompletedAt = null.TimeFrom(time.Now())
+	require.NoError(t, store.SaveVRFKeyRotation(r1))
+	r2 := newRotation("k2", "k3")
+
+	last, err = store.FindLastVRFKeyRotation()
+	require.NoError(t, err)
+	require.NotNil(t, last)
+	assert.Equal(t, r2.ID, last.ID)
+	assert.Equal(t, "k3", last.NewKey)
+	assert.False(t, last.GraceEnded(time.Now()))
+
+	rotations, err := store.FindVRFKeyRotations()
+	require.NoError(t, err)
+	require.Len(t, rotations, 2)
+	assert.Equal(t, r1.ID, rotations[0].ID)
+	assert.Equal(t, models.VRFKeyRotationStatus(models.VRFKeyRotationStatus_COMPLETED), rotations[0].Status)
+	assert.True(t, rotations[0].CompletedAt.Valid)
+	assert.False(t, rotations[1].CompletedAt.Valid)
+}
+
+func TestORM_EncryptedVRFKeyShares(t *testing.T) {
+	t.Parallel()
+	store, cleanup := cltest.NewStore(t)
+	defer cleanup()
+
+	shares, err := vrfkey.GenerateKeyShares(2, 3)
+	require.NoError(t, err)
+	found, err := store.FindEncryptedVRFKeyShare(shares[0].GroupPublicKey())
+	require.NoError(t, err)
+	assert.Nil(t, found)
+
+	encrypted, err := shares[1].Encrypt(cltest.Password, utils.FastScryptParams)
+	require.NoError(t, err)
+	require.NoError(t, store.CreateEncryptedVRFKeyShare(encrypted))
+	//A node holds one share of each group key
+	other, err := shares[2].Encrypt(cltest.Password, utils.FastScryptParams)
+	require.NoError(t, err)
+	assert.Error(t, store.CreateEncryptedVRFKeyShare(other))
+
+	found, err = store.FindEncryptedVRFKeyShare(shares[0].GroupPublicKey())
+	require.NoError(t, err)
+	require.NotNil(t, found)
+	assert.Equal(t, shares[1].Commitments, found.Commitments)
+	decrypted, err := found.Decrypt(cltest.Password)
+	require.NoError(t, err)
+	assert.Equal(t, shares[1].PublicKey, decrypted.PublicKey)
+	assert.Equal(t, 1, decrypted.Index)
+
+	all, err := store.FindEncryptedVRFKeyShares()
+	require.NoError(t, err)
+	assert.Len(t, all, 1)
+}
+
+// createVRFRequest stores a request with the fixture block hash and seeds, recurrent requests run every hour
+func createVRFRequest(t *testing.T, store *strpkg.Store, assocID uint64, caller string, blockNum uint64, reqType models.VRFRequestType, status models.VRFRequestStatus) *models.VRFRequest {
+	req := &models.VRFRequest{
+		AssocID:   assocID,
+		BlockNum:  blockNum,
+		BlockHash: "000607e21b8d9da0ade735bcd19a9f8812335b510482d4b9f8c53753e0ebe9c8",
+		Seeds:     pq.StringArray([]string{"2"}),
+		Count:     1,
+		Caller:    caller,
+		Type:      reqType,
+		Status:    status,
+	}
+	if reqType == models.VRFRequestType_RECURRENT {
+		req.Frequency = "@every 1h"
+	}
+	err := store.CreateVRFRequest(req, nil)
+	require.NoError(t, err)
+	return req
+}
+
+// createVRFRequestJob stores a job of the request at the block of the request
+func createVRFRequestJob(t *testing.T, store *strpkg.Store, req *models.VRFRequest, status models.VRFRequestJobStatus, retries uint8, retryAt null.Time) *models.VRFRequestJob {
+	job := models.NewVRFRequestJob(req)
+	job.Status = status
+	job.Retries = retries
+	job.RetryAt = retryAt
+	err := store.SaveVRFRequestJob(job)
+	require.NoError(t, err)
+	return job
+}
+
+// createCommittedVRFRequestJob stores a job of the request at the block, with the secrets it committed to in
+// the block commitTxBlockNum
+func createCommittedVRFRequestJob(t *testing.T, store *strpkg.Store, req *models.VRFRequest, status models.VRFRequestJobStatus, blockNum, commitTxBlockNum uint64) *models.VRFRequestJob {
+	job := models.NewVRFRequestJobAtBlock(req, blockNum, req.BlockHash)
+	job.Status = status
+	job.Secrets = pq.StringArray{"b5a2c96250612366ea272ffac6d9744aaf4b45aacd96aa7cfcb931ee3b558259"}
+	job.CommittedAt = null.TimeFrom(time.Now())
+	job.CommitTxBlockNum = commitTxBlockNum
+	err := store.SaveVRFRequestJob(job)
+	require.NoError(t, err)
+	return job
+}
+
 func validateVRFRequest(actual *models.VRFRequest, expected *models.VRFRequest, t *testing.T) {
 	assert.Equal(t, actual.ID, expected.ID)
 	assert.Equal(t, actual.AssocID, expected.AssocID)
@@ -130,8 +130,47 @@ type ConfigSchema struct {
 	VRFContract                               string          `env:"VRF_CONTRACT"`
 	VRFJobTable                               string          `env:"VRF_JOB_TABLE" default:"jobs"`
 	VRFContractKey                            string          `env:"VRF_CONTRACT_KEY"`
+	VRFFulfilmentActor                        string          `env:"VRF_FULFILMENT_ACTOR"`
+	VRFFulfilmentPermission                   string          `env:"VRF_FULFILMENT_PERMISSION" default:"active"`
+	VRFFulfilmentKeys                         string          `env:"VRF_FULFILMENT_KEYS"`
+	VRFSeedType                               string          `env:"VRF_SEED_TYPE" default:"uint64"`
 	VRFKeyStorePassword                       string          `env:"VRF_KEY_STORE_PASSWORD"`
+	VRFDefaultKey                             string          `env:"VRF_DEFAULT_KEY"`
+	VRFQuorum                                 uint8           `env:"VRF_QUORUM" default:"0"`
+	VRFCallerStrategies                       string          `env:"VRF_CALLER_STRATEGIES"`
+	VRFRevealInterval                         time.Duration   `env:"VRF_REVEAL_INTERVAL" default:"5s"`
+	VRFConfirmInterval                        time.Duration   `env:"VRF_CONFIRM_INTERVAL" default:"10s"`
+	VRFKeyReloadInterval                      time.Duration   `env:"VRF_KEY_RELOAD_INTERVAL" default:"1m"`
 	VRFHeartBeatFrequency                     uint32          `env:"VRF_HEART_BEAT_FREQUENCY" default:"100"`
+	VRFWaitIrreversible                       bool            `env:"VRF_WAIT_IRREVERSIBLE" default:"false"`
+	VRFRetryInterval                          time.Duration   `env:"VRF_RETRY_INTERVAL" default:"10s"`
+	VRFRetryMinBackoff                        time.Duration   `env:"VRF_RETRY_MIN_BACKOFF" default:"5s"`
+	VRFRetryMaxBackoff                        time.Duration   `env:"VRF_RETRY_MAX_BACKOFF" default:"5m"`
+	VRFDeadLetterReplayInterval               time.Duration   `env:"VRF_DEAD_LETTER_REPLAY_INTERVAL" default:"30s"`
+	VRFWorkers                                uint16          `env:"VRF_WORKERS" default:"4"`
+	VRFOrderPerCaller                         bool            `env:"VRF_ORDER_PER_CALLER" default:"true"`
+	VRFMetricsPort                            uint16          `env:"VRF_METRICS_PORT" default:"0"`
+	VRFAPIPort                                uint16          `env:"VRF_API_PORT" default:"0"`
+	VRFBatchSize                              uint16          `env:"VRF_BATCH_SIZE" default:"1"`
+	VRFBatchWindow                            time.Duration   `env:"VRF_BATCH_WINDOW" default:"500ms"`
+	VRFBatchMaxBytes                          uint32          `env:"VRF_BATCH_MAX_BYTES" default:"65536"`
+	VRFResourceMonitorInterval                time.Duration   `env:"VRF_RESOURCE_MONITOR_INTERVAL" default:"1m"`
+	VRFResourceWarnPercent                    uint8           `env:"VRF_RESOURCE_WARN_PERCENT" default:"80"`
+	VRFResourcePausePercent                   uint8           `env:"VRF_RESOURCE_PAUSE_PERCENT" default:"98"`
+	VRFThresholdGroupKey                      string          `env:"VRF_THRESHOLD_GROUP_KEY"`
+	VRFThresholdPeerIDs                       string          `env:"VRF_THRESHOLD_PEER_IDS"`
+	VRFThresholdTimeout                       time.Duration   `env:"VRF_THRESHOLD_TIMEOUT" default:"30s"`
+	EOSRentMode                               string          `env:"EOS_RENT_MODE"`
+	EOSRentPayer                              string          `env:"EOS_RENT_PAYER"`
+	EOSRentPayerKey                           string          `env:"EOS_RENT_PAYER_KEY"`
+	EOSRentPayment                            string          `env:"EOS_RENT_PAYMENT" default:"1.0000 TLOS"`
+	EOSRentDailyBudget                        string          `env:"EOS_RENT_DAILY_BUDGET" default:"10.0000 TLOS"`
+	EOSPowerupDays                            uint32          `env:"EOS_POWERUP_DAYS" default:"1"`
+	EOSPowerupCPUFrac                         uint64          `env:"EOS_POWERUP_CPU_FRAC" default:"100000000"`
+	EOSPowerupNetFrac                         uint64          `env:"EOS_POWERUP_NET_FRAC" default:"10000000"`
+	EOSTxCheckInterval                        time.Duration   `env:"EOS_TX_CHECK_INTERVAL" default:"5s"`
+	EOSTxExpiration                           time.Duration   `env:"EOS_TX_EXPIRATION" default:"30s"`
+	EOSTxMaxAttempts                          uint32          `env:"EOS_TX_MAX_ATTEMPTS" default:"5"`
 }
 
 // EnvVarName gets the environment variable name for a config schema field
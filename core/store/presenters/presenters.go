@@ -702,3 +702,146 @@ func NewExplorerStatus(statsPusher synchronization.StatsPusher) ExplorerStatus {
 		Url:    url.String(),
 	}
 }
+
+// VRFRequest is a jsonapi wrapper for an EOS VRF request, the jobs are only
+// included in the detail view.
+type VRFRequest struct {
+	ID              uint64                  `json:"-"`
+	AssocID         uint64                  `json:"assocId"`
+	Caller          string                  `json:"caller"`
+	Type            models.VRFRequestType   `json:"type"`
+	Status          models.VRFRequestStatus `json:"status"`
+	BlockNum        uint64                  `json:"blockNum"`
+	BlockHash       string                  `json:"blockHash"`
+	Seeds           []string                `json:"seeds"`
+	Frequency       string                  `json:"frequency,omitempty"`
+	Count           uint8                   `json:"count"`
+	KeyHash         string                  `json:"keyHash,omitempty"`
+	ValueCount      uint16                  `json:"valueCount"`
+	ValueMin        uint64                  `json:"valueMin"`
+	ValueMax        uint64                  `json:"valueMax"`
+	UniqueValues    bool                    `json:"uniqueValues"`
+	StartAt         null.Time               `json:"startAt"`
+	EndAt           null.Time               `json:"endAt"`
+	RemovedBlockNum uint64                  `json:"removedBlockNum,omitempty"`
+	CreatedAt       time.Time               `json:"createdAt"`
+	UpdatedAt       time.Time               `json:"updatedAt"`
+	Jobs            []VRFRequestJob         `json:"jobs,omitempty"`
+}
+
+// VRFRequestJob is a job of a VRFRequest with its runs.
+type VRFRequestJob struct {
+	ID               uint64                     `json:"id"`
+	BlockNum         uint64                     `json:"blockNum"`
+	BlockHash        string                     `json:"blockHash"`
+	Status           models.VRFRequestJobStatus `json:"status"`
+	Retries          uint8                      `json:"retries"`
+	RetryAt          null.Time                  `json:"retryAt"`
+	CommittedAt      null.Time                  `json:"committedAt"`
+	CommitTxBlockNum uint64                     `json:"commitTxBlockNum,omitempty"`
+	StartAt          time.Time                  `json:"startAt"`
+	EndAt            null.Time                  `json:"endAt"`
+	Runs             []VRFRequestRun            `json:"runs"`
+}
+
+// VRFRequestRun is an attempt to fulfil a VRFRequestJob, with the transaction
+// it pushed and the proofs in it.
+type VRFRequestRun struct {
+	ID          uint64                     `json:"id"`
+	Status      models.VRFRequestRunStatus `json:"status"`
+	StatusMsg   string                     `json:"statusMsg"`
+	TxID        string                     `json:"txId,omitempty"`
+	TxBlockNum  uint64                     `json:"txBlockNum,omitempty"`
+	Proofs      *models.JSON               `json:"proofs,omitempty"`
+	KeyHash     string                     `json:"keyHash,omitempty"`
+	StartAt     time.Time                  `json:"startAt"`
+	EndAt       null.Time                  `json:"endAt"`
+	ConfirmedAt null.Time                  `json:"confirmedAt"`
+}
+
+// NewVRFRequest creates an instance of VRFRequest without its jobs.
+func NewVRFRequest(req models.VRFRequest) VRFRequest {
+	return VRFRequest{
+		ID:              req.ID,
+		AssocID:         req.AssocID,
+		Caller:          req.Caller,
+		Type:            req.Type,
+		Status:          req.Status,
+		BlockNum:        req.BlockNum,
+		BlockHash:       req.BlockHash,
+		Seeds:           req.Seeds,
+		Frequency:       req.Frequency,
+		Count:           req.Count,
+		KeyHash:         req.KeyHash,
+		ValueCount:      req.ValueCount,
+		ValueMin:        req.ValueMin,
+		ValueMax:        req.ValueMax,
+		UniqueValues:    req.UniqueValues,
+		StartAt:         req.StartAt,
+		EndAt:           req.EndAt,
+		RemovedBlockNum: req.RemovedBlockNum,
+		CreatedAt:       req.CreatedAt,
+		UpdatedAt:       req.UpdatedAt,
+	}
+}
+
+// NewVRFRequestWithJobs creates an instance of VRFRequest that includes its
+// jobs, runsByJob are the runs of each job indexed by job id. The secrets of
+// commit-reveal jobs are left out, as they must not be disclosed before they
+// are revealed.
+func NewVRFRequestWithJobs(req models.VRFRequest, jobs []*models.VRFRequestJob, runsByJob map[uint64][]*models.VRFRequestRun) VRFRequest {
+	presenter := NewVRFRequest(req)
+	presenter.Jobs = make([]VRFRequestJob, 0, len(jobs))
+	for _, job := range jobs {
+		runs := make([]VRFRequestRun, 0, len(runsByJob[job.ID]))
+		for _, run := range runsByJob[job.ID] {
+			runs = append(runs, VRFRequestRun{
+				ID:          run.ID,
+				Status:      run.Status,
+				StatusMsg:   run.StatusMsg,
+				TxID:        run.TxID,
+				TxBlockNum:  run.TxBlockNum,
+				Proofs:      run.Proofs,
+				KeyHash:     run.KeyHash,
+				StartAt:     run.StartAt,
+				EndAt:       run.EndAt,
+				ConfirmedAt: run.ConfirmedAt,
+			})
+		}
+		presenter.Jobs = append(presenter.Jobs, VRFRequestJob{
+			ID:               job.ID,
+			BlockNum:         job.BlockNum,
+			BlockHash:        job.BlockHash,
+			Status:           job.Status,
+			Retries:          job.Retries,
+			RetryAt:          job.RetryAt,
+			CommittedAt:      job.CommittedAt,
+			CommitTxBlockNum: job.CommitTxBlockNum,
+			StartAt:          job.StartAt,
+			EndAt:            job.EndAt,
+			Runs:             runs,
+		})
+	}
+	return presenter
+}
+
+// GetID returns the jsonapi ID.
+func (r VRFRequest) GetID() string {
+	return strconv.FormatUint(r.ID, 10)
+}
+
+// GetName returns the collection name for jsonapi.
+func (VRFRequest) GetName() string {
+	return "vrf_requests"
+}
+
+// SetID is used to conform to the UnmarshallIdentifier interface for
+// deserializing from jsonapi documents.
+func (r *VRFRequest) SetID(value string) error {
+	id, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return err
+	}
+	r.ID = id
+	return nil
+}
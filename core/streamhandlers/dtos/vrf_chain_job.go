@@ -4,8 +4,11 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/sebastianmontero/vrf-oracle/core/services/vrf"
 	"github.com/sebastianmontero/vrf-oracle/core/store/models"
+	null "gopkg.in/guregu/null.v4"
 )
 
 //Required becuase sometimes unit64 is sent as string
@@ -28,36 +31,118 @@ func (m *UINT64) UnmarshalJSON(b []byte) error {
 	return nil
 }
 
+//SEED a uint64, uint128 or checksum256 seed as rendered by the chain, it is parsed once its type is known,
+//as a checksum256 can not be told apart from a decimal number
+type SEED string
+
+func (m *SEED) UnmarshalJSON(b []byte) error {
+	*m = SEED(strings.ReplaceAll(string(b), `"`, ""))
+	return nil
+}
+
+//eosio time_point and time_point_sec format, always in UTC
+const eosTimeFormat = "2006-01-02T15:04:05"
+
+//TIME Parses eosio time_point and time_point_sec values, the epoch is considered as not set
+type TIME null.Time
+
+func (m *TIME) UnmarshalJSON(b []byte) error {
+	s := strings.ReplaceAll(string(b), `"`, "")
+	if s == "" || s == "null" {
+		*m = TIME(null.Time{})
+		return nil
+	}
+	t, err := time.Parse(eosTimeFormat, s)
+	if err != nil {
+		return err
+	}
+	*m = TIME(null.NewTime(t, t.Unix() > 0))
+	return nil
+}
+
 type VRFChainJob struct {
-	AssocID     UINT64   `json:"assoc_id,omitempty"`
-	Seeds       []STRING `json:"seeds,omitempty"`
-	CreatedDate string   `json:"created_date,omitempty"`
+	AssocID     UINT64 `json:"assoc_id,omitempty"`
+	Seeds       []SEED `json:"seeds,omitempty"`
+	Frequency   STRING `json:"frequency,omitempty"`
+	StartAt     TIME   `json:"start_at,omitempty"`
+	EndAt       TIME   `json:"end_at,omitempty"`
+	ValueCount  UINT64 `json:"value_count,omitempty"`
+	ValueMin    UINT64 `json:"value_min,omitempty"`
+	ValueMax    UINT64 `json:"value_max,omitempty"`
+	Unique      bool   `json:"unique,omitempty"`
+	KeyHash     STRING `json:"key_hash,omitempty"`
+	CreatedDate string `json:"created_date,omitempty"`
 	BlockNum    uint64
 	BlockHash   string
 	Caller      string
 }
 
-func (m *VRFChainJob) VRFRequest() *models.VRFRequest {
+//VRFRequest creates the request of the job, seedType is the abi type of the seeds in the jobs table.
+//Returns an error if the job requests more values than can be derived from a proof
+func (m *VRFChainJob) VRFRequest(seedType string) (*models.VRFRequest, error) {
+	if m.ValueCount > vrf.MaxExpandedValues {
+		return nil, fmt.Errorf("Value count: %v exceeds the maximum: %v", m.ValueCount, vrf.MaxExpandedValues)
+	}
+	seeds, err := m.decimalSeeds(seedType)
+	if err != nil {
+		return nil, err
+	}
 	return &models.VRFRequest{
 		AssocID:   uint64(m.AssocID),
 		BlockNum:  m.BlockNum,
 		BlockHash: m.BlockHash,
-		Seeds:     m.stringSeeds(),
+		Seeds:     seeds,
+		Frequency: string(m.Frequency),
 		Count:     uint8(len(m.Seeds)),
 		Caller:    m.Caller,
-		Type:      models.VRFRequestType_IMMEDIATE,
+		Type:      m.requestType(),
 		Status:    models.VRFRequestStatus_ACTIVE,
+		StartAt:   null.Time(m.StartAt),
+		EndAt:     null.Time(m.EndAt),
+
+		ValueCount:   uint16(m.ValueCount),
+		ValueMin:     uint64(m.ValueMin),
+		ValueMax:     uint64(m.ValueMax),
+		UniqueValues: m.Unique,
+		KeyHash:      m.keyHash(),
+	}, nil
+}
+
+//keyHash the hash of the key that has to generate the proofs, lowercase and without 0x prefix,
+//empty if the job does not select a key, which the contract renders as an all zeros checksum256
+func (m *VRFChainJob) keyHash() string {
+	keyHash := strings.ToLower(strings.TrimPrefix(string(m.KeyHash), "0x"))
+	if strings.Trim(keyHash, "0") == "" {
+		return ""
+	}
+	return keyHash
+}
+
+//requestType a job with a frequency is recurrent, one with only a start date is scheduled
+//and one with neither is resolved immediately
+func (m *VRFChainJob) requestType() models.VRFRequestType {
+	if m.Frequency != "" {
+		return models.VRFRequestType_RECURRENT
+	}
+	if m.StartAt.Valid {
+		return models.VRFRequestType_SCHEDULED
 	}
+	return models.VRFRequestType_IMMEDIATE
 }
 
 func (m *VRFChainJob) String() string {
-	return fmt.Sprintf("\nVRFChainJob{ \n\tAssocID: %v, \n\tSeeds: %v, \n\tCreatedDate: %v\n}", m.AssocID, m.Seeds, m.CreatedDate)
+	return fmt.Sprintf("\nVRFChainJob{ \n\tAssocID: %v, \n\tSeeds: %v, \n\tFrequency: %v, \n\tStartAt: %v, \n\tEndAt: %v, \n\tCreatedDate: %v\n}", m.AssocID, m.Seeds, m.Frequency, m.StartAt.Time, m.EndAt.Time, m.CreatedDate)
 }
 
-func (m *VRFChainJob) stringSeeds() []string {
+//decimalSeeds parses the seeds as seedType and returns their decimal representation
+func (m *VRFChainJob) decimalSeeds(seedType string) ([]string, error) {
 	ss := make([]string, 0, len(m.Seeds))
 	for _, seed := range m.Seeds {
-		ss = append(ss, string(seed))
+		parsed, err := vrf.ParseEOSSeed(string(seed), seedType)
+		if err != nil {
+			return nil, err
+		}
+		ss = append(ss, parsed.String())
 	}
-	return ss
+	return ss, nil
 }
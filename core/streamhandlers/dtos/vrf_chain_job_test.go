@@ -3,7 +3,11 @@ package dtos_test
 import (
 	"encoding/json"
 	"testing"
+	"time"
 
+	"github.com/lib/pq"
+	"github.com/sebastianmontero/vrf-oracle/core/services/vrf"
+	"github.com/sebastianmontero/vrf-oracle/core/store/models"
 	"github.com/sebastianmontero/vrf-oracle/core/streamhandlers/dtos"
 	"github.com/stretchr/testify/assert"
 )
@@ -40,3 +44,169 @@ func TestDTO_UnmarshallVRFChainJob(t *testing.T) {
 	assert.Equal(t, string(job.Seeds[0]), "1617081008500000")
 
 }
+
+func TestDTO_VRFChainJobRequestType(t *testing.T) {
+	//immediate
+	payload := `{
+		"assoc_id":1,
+		"seeds":[1],
+		"start_at":"1970-01-01T00:00:00.000",
+		"end_at":"1970-01-01T00:00:00.000"
+	}`
+	job := &dtos.VRFChainJob{}
+	err := json.Unmarshal([]byte(payload), job)
+	assert.NoError(t, err)
+	req, err := job.VRFRequest(vrf.EOSSeedType_UINT64)
+	assert.NoError(t, err)
+	assert.Equal(t, models.VRFRequestType(models.VRFRequestType_IMMEDIATE), req.Type)
+	assert.False(t, req.StartAt.Valid)
+	assert.False(t, req.EndAt.Valid)
+
+	//scheduled
+	payload = `{
+		"assoc_id":2,
+		"seeds":[1],
+		"start_at":"2021-03-30T05:10:08.5"
+	}`
+	job = &dtos.VRFChainJob{}
+	err = json.Unmarshal([]byte(payload), job)
+	assert.NoError(t, err)
+	req, err = job.VRFRequest(vrf.EOSSeedType_UINT64)
+	assert.NoError(t, err)
+	assert.Equal(t, models.VRFRequestType(models.VRFRequestType_SCHEDULED), req.Type)
+	assert.True(t, req.StartAt.Valid)
+	assert.Equal(t, time.Date(2021, 3, 30, 5, 10, 8, 500000000, time.UTC), req.StartAt.Time)
+	assert.False(t, req.EndAt.Valid)
+
+	//recurrent
+	payload = `{
+		"assoc_id":3,
+		"seeds":[1],
+		"frequency":"@every 6h",
+		"start_at":"2021-03-30T05:10:08",
+		"end_at":"2021-04-30T05:10:08"
+	}`
+	job = &dtos.VRFChainJob{}
+	err = json.Unmarshal([]byte(payload), job)
+	assert.NoError(t, err)
+	req, err = job.VRFRequest(vrf.EOSSeedType_UINT64)
+	assert.NoError(t, err)
+	assert.Equal(t, models.VRFRequestType(models.VRFRequestType_RECURRENT), req.Type)
+	assert.Equal(t, "@every 6h", req.Frequency)
+	assert.Equal(t, time.Date(2021, 3, 30, 5, 10, 8, 0, time.UTC), req.StartAt.Time)
+	assert.Equal(t, time.Date(2021, 4, 30, 5, 10, 8, 0, time.UTC), req.EndAt.Time)
+}
+
+func TestDTO_VRFChainJobWideSeeds(t *testing.T) {
+	payload := `{
+		"assoc_id":1,
+		"seeds":["340282366920938463463374607431768211455", 1]
+	}`
+	job := &dtos.VRFChainJob{}
+	err := json.Unmarshal([]byte(payload), job)
+	assert.NoError(t, err)
+	req, err := job.VRFRequest(vrf.EOSSeedType_UINT128)
+	assert.NoError(t, err)
+	assert.Equal(t, pq.StringArray{"340282366920938463463374607431768211455", "1"}, req.Seeds)
+	_, err = job.VRFRequest(vrf.EOSSeedType_UINT64)
+	assert.Error(t, err)
+
+	//uint128 seeds of firehose deltas are rendered by the abi decoder of eos-go, 0x followed by the bytes in little endian order
+	payload = `{
+		"assoc_id":1,
+		"seeds":["0x02000000000000000300000000000000", "0xffffffffffffffffffffffffffffffff"]
+	}`
+	job = &dtos.VRFChainJob{}
+	err = json.Unmarshal([]byte(payload), job)
+	assert.NoError(t, err)
+	req, err = job.VRFRequest(vrf.EOSSeedType_UINT128)
+	assert.NoError(t, err)
+	assert.Equal(t, pq.StringArray{"55340232221128654850", "340282366920938463463374607431768211455"}, req.Seeds)
+
+	//checksum256 seeds are hex strings even if they only have digits
+	payload = `{
+		"assoc_id":1,
+		"seeds":[
+			"00000000000000000000000000000000000000000000000000000000000000ff",
+			"0000000000000000000000000000000000000000000000000000000000000010",
+			"ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff"
+		]
+	}`
+	job = &dtos.VRFChainJob{}
+	err = json.Unmarshal([]byte(payload), job)
+	assert.NoError(t, err)
+	req, err = job.VRFRequest(vrf.EOSSeedType_CHECKSUM256)
+	assert.NoError(t, err)
+	assert.Equal(t, pq.StringArray{
+		"255",
+		"16",
+		"115792089237316195423570985008687907853269984665640564039457584007913129639935",
+	}, req.Seeds)
+
+	payload = `{
+		"assoc_id":1,
+		"seeds":["not a seed"]
+	}`
+	job = &dtos.VRFChainJob{}
+	err = json.Unmarshal([]byte(payload), job)
+	assert.NoError(t, err)
+	_, err = job.VRFRequest(vrf.EOSSeedType_UINT64)
+	assert.Error(t, err)
+}
+
+func TestDTO_VRFChainJobKeyHash(t *testing.T) {
+	payload := `{
+		"assoc_id":1,
+		"seeds":[1],
+		"key_hash":"0xC1A2B3C4D5E6F708091A2B3C4D5E6F708091A2B3C4D5E6F708091A2B3C4D5E6F7"
+	}`
+	job := &dtos.VRFChainJob{}
+	err := json.Unmarshal([]byte(payload), job)
+	assert.NoError(t, err)
+	req, err := job.VRFRequest(vrf.EOSSeedType_UINT64)
+	assert.NoError(t, err)
+	assert.Equal(t, "c1a2b3c4d5e6f708091a2b3c4d5e6f708091a2b3c4d5e6f708091a2b3c4d5e6f7", req.KeyHash)
+
+	//all zeros checksum256 means no key was selected
+	payload = `{
+		"assoc_id":1,
+		"seeds":[1],
+		"key_hash":"0000000000000000000000000000000000000000000000000000000000000000"
+	}`
+	job = &dtos.VRFChainJob{}
+	err = json.Unmarshal([]byte(payload), job)
+	assert.NoError(t, err)
+	req, err = job.VRFRequest(vrf.EOSSeedType_UINT64)
+	assert.NoError(t, err)
+	assert.Equal(t, "", req.KeyHash)
+}
+
+func TestDTO_VRFChainJobValueCount(t *testing.T) {
+	payload := `{
+		"assoc_id":1,
+		"seeds":[1],
+		"value_count":1000,
+		"value_min":1,
+		"value_max":6
+	}`
+	job := &dtos.VRFChainJob{}
+	err := json.Unmarshal([]byte(payload), job)
+	assert.NoError(t, err)
+	req, err := job.VRFRequest(vrf.EOSSeedType_UINT64)
+	assert.NoError(t, err)
+	assert.Equal(t, uint16(1000), req.ValueCount)
+
+	//65537 would be truncated to 1
+	payload = `{
+		"assoc_id":1,
+		"seeds":[1],
+		"value_count":65537,
+		"value_min":1,
+		"value_max":6
+	}`
+	job = &dtos.VRFChainJob{}
+	err = json.Unmarshal([]byte(payload), job)
+	assert.NoError(t, err)
+	_, err = job.VRFRequest(vrf.EOSSeedType_UINT64)
+	assert.EqualError(t, err, "Value count: 65537 exceeds the maximum: 1000")
+}
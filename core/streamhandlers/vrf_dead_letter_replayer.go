@@ -0,0 +1,82 @@
+package streamhandlers
+
+import (
+	"sync"
+	"time"
+
+	"github.com/sebastianmontero/vrf-oracle/core/logger"
+	storepkg "github.com/sebastianmontero/vrf-oracle/core/store"
+	"github.com/sebastianmontero/vrf-oracle/core/store/models"
+)
+
+//VRFDeadLetterReplayer periodically replays the dead letters that have been marked for replay,
+//dead letters that fail again go back to failed with the new error
+type VRFDeadLetterReplayer struct {
+	Store   *storepkg.Store
+	Handler *VRFDeltaHandler
+	chStop  chan struct{}
+	wg      sync.WaitGroup
+}
+
+func NewVRFDeadLetterReplayer(store *storepkg.Store, handler *VRFDeltaHandler) *VRFDeadLetterReplayer {
+	return &VRFDeadLetterReplayer{
+		Store:   store,
+		Handler: handler,
+	}
+}
+
+//Start starts polling for dead letters to replay
+func (m *VRFDeadLetterReplayer) Start() {
+	m.chStop = make(chan struct{})
+	m.wg.Add(1)
+	go m.run()
+}
+
+//Stop stops polling and waits for the dead letter being replayed to finish
+func (m *VRFDeadLetterReplayer) Stop() {
+	close(m.chStop)
+	m.wg.Wait()
+}
+
+func (m *VRFDeadLetterReplayer) run() {
+	defer m.wg.Done()
+	ticker := time.NewTicker(m.Store.Config.VRFDeadLetterReplayInterval())
+	defer ticker.Stop()
+	for {
+		select {
+		case <-m.chStop:
+			return
+		case <-ticker.C:
+			m.replay()
+		}
+	}
+}
+
+func (m *VRFDeadLetterReplayer) replay() {
+	deadLetters, err := m.Store.FindVRFDeadLetters(models.VRFDeadLetterStatus_REPLAY)
+	if err != nil {
+		logger.Errorf("Failed to find dead letters to replay: %v", err)
+		return
+	}
+	for _, deadLetter := range deadLetters {
+		select {
+		case <-m.chStop:
+			return
+		default:
+		}
+		logger.Infof("Replaying dead letter: %v", deadLetter)
+		deadLetter.Replays++
+		err := m.Handler.Replay(deadLetter)
+		if err != nil {
+			logger.Errorf("Failed to replay dead letter: %v, error: %v", deadLetter.ID, err)
+			deadLetter.Status = models.VRFDeadLetterStatus_FAILED
+			deadLetter.Error = err.Error()
+		} else {
+			deadLetter.Status = models.VRFDeadLetterStatus_REPLAYED
+		}
+		err = m.Store.SaveVRFDeadLetter(deadLetter)
+		if err != nil {
+			logger.Errorf("Failed to update dead letter: %v, error: %v", deadLetter.ID, err)
+		}
+	}
+}
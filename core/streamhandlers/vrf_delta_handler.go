@@ -2,8 +2,12 @@ package streamhandlers
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
 
 	"github.com/dfuse-io/bstream"
+	"github.com/jpillora/backoff"
 	pbcodec "github.com/dfuse-io/dfuse-eosio/pb/dfuse/eosio/codec/v1"
 	pbbstream "github.com/dfuse-io/pbgo/dfuse/bstream/v1"
 	"github.com/sebastianmontero/dfuse-firehose-client/dfclient"
@@ -13,43 +17,293 @@ import (
 	"github.com/sebastianmontero/vrf-oracle/core/streamhandlers/dtos"
 )
 
+//ErrMalformedDelta the delta can not be decoded into a vrf request, processing it again would fail again
+var ErrMalformedDelta = errors.New("Malformed delta")
+
+//VRFDeltaHandler stores the vrf requests found in the jobs table and sends them to the consumer,
+//if WaitIrreversible is set requests are stored as pending and only sent to the consumer once
+//their block becomes irreversible, pending requests in blocks that are undone get cancelled.
+//Requests whose row is removed from the jobs table are marked as removed, once the removal is
+//irreversible if WaitIrreversible is set.
+//Malformed deltas are stored as dead letters so that they can be replayed later, other errors are transient,
+//e.g. the db is down, so the delta is processed again with backoff without advancing the cursor
 type VRFDeltaHandler struct {
-	Cursor   string
-	JobTable string
-	Store    *storepkg.Store
-	Consumer chan *models.VRFRequest
+	Cursor           string
+	JobTable         string
+	WaitIrreversible bool
+	Store            *storepkg.Store
+	Consumer         chan *models.VRFRequest
+}
+
+//vrfDelta the information of a jobs table delta required to process it
+type vrfDelta struct {
+	Operation pbcodec.DBOp_Operation
+	Scope     string
+	OldData   []byte
+	NewData   []byte
+	BlockNum  uint64
+	BlockHash string
+}
+
+//ForkSteps fork steps the handler has to be subscribed to
+func (m *VRFDeltaHandler) ForkSteps() []pbbstream.ForkStep {
+	if m.WaitIrreversible {
+		return []pbbstream.ForkStep{
+			pbbstream.ForkStep_STEP_NEW,
+			pbbstream.ForkStep_STEP_UNDO,
+			pbbstream.ForkStep_STEP_IRREVERSIBLE,
+		}
+	}
+	return []pbbstream.ForkStep{pbbstream.ForkStep_STEP_NEW}
 }
 
 func (m *VRFDeltaHandler) OnDelta(delta *dfclient.TableDelta, cursor string, forkStep pbbstream.ForkStep) {
 	logger.Infof("On Delta: \nCursor: %v \nFork Step: %v \nDelta %v ", cursor, forkStep, delta)
 	if delta.TableName == m.JobTable {
-		switch delta.Operation {
-		case pbcodec.DBOp_OPERATION_INSERT, pbcodec.DBOp_OPERATION_UPDATE:
-			job := &dtos.VRFChainJob{}
-			err := json.Unmarshal(delta.NewData, job)
-			if err != nil {
-				logger.Panicf("Error unmarshalling vrf job table new data: %v error: %v", string(delta.NewData), err)
-			}
-			job.Caller = delta.Scope
-			job.BlockNum = uint64(delta.Block.Number)
-			job.BlockHash = delta.Block.Id
-			logger.Infof("VRFChainJob: ", job)
-			request := job.VRFRequest()
-			cr := &models.Cursor{
-				ID:     models.CursorID_VRF_REQUESTS,
-				Cursor: cursor,
-			}
-			logger.Infof("Storing: %v, v%", request, cr)
-			err = m.Store.CreateVRFRequest(request, cr)
+		cr := &models.Cursor{
+			ID:     models.CursorID_VRF_REQUESTS,
+			Cursor: cursor,
+		}
+		vrfDelta := &vrfDelta{
+			Operation: delta.Operation,
+			Scope:     delta.Scope,
+			OldData:   delta.OldData,
+			NewData:   delta.NewData,
+			BlockNum:  uint64(delta.Block.Number),
+			BlockHash: delta.Block.Id,
+		}
+		err := m.process(vrfDelta, forkStep, cr)
+		b := &backoff.Backoff{
+			Min:    m.Store.Config.VRFRetryMinBackoff(),
+			Max:    m.Store.Config.VRFRetryMaxBackoff(),
+			Factor: 2,
+		}
+		for err != nil && !errors.Is(err, ErrMalformedDelta) {
+			wait := b.Duration()
+			logger.Errorf("Failed to process delta, retrying in %v, error: %v", wait, err)
+			time.Sleep(wait)
+			err = m.process(vrfDelta, forkStep, cr)
+		}
+		if err != nil {
+			m.deadLetter(delta, forkStep, cr, err)
+		}
+	}
+	m.Cursor = cursor
+}
+
+//Replay processes a dead letter, the cursor is not updated as the stream has already moved past it
+func (m *VRFDeltaHandler) Replay(deadLetter *models.VRFDeadLetter) error {
+	operation, ok := pbcodec.DBOp_Operation_value[deadLetter.Operation]
+	if !ok {
+		return fmt.Errorf("Unknown operation: %v", deadLetter.Operation)
+	}
+	forkStep, ok := pbbstream.ForkStep_value[deadLetter.ForkStep]
+	if !ok {
+		return fmt.Errorf("Unknown fork step: %v", deadLetter.ForkStep)
+	}
+	return m.process(&vrfDelta{
+		Operation: pbcodec.DBOp_Operation(operation),
+		Scope:     deadLetter.Scope,
+		OldData:   toBytes(deadLetter.OldData),
+		NewData:   toBytes(deadLetter.NewData),
+		BlockNum:  deadLetter.BlockNum,
+		BlockHash: deadLetter.BlockHash,
+	}, pbbstream.ForkStep(forkStep), nil)
+}
+
+func (m *VRFDeltaHandler) process(delta *vrfDelta, forkStep pbbstream.ForkStep, cursor *models.Cursor) error {
+	switch forkStep {
+	case pbbstream.ForkStep_STEP_NEW:
+		return m.onNew(delta, cursor)
+	case pbbstream.ForkStep_STEP_UNDO:
+		return m.onUndo(delta, cursor)
+	case pbbstream.ForkStep_STEP_IRREVERSIBLE:
+		return m.onIrreversible(delta, cursor)
+	}
+	return nil
+}
+
+func (m *VRFDeltaHandler) onNew(delta *vrfDelta, cursor *models.Cursor) error {
+	switch delta.Operation {
+	case pbcodec.DBOp_OPERATION_INSERT, pbcodec.DBOp_OPERATION_UPDATE:
+		job, err := chainJob(delta, delta.NewData)
+		if err != nil {
+			return err
+		}
+		request, err := job.VRFRequest(m.Store.Config.VRFSeedType())
+		if err != nil {
+			return fmt.Errorf("%w, invalid vrf job: %v, error: %v", ErrMalformedDelta, job, err)
+		}
+		if m.WaitIrreversible {
+			request.Status = models.VRFRequestStatus_PENDING
+		}
+		err = m.createRequest(request, cursor)
+		if err != nil {
+			return err
+		}
+		if !m.WaitIrreversible {
+			m.Consumer <- request
+		}
+	case pbcodec.DBOp_OPERATION_REMOVE:
+		if !m.WaitIrreversible {
+			return m.markRemoved(delta, cursor)
+		}
+	}
+	return nil
+}
+
+//onUndo undo operations are reversed so the data written by the undone block is in the old data,
+//an undone removal only has new data, the row that is back in the table
+func (m *VRFDeltaHandler) onUndo(delta *vrfDelta, cursor *models.Cursor) error {
+	if len(delta.OldData) == 0 {
+		if len(delta.NewData) == 0 || m.WaitIrreversible {
+			return m.Store.SaveCursor(cursor)
+		}
+		return m.unmarkRemoved(delta, cursor)
+	}
+	job, err := chainJob(delta, delta.OldData)
+	if err != nil {
+		return err
+	}
+	request, err := m.findRequest(job)
+	if err != nil {
+		return err
+	}
+	if request == nil || request.Status != models.VRFRequestStatus_PENDING {
+		return m.Store.SaveCursor(cursor)
+	}
+	logger.Infof("Cancelling vrf request: %v, its block was undone", request)
+	request.Status = models.VRFRequestStatus_CANCELLED
+	return m.saveRequest(request, cursor)
+}
+
+func (m *VRFDeltaHandler) onIrreversible(delta *vrfDelta, cursor *models.Cursor) error {
+	switch delta.Operation {
+	case pbcodec.DBOp_OPERATION_INSERT, pbcodec.DBOp_OPERATION_UPDATE:
+		job, err := chainJob(delta, delta.NewData)
+		if err != nil {
+			return err
+		}
+		request, err := m.findRequest(job)
+		if err != nil {
+			return err
+		}
+		switch {
+		case request == nil:
+			//The new step was not seen, i.e. the stream started after it
+			request, err = job.VRFRequest(m.Store.Config.VRFSeedType())
 			if err != nil {
-				logger.Panicf("Failed to store vrf request: %v, error: %v", request, err)
+				return fmt.Errorf("%w, invalid vrf job: %v, error: %v", ErrMalformedDelta, job, err)
 			}
-			m.Consumer <- request
-		case pbcodec.DBOp_OPERATION_REMOVE:
-			logger.Tracef("Not handling vrf job removals: %v", string(delta.OldData))
+			err = m.createRequest(request, cursor)
+		case request.Status == models.VRFRequestStatus_PENDING:
+			request.Status = models.VRFRequestStatus_ACTIVE
+			err = m.saveRequest(request, cursor)
+		default:
+			return m.Store.SaveCursor(cursor)
+		}
+		if err != nil {
+			return err
 		}
+		m.Consumer <- request
+		return nil
+	case pbcodec.DBOp_OPERATION_REMOVE:
+		return m.markRemoved(delta, cursor)
+	default:
+		return m.Store.SaveCursor(cursor)
+	}
+}
+
+//markRemoved the contract removes the row of a request once it resolves it, the requests stored from the row
+//are marked so that the resolver does not fulfil them again. The removal happens in a later block than the
+//request, so it is matched by caller and assoc id to the requests stored up to the block of the removal
+func (m *VRFDeltaHandler) markRemoved(delta *vrfDelta, cursor *models.Cursor) error {
+	job, err := chainJob(delta, delta.OldData)
+	if err != nil {
+		return err
+	}
+	marked, err := m.Store.MarkVRFRequestsRemoved(job.Caller, uint64(job.AssocID), job.BlockNum, cursor)
+	if err != nil {
+		return fmt.Errorf("Failed to mark vrf requests of removed job: %v, error: %v", job, err)
+	}
+	logger.Infof("Marked %v vrf requests as removed from the jobs table, job: %v", marked, job)
+	return nil
+}
+
+//unmarkRemoved the block that removed the row was undone, so the requests stored from it are unresolved again
+func (m *VRFDeltaHandler) unmarkRemoved(delta *vrfDelta, cursor *models.Cursor) error {
+	job, err := chainJob(delta, delta.NewData)
+	if err != nil {
+		return err
+	}
+	unmarked, err := m.Store.UnmarkVRFRequestsRemoved(job.Caller, uint64(job.AssocID), job.BlockNum, cursor)
+	if err != nil {
+		return fmt.Errorf("Failed to unmark vrf requests of job: %v, error: %v", job, err)
+	}
+	logger.Infof("Unmarked %v vrf requests, the removal of job: %v was undone", unmarked, job)
+	return nil
+}
+
+func chainJob(delta *vrfDelta, data []byte) (*dtos.VRFChainJob, error) {
+	job := &dtos.VRFChainJob{}
+	err := json.Unmarshal(data, job)
+	if err != nil {
+		return nil, fmt.Errorf("%w, error unmarshalling vrf job table data: %v error: %v", ErrMalformedDelta, string(data), err)
+	}
+	job.Caller = delta.Scope
+	job.BlockNum = delta.BlockNum
+	job.BlockHash = delta.BlockHash
+	logger.Infof("VRFChainJob: %v", job)
+	return job, nil
+}
+
+func (m *VRFDeltaHandler) findRequest(job *dtos.VRFChainJob) (*models.VRFRequest, error) {
+	request, err := m.Store.FindVRFRequestAtBlock(job.Caller, uint64(job.AssocID), job.BlockHash)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to find vrf request for job: %v, error: %v", job, err)
+	}
+	return request, nil
+}
+
+func (m *VRFDeltaHandler) createRequest(request *models.VRFRequest, cursor *models.Cursor) error {
+	logger.Infof("Storing: %v, %v", request, cursor)
+	err := m.Store.CreateVRFRequest(request, cursor)
+	if err != nil {
+		return fmt.Errorf("Failed to store vrf request: %v, error: %v", request, err)
+	}
+	return nil
+}
+
+func (m *VRFDeltaHandler) saveRequest(request *models.VRFRequest, cursor *models.Cursor) error {
+	err := m.Store.SaveVRFRequest(request, cursor)
+	if err != nil {
+		return fmt.Errorf("Failed to update vrf request: %v, error: %v", request, err)
+	}
+	return nil
+}
+
+//deadLetter stores the malformed delta and advances the cursor,
+//if the dead letter can not be stored the oracle stops so that the delta is not lost
+func (m *VRFDeltaHandler) deadLetter(delta *dfclient.TableDelta, forkStep pbbstream.ForkStep, cursor *models.Cursor, err error) {
+	logger.Errorf("Failed to process delta, storing it as a dead letter, error: %v", err)
+	deadLetter := &models.VRFDeadLetter{
+		Code:      delta.Code,
+		JobTable:  delta.TableName,
+		Scope:     delta.Scope,
+		Operation: delta.Operation.String(),
+		ForkStep:  forkStep.String(),
+		OldData:   string(delta.OldData),
+		NewData:   string(delta.NewData),
+		BlockNum:  uint64(delta.Block.Number),
+		BlockHash: delta.Block.Id,
+		Cursor:    cursor.Cursor,
+		Error:     err.Error(),
+		Status:    models.VRFDeadLetterStatus_FAILED,
+	}
+	dlErr := m.Store.CreateVRFDeadLetter(deadLetter, cursor)
+	if dlErr != nil {
+		logger.Panicf("Failed to store dead letter: %v, error: %v", deadLetter, dlErr)
 	}
-	m.Cursor = cursor
 }
 
 func (m *VRFDeltaHandler) OnHeartBeat(block *pbcodec.Block, cursor string) {
@@ -58,7 +312,7 @@ func (m *VRFDeltaHandler) OnHeartBeat(block *pbcodec.Block, cursor string) {
 		Cursor: cursor,
 	})
 	if err != nil {
-		logger.Panicf("Failed to update cursor: %v, error: %v", cursor, err)
+		logger.Errorf("Failed to update cursor: %v, error: %v", cursor, err)
 	}
 }
 
@@ -69,3 +323,10 @@ func (m *VRFDeltaHandler) OnError(err error) {
 func (m *VRFDeltaHandler) OnComplete(lastBlockRef bstream.BlockRef) {
 	logger.Infof("On Complete Last Block Ref: %v", lastBlockRef)
 }
+
+func toBytes(data string) []byte {
+	if data == "" {
+		return nil
+	}
+	return []byte(data)
+}
@@ -0,0 +1,226 @@
+package streamhandlers_test
+
+import (
+	"testing"
+
+	pbcodec "github.com/dfuse-io/dfuse-eosio/pb/dfuse/eosio/codec/v1"
+	pbbstream "github.com/dfuse-io/pbgo/dfuse/bstream/v1"
+	"github.com/sebastianmontero/dfuse-firehose-client/dfclient"
+	"github.com/sebastianmontero/vrf-oracle/core/internal/cltest"
+	"github.com/sebastianmontero/vrf-oracle/core/store/models"
+	"github.com/sebastianmontero/vrf-oracle/core/streamhandlers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	jobTable  = "jobs"
+	jobData   = `{"assoc_id":"7","seeds":["1617081008500000"],"created_date":"2021-03-30T05:10:08.5"}`
+	blockHash = "000607e21b8d9da0ade735bcd19a9f8812335b510482d4b9f8c53753e0ebe9c8"
+)
+
+func newDelta(operation pbcodec.DBOp_Operation, oldData, newData string) *dfclient.TableDelta {
+	delta := &dfclient.TableDelta{
+		Operation: operation,
+		Code:      "vrf",
+		Scope:     "user1",
+		TableName: jobTable,
+		Block: &pbcodec.Block{
+			Id:     blockHash,
+			Number: 395234,
+		},
+	}
+	if oldData != "" {
+		delta.OldData = []byte(oldData)
+	}
+	if newData != "" {
+		delta.NewData = []byte(newData)
+	}
+	return delta
+}
+
+func assertNotConsumed(t *testing.T, consumer chan *models.VRFRequest) {
+	select {
+	case req := <-consumer:
+		assert.Fail(t, "Request should not have been sent to the consumer", "request: %v", req)
+	default:
+	}
+}
+
+func TestVRFDeltaHandler_WaitIrreversible(t *testing.T) {
+	t.Parallel()
+	store, cleanup := cltest.NewStore(t)
+	defer cleanup()
+
+	consumer := make(chan *models.VRFRequest, 1)
+	handler := &streamhandlers.VRFDeltaHandler{
+		JobTable:         jobTable,
+		WaitIrreversible: true,
+		Store:            store,
+		Consumer:         consumer,
+	}
+	assert.Equal(t, []pbbstream.ForkStep{
+		pbbstream.ForkStep_STEP_NEW,
+		pbbstream.ForkStep_STEP_UNDO,
+		pbbstream.ForkStep_STEP_IRREVERSIBLE,
+	}, handler.ForkSteps())
+
+	handler.OnDelta(newDelta(pbcodec.DBOp_OPERATION_INSERT, "", jobData), "cursor1", pbbstream.ForkStep_STEP_NEW)
+	assertNotConsumed(t, consumer)
+	req, err := store.FindVRFRequestAtBlock("user1", 7, blockHash)
+	require.NoError(t, err)
+	require.NotNil(t, req)
+	assert.Equal(t, models.VRFRequestStatus(models.VRFRequestStatus_PENDING), req.Status)
+	assert.Equal(t, uint64(395234), req.BlockNum)
+	assert.Equal(t, "cursor1", handler.Cursor)
+
+	handler.OnDelta(newDelta(pbcodec.DBOp_OPERATION_INSERT, "", jobData), "cursor2", pbbstream.ForkStep_STEP_IRREVERSIBLE)
+	require.Len(t, consumer, 1)
+	consumed := <-consumer
+	assert.Equal(t, req.ID, consumed.ID)
+	assert.Equal(t, models.VRFRequestStatus(models.VRFRequestStatus_ACTIVE), consumed.Status)
+	req, err = store.FindVRFRequest(req.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.VRFRequestStatus(models.VRFRequestStatus_ACTIVE), req.Status)
+
+	//Already active, it is not sent again
+	handler.OnDelta(newDelta(pbcodec.DBOp_OPERATION_INSERT, "", jobData), "cursor3", pbbstream.ForkStep_STEP_IRREVERSIBLE)
+	assertNotConsumed(t, consumer)
+
+	//The removal is only recorded once it is irreversible
+	handler.OnDelta(newDelta(pbcodec.DBOp_OPERATION_REMOVE, jobData, ""), "cursor4", pbbstream.ForkStep_STEP_NEW)
+	req, err = store.FindVRFRequest(req.ID)
+	require.NoError(t, err)
+	assert.False(t, req.Removed())
+	handler.OnDelta(newDelta(pbcodec.DBOp_OPERATION_REMOVE, jobData, ""), "cursor5", pbbstream.ForkStep_STEP_IRREVERSIBLE)
+	req, err = store.FindVRFRequest(req.ID)
+	require.NoError(t, err)
+	assert.True(t, req.Removed())
+}
+
+func TestVRFDeltaHandler_WaitIrreversibleUndo(t *testing.T) {
+	t.Parallel()
+	store, cleanup := cltest.NewStore(t)
+	defer cleanup()
+
+	consumer := make(chan *models.VRFRequest, 1)
+	handler := &streamhandlers.VRFDeltaHandler{
+		JobTable:         jobTable,
+		WaitIrreversible: true,
+		Store:            store,
+		Consumer:         consumer,
+	}
+
+	handler.OnDelta(newDelta(pbcodec.DBOp_OPERATION_INSERT, "", jobData), "cursor1", pbbstream.ForkStep_STEP_NEW)
+	//Undo operations are reversed, the undone insert is a removal of the data it wrote
+	handler.OnDelta(newDelta(pbcodec.DBOp_OPERATION_REMOVE, jobData, ""), "cursor2", pbbstream.ForkStep_STEP_UNDO)
+	assertNotConsumed(t, consumer)
+	req, err := store.FindVRFRequestAtBlock("user1", 7, blockHash)
+	require.NoError(t, err)
+	require.NotNil(t, req)
+	assert.Equal(t, models.VRFRequestStatus(models.VRFRequestStatus_CANCELLED), req.Status)
+
+	deadLetters, err := store.FindVRFDeadLetters(models.VRFDeadLetterStatus_FAILED)
+	require.NoError(t, err)
+	assert.Empty(t, deadLetters)
+}
+
+func TestVRFDeltaHandler_WaitIrreversibleWithoutNew(t *testing.T) {
+	t.Parallel()
+	store, cleanup := cltest.NewStore(t)
+	defer cleanup()
+
+	consumer := make(chan *models.VRFRequest, 1)
+	handler := &streamhandlers.VRFDeltaHandler{
+		JobTable:         jobTable,
+		WaitIrreversible: true,
+		Store:            store,
+		Consumer:         consumer,
+	}
+
+	//The stream started after the new step of the block
+	handler.OnDelta(newDelta(pbcodec.DBOp_OPERATION_INSERT, "", jobData), "cursor1", pbbstream.ForkStep_STEP_IRREVERSIBLE)
+	require.Len(t, consumer, 1)
+	consumed := <-consumer
+	assert.Equal(t, models.VRFRequestStatus(models.VRFRequestStatus_ACTIVE), consumed.Status)
+	req, err := store.FindVRFRequestAtBlock("user1", 7, blockHash)
+	require.NoError(t, err)
+	require.NotNil(t, req)
+	assert.Equal(t, consumed.ID, req.ID)
+	assert.Equal(t, models.VRFRequestStatus(models.VRFRequestStatus_ACTIVE), req.Status)
+}
+
+func TestVRFDeltaHandler_Removal(t *testing.T) {
+	t.Parallel()
+	store, cleanup := cltest.NewStore(t)
+	defer cleanup()
+
+	consumer := make(chan *models.VRFRequest, 1)
+	handler := &streamhandlers.VRFDeltaHandler{
+		JobTable: jobTable,
+		Store:    store,
+		Consumer: consumer,
+	}
+	removal := func(operation pbcodec.DBOp_Operation, oldData, newData string) *dfclient.TableDelta {
+		delta := newDelta(operation, oldData, newData)
+		delta.Block = &pbcodec.Block{
+			Id:     "000607e81b8d9da0ade735bcd19a9f8812335b510482d4b9f8c53753e0ebe9c8",
+			Number: 395240,
+		}
+		return delta
+	}
+
+	handler.OnDelta(newDelta(pbcodec.DBOp_OPERATION_INSERT, "", jobData), "cursor1", pbbstream.ForkStep_STEP_NEW)
+	require.Len(t, consumer, 1)
+	req := <-consumer
+	assert.False(t, req.Removed())
+
+	//The contract resolved the request in a later block
+	handler.OnDelta(removal(pbcodec.DBOp_OPERATION_REMOVE, jobData, ""), "cursor2", pbbstream.ForkStep_STEP_NEW)
+	stored, err := store.FindVRFRequest(req.ID)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(395240), stored.RemovedBlockNum)
+	assert.Equal(t, "cursor2", handler.Cursor)
+
+	//Saving the request held in memory keeps the mark
+	req.Status = models.VRFRequestStatus_COMPLETED
+	require.NoError(t, store.SaveVRFRequest(req, nil))
+	stored, err = store.FindVRFRequest(req.ID)
+	require.NoError(t, err)
+	assert.True(t, stored.Removed())
+
+	//Undo operations are reversed, the undone removal is an insert of the data it removed
+	handler.OnDelta(removal(pbcodec.DBOp_OPERATION_INSERT, "", jobData), "cursor3", pbbstream.ForkStep_STEP_UNDO)
+	stored, err = store.FindVRFRequest(req.ID)
+	require.NoError(t, err)
+	assert.False(t, stored.Removed())
+
+	deadLetters, err := store.FindVRFDeadLetters(models.VRFDeadLetterStatus_FAILED)
+	require.NoError(t, err)
+	assert.Empty(t, deadLetters)
+}
+
+func TestVRFDeltaHandler_DeadLettersMalformedDeltas(t *testing.T) {
+	t.Parallel()
+	store, cleanup := cltest.NewStore(t)
+	defer cleanup()
+
+	consumer := make(chan *models.VRFRequest, 1)
+	handler := &streamhandlers.VRFDeltaHandler{
+		JobTable: jobTable,
+		Store:    store,
+		Consumer: consumer,
+	}
+
+	handler.OnDelta(newDelta(pbcodec.DBOp_OPERATION_INSERT, "", `{"assoc_id":"7","seeds":`), "cursor1", pbbstream.ForkStep_STEP_NEW)
+	assertNotConsumed(t, consumer)
+	assert.Equal(t, "cursor1", handler.Cursor)
+	deadLetters, err := store.FindVRFDeadLetters(models.VRFDeadLetterStatus_FAILED)
+	require.NoError(t, err)
+	require.Len(t, deadLetters, 1)
+	assert.Equal(t, "cursor1", deadLetters[0].Cursor)
+	assert.Contains(t, deadLetters[0].Error, streamhandlers.ErrMalformedDelta.Error())
+	cursor, err := store.FindCursor(models.CursorID_VRF_REQUESTS)
+	require.NoError(t, err)
+	assert.Equal(t, "cursor1", cursor.Cursor)
+}
@@ -26,6 +26,7 @@ import (
 	"github.com/gobuffalo/packr"
 	"github.com/sebastianmontero/vrf-oracle/core/logger"
 	"github.com/sebastianmontero/vrf-oracle/core/services/chainlink"
+	strpkg "github.com/sebastianmontero/vrf-oracle/core/store"
 	"github.com/sebastianmontero/vrf-oracle/core/store/orm"
 	"github.com/sebastianmontero/vrf-oracle/core/store/presenters"
 	"github.com/ulule/limiter"
@@ -115,6 +116,38 @@ func Router(app chainlink.Application) *gin.Engine {
 	return engine
 }
 
+// VRFOracleRouter serves the vrf requests routes from the vrf oracle process.
+// The oracle and the node compete for the advisory lock of the database, so
+// the node, and its router, can not run on the database the oracle is using.
+// Only API token authentication is supported, with the token of the node's
+// user, as there are no session routes to log in.
+func VRFOracleRouter(store *strpkg.Store) *gin.Engine {
+	engine := gin.New()
+	config := store.Config
+	engine.Use(
+		limits.RequestSizeLimiter(config.DefaultHTTPLimit()),
+		loggerFunc(),
+		gin.Recovery(),
+		secureMiddleware(config),
+	)
+	engine.Use(helmet.Default())
+
+	authv2 := engine.Group(
+		"/v2",
+		rateLimiter(
+			config.AuthenticatedRateLimitPeriod().Duration(),
+			config.AuthenticatedRateLimit(),
+		),
+		RequireAuth(store, AuthenticateByToken),
+	)
+	vrc := VRFRequestsController{store}
+	authv2.GET("/vrf_requests", paginatedRequest(vrc.Index))
+	authv2.GET("/vrf_requests/:ID", vrc.Show)
+	authv2.POST("/vrf_requests/:ID/retry", vrc.Retry)
+	authv2.POST("/vrf_requests/:ID/cancel", vrc.Cancel)
+	return engine
+}
+
 func rateLimiter(period time.Duration, limit int64) gin.HandlerFunc {
 	store := memory.NewStore()
 	rate := limiter.Rate{
@@ -256,6 +289,12 @@ func v2Routes(app chainlink.Application, r *gin.RouterGroup) {
 		authv2.GET("/transactions", paginatedRequest(txs.Index))
 		authv2.GET("/transactions/:TxHash", txs.Show)
 
+		vrc := VRFRequestsController{app.GetStore()}
+		authv2.GET("/vrf_requests", paginatedRequest(vrc.Index))
+		authv2.GET("/vrf_requests/:ID", vrc.Show)
+		authv2.POST("/vrf_requests/:ID/retry", vrc.Retry)
+		authv2.POST("/vrf_requests/:ID/cancel", vrc.Cancel)
+
 		bdc := BulkDeletesController{app}
 		authv2.DELETE("/bulk_delete_runs", bdc.Delete)
 
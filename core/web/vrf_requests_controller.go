@@ -0,0 +1,170 @@
+package web
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/sebastianmontero/vrf-oracle/core/logger"
+	strpkg "github.com/sebastianmontero/vrf-oracle/core/store"
+	"github.com/sebastianmontero/vrf-oracle/core/store/models"
+	"github.com/sebastianmontero/vrf-oracle/core/store/orm"
+	"github.com/sebastianmontero/vrf-oracle/core/store/presenters"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+)
+
+// VRFRequestsController displays the EOS VRF requests with their jobs and runs.
+// It only needs the store, so that the vrf oracle can serve it as well, see
+// VRFOracleRouter.
+type VRFRequestsController struct {
+	Store *strpkg.Store
+}
+
+// Index returns paginated VRF requests, newest first, optionally filtered by
+// caller, assocId, status and a fromBlock/toBlock range.
+// Example:
+//  "<application>/vrf_requests?caller=alice&status=Active&fromBlock=1000"
+func (vrc *VRFRequestsController) Index(c *gin.Context, size, page, offset int) {
+	filter, err := parseVRFRequestFilter(c)
+	if err != nil {
+		jsonAPIError(c, http.StatusUnprocessableEntity, err)
+		return
+	}
+	reqs, count, err := vrc.Store.VRFRequests(filter, offset, size)
+	preqs := make([]presenters.VRFRequest, len(reqs))
+	for i, req := range reqs {
+		preqs[i] = presenters.NewVRFRequest(req)
+	}
+	paginatedResponse(c, "vrf_requests", size, page, preqs, count, err)
+}
+
+// Show returns the details of a VRF request, including its jobs and their runs.
+// Example:
+//  "<application>/vrf_requests/:ID"
+func (vrc *VRFRequestsController) Show(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("ID"), 10, 64)
+	if err != nil {
+		jsonAPIError(c, http.StatusUnprocessableEntity, err)
+		return
+	}
+	req, err := vrc.Store.FindVRFRequest(id)
+	if errors.Cause(err) == orm.ErrorNotFound {
+		jsonAPIError(c, http.StatusNotFound, errors.New("VRF request not found"))
+		return
+	}
+	if err != nil {
+		jsonAPIError(c, http.StatusInternalServerError, err)
+		return
+	}
+	jobs, runsByJob, err := vrc.Store.FindVRFRequestJobsWithRuns(id)
+	if err != nil {
+		jsonAPIError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	jsonAPIResponse(c, presenters.NewVRFRequestWithJobs(*req, jobs, runsByJob), "vrf_request")
+}
+
+// Retry creates a new job that processes the last job of a VRF request again,
+// the last job must have finished.
+// Example:
+//  "<application>/vrf_requests/:ID/retry"
+func (vrc *VRFRequestsController) Retry(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("ID"), 10, 64)
+	if err != nil {
+		jsonAPIError(c, http.StatusUnprocessableEntity, err)
+		return
+	}
+	job, err := vrc.Store.RetryVRFRequest(id)
+	if err != nil {
+		vrfRequestActionError(c, err)
+		return
+	}
+	logger.Infow("VRF request retried", "id", id, "job", job.ID)
+	vrc.Show(c)
+}
+
+// VRFRequestCancel is the optional body of a cancel request.
+type VRFRequestCancel struct {
+	Reason string `json:"reason"`
+}
+
+// Cancel cancels a VRF request, its jobs that have not been processed yet are
+// failed with the reason given.
+// Example:
+//  "<application>/vrf_requests/:ID/cancel"
+func (vrc *VRFRequestsController) Cancel(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("ID"), 10, 64)
+	if err != nil {
+		jsonAPIError(c, http.StatusUnprocessableEntity, err)
+		return
+	}
+	request := VRFRequestCancel{}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&request); err != nil {
+			jsonAPIError(c, http.StatusUnprocessableEntity, err)
+			return
+		}
+	}
+	msg := "Cancelled by an operator"
+	if request.Reason != "" {
+		msg = fmt.Sprintf("%v: %v", msg, request.Reason)
+	}
+	_, err = vrc.Store.CancelVRFRequest(id, msg)
+	if err != nil {
+		vrfRequestActionError(c, err)
+		return
+	}
+	logger.Infow("VRF request cancelled", "id", id, "reason", request.Reason)
+	vrc.Show(c)
+}
+
+func vrfRequestActionError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, orm.ErrorNotFound):
+		jsonAPIError(c, http.StatusNotFound, errors.New("VRF request not found"))
+	case errors.Is(err, orm.ErrVRFRequestStateConflict):
+		jsonAPIError(c, http.StatusConflict, err)
+	default:
+		jsonAPIError(c, http.StatusInternalServerError, err)
+	}
+}
+
+func parseVRFRequestFilter(c *gin.Context) (orm.VRFRequestFilter, error) {
+	filter := orm.VRFRequestFilter{
+		Caller: c.Query("caller"),
+		Status: models.VRFRequestStatus(c.Query("status")),
+	}
+	if value := c.Query("assocId"); value != "" {
+		assocID, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return filter, fmt.Errorf("invalid assocId: %v", value)
+		}
+		filter.AssocID = &assocID
+	}
+	var err error
+	if filter.FromBlock, err = parseBlockNum(c, "fromBlock"); err != nil {
+		return filter, err
+	}
+	if filter.ToBlock, err = parseBlockNum(c, "toBlock"); err != nil {
+		return filter, err
+	}
+	if filter.ToBlock > 0 && filter.FromBlock > filter.ToBlock {
+		return filter, errors.New("fromBlock must not be greater than toBlock")
+	}
+	return filter, nil
+}
+
+func parseBlockNum(c *gin.Context, param string) (uint64, error) {
+	value := c.Query(param)
+	if value == "" {
+		return 0, nil
+	}
+	blockNum, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %v: %v", param, value)
+	}
+	return blockNum, nil
+}
@@ -0,0 +1,235 @@
+package web_test
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sebastianmontero/vrf-oracle/core/services/eth"
+
+	"github.com/sebastianmontero/vrf-oracle/core/auth"
+	"github.com/sebastianmontero/vrf-oracle/core/internal/cltest"
+	"github.com/sebastianmontero/vrf-oracle/core/store"
+	"github.com/sebastianmontero/vrf-oracle/core/store/models"
+	"github.com/sebastianmontero/vrf-oracle/core/store/presenters"
+	"github.com/sebastianmontero/vrf-oracle/core/web"
+
+	"github.com/lib/pq"
+	"github.com/manyminds/api2go/jsonapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func mustInsertVRFRequest(t *testing.T, store *store.Store, assocID uint64, caller string, blockNum uint64) *models.VRFRequest {
+	req := &models.VRFRequest{
+		AssocID:   assocID,
+		BlockNum:  blockNum,
+		BlockHash: "000607e21b8d9da0ade735bcd19a9f8812335b510482d4b9f8c53753e0ebe9c8",
+		Seeds:     pq.StringArray([]string{"2"}),
+		Count:     1,
+		Caller:    caller,
+		Type:      models.VRFRequestType_IMMEDIATE,
+		Status:    models.VRFRequestStatus_ACTIVE,
+	}
+	require.NoError(t, store.CreateVRFRequest(req, nil))
+	return req
+}
+
+func TestVRFRequestsController_Index(t *testing.T) {
+	t.Parallel()
+
+	rpcClient, gethClient, _, assertMocksCalled := cltest.NewEthMocksWithStartupAssertions(t)
+	defer assertMocksCalled()
+	app, cleanup := cltest.NewApplication(t,
+		eth.NewClientWith(rpcClient, gethClient),
+	)
+	defer cleanup()
+	require.NoError(t, app.Start())
+	store := app.GetStore()
+	client := app.NewHTTPClient()
+
+	mustInsertVRFRequest(t, store, 1, "user1", 100)
+	req2 := mustInsertVRFRequest(t, store, 2, "user1", 200)
+	mustInsertVRFRequest(t, store, 3, "user2", 300)
+
+	resp, cleanup := client.Get(fmt.Sprintf("/v2/vrf_requests?size=%d&caller=%s", 1, "user1"))
+	defer cleanup()
+	cltest.AssertServerResponse(t, resp, http.StatusOK)
+
+	var links jsonapi.Links
+	var reqs []presenters.VRFRequest
+	body := cltest.ParseResponseBody(t, resp)
+	require.NoError(t, web.ParsePaginatedResponse(body, &reqs, &links))
+	assert.NotEmpty(t, links["next"].Href)
+	assert.Empty(t, links["prev"].Href)
+
+	require.Len(t, reqs, 1)
+	assert.Equal(t, req2.ID, reqs[0].ID)
+	assert.Equal(t, req2.AssocID, reqs[0].AssocID)
+	assert.Empty(t, reqs[0].Jobs)
+
+	resp, cleanup = client.Get("/v2/vrf_requests?fromBlock=x")
+	defer cleanup()
+	cltest.AssertServerResponse(t, resp, http.StatusUnprocessableEntity)
+
+	resp, cleanup = client.Get("/v2/vrf_requests?fromBlock=300&toBlock=200")
+	defer cleanup()
+	cltest.AssertServerResponse(t, resp, http.StatusUnprocessableEntity)
+}
+
+func TestVRFRequestsController_Show(t *testing.T) {
+	t.Parallel()
+
+	rpcClient, gethClient, _, assertMocksCalled := cltest.NewEthMocksWithStartupAssertions(t)
+	defer assertMocksCalled()
+	app, cleanup := cltest.NewApplication(t,
+		eth.NewClientWith(rpcClient, gethClient),
+	)
+	defer cleanup()
+	require.NoError(t, app.Start())
+	store := app.GetStore()
+	client := app.NewHTTPClient()
+
+	req := mustInsertVRFRequest(t, store, 1, "user1", 100)
+	job := models.NewVRFRequestJob(req)
+	require.NoError(t, store.SaveVRFRequestJob(job))
+	run := models.NewVRFRequestRun(job)
+	run.UpdateStatus(models.VRFRequestRunStatus_FAILED, "Calling SetRand on contract failed", 3)
+	run.SetTx("c8a3b6e5f4a1d2c3b4a5968778695a4b3c2d1e0f9a8b7c6d5e4f3a2b1c0d9e8f", 1200)
+	require.NoError(t, store.SaveVRFRequestRun(run, false))
+
+	resp, cleanup := client.Get(fmt.Sprintf("/v2/vrf_requests/%d", req.ID))
+	defer cleanup()
+	cltest.AssertServerResponse(t, resp, http.StatusOK)
+
+	preq := presenters.VRFRequest{}
+	require.NoError(t, cltest.ParseJSONAPIResponse(t, resp, &preq))
+	assert.Equal(t, req.ID, preq.ID)
+	require.Len(t, preq.Jobs, 1)
+	assert.Equal(t, job.ID, preq.Jobs[0].ID)
+	assert.Equal(t, models.VRFRequestJobStatus(models.VRFRequestJobStatus_ACTIVE), preq.Jobs[0].Status)
+	require.Len(t, preq.Jobs[0].Runs, 1)
+	assert.Equal(t, "Calling SetRand on contract failed", preq.Jobs[0].Runs[0].StatusMsg)
+	assert.Equal(t, run.TxID, preq.Jobs[0].Runs[0].TxID)
+	assert.Equal(t, uint64(1200), preq.Jobs[0].Runs[0].TxBlockNum)
+
+	resp, cleanup = client.Get(fmt.Sprintf("/v2/vrf_requests/%d", req.ID+1))
+	defer cleanup()
+	cltest.AssertServerResponse(t, resp, http.StatusNotFound)
+}
+
+func TestVRFOracleRouter_VRFRequests(t *testing.T) {
+	t.Parallel()
+
+	store, cleanup := cltest.NewStore(t)
+	defer cleanup()
+	user := cltest.MustRandomUser()
+	require.NoError(t, user.SetAuthToken(&auth.Token{AccessKey: cltest.APIKey, Secret: cltest.APISecret}))
+	require.NoError(t, store.SaveUser(&user))
+	req := mustInsertVRFRequest(t, store, 1, "user1", 100)
+	router := web.VRFOracleRouter(store)
+
+	get := func(path, secret string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		httpReq, err := http.NewRequest("GET", path, nil)
+		require.NoError(t, err)
+		httpReq.Header.Set(web.APIKey, cltest.APIKey)
+		httpReq.Header.Set(web.APISecret, secret)
+		router.ServeHTTP(w, httpReq)
+		return w
+	}
+
+	w := get("/v2/vrf_requests", cltest.APISecret)
+	require.Equal(t, http.StatusOK, w.Code)
+	var links jsonapi.Links
+	var reqs []presenters.VRFRequest
+	require.NoError(t, web.ParsePaginatedResponse(w.Body.Bytes(), &reqs, &links))
+	require.Len(t, reqs, 1)
+	assert.Equal(t, req.ID, reqs[0].ID)
+
+	w = get(fmt.Sprintf("/v2/vrf_requests/%d", req.ID), cltest.APISecret)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = get("/v2/vrf_requests", "bad-secret")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestVRFRequestsController_Retry(t *testing.T) {
+	t.Parallel()
+
+	rpcClient, gethClient, _, assertMocksCalled := cltest.NewEthMocksWithStartupAssertions(t)
+	defer assertMocksCalled()
+	app, cleanup := cltest.NewApplication(t,
+		eth.NewClientWith(rpcClient, gethClient),
+	)
+	defer cleanup()
+	require.NoError(t, app.Start())
+	store := app.GetStore()
+	client := app.NewHTTPClient()
+
+	req := mustInsertVRFRequest(t, store, 1, "user1", 100)
+	job := models.NewVRFRequestJob(req)
+	require.NoError(t, store.SaveVRFRequestJob(job))
+
+	resp, cleanup := client.Post(fmt.Sprintf("/v2/vrf_requests/%d/retry", req.ID), nil)
+	defer cleanup()
+	cltest.AssertServerResponse(t, resp, http.StatusConflict)
+
+	run := models.NewVRFRequestRun(job)
+	run.UpdateStatus(models.VRFRequestRunStatus_FAILED, "Calling SetRand on contract failed", 0)
+	require.NoError(t, store.SaveVRFRequestRun(run, true))
+
+	resp, cleanup = client.Post(fmt.Sprintf("/v2/vrf_requests/%d/retry", req.ID), nil)
+	defer cleanup()
+	cltest.AssertServerResponse(t, resp, http.StatusOK)
+
+	preq := presenters.VRFRequest{}
+	require.NoError(t, cltest.ParseJSONAPIResponse(t, resp, &preq))
+	assert.Equal(t, models.VRFRequestStatus(models.VRFRequestStatus_ACTIVE), preq.Status)
+	require.Len(t, preq.Jobs, 2)
+	assert.Equal(t, models.VRFRequestJobStatus(models.VRFRequestJobStatus_FAILED), preq.Jobs[0].Status)
+	assert.Equal(t, models.VRFRequestJobStatus(models.VRFRequestJobStatus_ACTIVE), preq.Jobs[1].Status)
+	assert.Len(t, preq.Jobs[0].Runs, 1)
+	assert.Empty(t, preq.Jobs[1].Runs)
+
+	resp, cleanup = client.Post(fmt.Sprintf("/v2/vrf_requests/%d/retry", req.ID+1), nil)
+	defer cleanup()
+	cltest.AssertServerResponse(t, resp, http.StatusNotFound)
+}
+
+func TestVRFRequestsController_Cancel(t *testing.T) {
+	t.Parallel()
+
+	rpcClient, gethClient, _, assertMocksCalled := cltest.NewEthMocksWithStartupAssertions(t)
+	defer assertMocksCalled()
+	app, cleanup := cltest.NewApplication(t,
+		eth.NewClientWith(rpcClient, gethClient),
+	)
+	defer cleanup()
+	require.NoError(t, app.Start())
+	store := app.GetStore()
+	client := app.NewHTTPClient()
+
+	req := mustInsertVRFRequest(t, store, 1, "user1", 100)
+	job := models.NewVRFRequestJob(req)
+	require.NoError(t, store.SaveVRFRequestJob(job))
+
+	body := bytes.NewBufferString(`{"reason":"contract redeployed"}`)
+	resp, cleanup := client.Post(fmt.Sprintf("/v2/vrf_requests/%d/cancel", req.ID), body)
+	defer cleanup()
+	cltest.AssertServerResponse(t, resp, http.StatusOK)
+
+	preq := presenters.VRFRequest{}
+	require.NoError(t, cltest.ParseJSONAPIResponse(t, resp, &preq))
+	assert.Equal(t, models.VRFRequestStatus(models.VRFRequestStatus_CANCELLED), preq.Status)
+	require.Len(t, preq.Jobs, 1)
+	assert.Equal(t, models.VRFRequestJobStatus(models.VRFRequestJobStatus_FAILED), preq.Jobs[0].Status)
+	require.Len(t, preq.Jobs[0].Runs, 1)
+	assert.Equal(t, "Cancelled by an operator: contract redeployed", preq.Jobs[0].Runs[0].StatusMsg)
+
+	resp, cleanup = client.Post(fmt.Sprintf("/v2/vrf_requests/%d/cancel", req.ID), nil)
+	defer cleanup()
+	cltest.AssertServerResponse(t, resp, http.StatusConflict)
+}